@@ -2,25 +2,37 @@ package apply
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
 	"github.com/mattermost/morph"
 	"github.com/mattermost/morph/drivers"
-	"github.com/mattermost/morph/drivers/mysql"
-	"github.com/mattermost/morph/drivers/postgres"
-	"github.com/mattermost/morph/drivers/sqlite"
 	"github.com/mattermost/morph/models"
+	"github.com/mattermost/morph/sources"
 	"github.com/mattermost/morph/sources/file"
+	morphtesting "github.com/mattermost/morph/testing"
+
+	_ "github.com/mattermost/morph/drivers/mysql"
+	_ "github.com/mattermost/morph/drivers/pgx"
+	_ "github.com/mattermost/morph/drivers/postgres"
+	_ "github.com/mattermost/morph/drivers/sqlite"
+	_ "github.com/mattermost/morph/sources/remote"
+	_ "github.com/mattermost/morph/sources/tar"
 )
 
 type ConnectionParameters struct {
-	DSN        string
+	DSN string
+	// DriverName is the name of a registered driver. If it is empty, the
+	// driver is resolved from the scheme of the DSN, e.g. postgres://.
 	DriverName string
+	// SourcePath is the path or the URL of the migrations, opened with the
+	// source registered for its scheme, e.g. tar://migrations.tar.gz.
 	SourcePath string
+	// Source is used instead of opening the SourcePath if it is set.
+	Source sources.Source
 }
 
 func Migrate(ctx context.Context, params ConnectionParameters, options ...morph.EngineOption) error {
-	engine, err := initializeEngine(ctx, params.DSN, params.DriverName, params.SourcePath, options...)
+	engine, err := initializeEngine(ctx, params, options...)
 	if err != nil {
 		return err
 	}
@@ -29,8 +41,24 @@ func Migrate(ctx context.Context, params ConnectionParameters, options ...morph.
 	return engine.ApplyAll()
 }
 
+// MigrateAndDumpSchema applies all pending migrations and returns the resulting
+// schema of the database.
+func MigrateAndDumpSchema(ctx context.Context, params ConnectionParameters, options ...morph.EngineOption) (*models.Schema, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer engine.Close()
+
+	if err := engine.ApplyAll(); err != nil {
+		return nil, err
+	}
+
+	return engine.DumpSchema()
+}
+
 func Up(ctx context.Context, limit int, params ConnectionParameters, options ...morph.EngineOption) (int, error) {
-	engine, err := initializeEngine(ctx, params.DSN, params.DriverName, params.SourcePath, options...)
+	engine, err := initializeEngine(ctx, params, options...)
 	if err != nil {
 		return -1, err
 	}
@@ -40,7 +68,7 @@ func Up(ctx context.Context, limit int, params ConnectionParameters, options ...
 }
 
 func Down(ctx context.Context, limit int, params ConnectionParameters, options ...morph.EngineOption) (int, error) {
-	engine, err := initializeEngine(ctx, params.DSN, params.DriverName, params.SourcePath, options...)
+	engine, err := initializeEngine(ctx, params, options...)
 	if err != nil {
 		return -1, err
 	}
@@ -49,8 +77,28 @@ func Down(ctx context.Context, limit int, params ConnectionParameters, options .
 	return engine.ApplyDown(limit)
 }
 
+func Redo(ctx context.Context, limit int, params ConnectionParameters, options ...morph.EngineOption) (int, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return -1, err
+	}
+	defer engine.Close()
+
+	return engine.Redo(limit)
+}
+
+func Baseline(ctx context.Context, version uint64, params ConnectionParameters, options ...morph.EngineOption) (int, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return -1, err
+	}
+	defer engine.Close()
+
+	return engine.Baseline(version)
+}
+
 func Plan(ctx context.Context, plan *models.Plan, params ConnectionParameters, options ...morph.EngineOption) error {
-	engine, err := initializeEngine(ctx, params.DSN, params.DriverName, params.SourcePath, options...)
+	engine, err := initializeEngine(ctx, params, options...)
 	if err != nil {
 		return err
 	}
@@ -59,8 +107,88 @@ func Plan(ctx context.Context, plan *models.Plan, params ConnectionParameters, o
 	return engine.ApplyPlan(plan)
 }
 
+// PlanProgress returns the index of the first step of the plan that has not been
+// applied yet.
+func PlanProgress(ctx context.Context, plan *models.Plan, params ConnectionParameters, options ...morph.EngineOption) (int, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return -1, err
+	}
+	defer engine.Close()
+
+	return engine.PlanProgress(plan)
+}
+
+// PlanRuns returns the last limit plan runs from the journal.
+func PlanRuns(ctx context.Context, limit int, params ConnectionParameters, options ...morph.EngineOption) ([]*models.PlanRun, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer engine.Close()
+
+	return engine.PlanRuns(limit)
+}
+
+// DumpSchema returns the schema of the database.
+func DumpSchema(ctx context.Context, params ConnectionParameters, options ...morph.EngineOption) (*models.Schema, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer engine.Close()
+
+	return engine.DumpSchema()
+}
+
+// CheckDrift compares the schema of the database with the schema obtained by
+// applying the same migrations to a scratch database.
+func CheckDrift(ctx context.Context, params ConnectionParameters, options ...morph.EngineOption) (*models.SchemaDiff, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer engine.Close()
+
+	return engine.CheckDrift()
+}
+
+// Squash consolidates the migrations of the source up to and including the given
+// version into a single migration, built from the schema they produce on a
+// scratch database. It returns the up and down files of the squashed migration.
+func Squash(ctx context.Context, version uint64, params ConnectionParameters, options ...morph.EngineOption) (*models.Migration, *models.Migration, error) {
+	engine, err := initializeEngine(ctx, params, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer engine.Close()
+
+	return engine.Squash(version)
+}
+
+// Seed applies all pending migrations, then the seeds of the directory at the
+// given path that are new or have changed.
+func Seed(ctx context.Context, seedsPath string, params ConnectionParameters, options ...morph.EngineOption) (int, error) {
+	seeds, err := file.OpenSeeds(seedsPath)
+	if err != nil {
+		return -1, err
+	}
+
+	engine, err := initializeEngine(ctx, params, append(options, morph.WithSeeds(seeds))...)
+	if err != nil {
+		return -1, err
+	}
+	defer engine.Close()
+
+	if err := engine.ApplyAll(); err != nil {
+		return -1, err
+	}
+
+	return engine.Seed()
+}
+
 func GeneratePlan(ctx context.Context, direction models.Direction, limit int, auto bool, params ConnectionParameters, options ...morph.EngineOption) (*models.Plan, error) {
-	engine, err := initializeEngine(ctx, params.DSN, params.DriverName, params.SourcePath, options...)
+	engine, err := initializeEngine(ctx, params, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -78,23 +206,28 @@ func GeneratePlan(ctx context.Context, direction models.Direction, limit int, au
 	return engine.GeneratePlan(migrations, auto)
 }
 
-func initializeEngine(ctx context.Context, dsn, driverName, path string, options ...morph.EngineOption) (*morph.Morph, error) {
-	src, err := file.Open(path)
+func RoundTrip(ctx context.Context, params ConnectionParameters, options ...morph.EngineOption) error {
+	src, err := openSource(params)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var driver drivers.Driver
-	switch driverName {
-	case "mysql":
-		driver, err = mysql.Open(dsn)
-	case "postgresql", "postgres":
-		driver, err = postgres.Open(dsn)
-	case "sqlite":
-		driver, err = sqlite.Open(dsn)
-	default:
-		err = fmt.Errorf("unsupported driver %s", driverName)
+	driver, err := openDriver(params.DSN, params.DriverName)
+	if err != nil {
+		return err
 	}
+	defer driver.Close()
+
+	return morphtesting.RoundTrip(ctx, driver, src, morphtesting.WithEngineOptions(options...))
+}
+
+func initializeEngine(ctx context.Context, params ConnectionParameters, options ...morph.EngineOption) (*morph.Morph, error) {
+	src, err := openSource(params)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := openDriver(params.DSN, params.DriverName)
 	if err != nil {
 		return nil, err
 	}
@@ -106,3 +239,22 @@ func initializeEngine(ctx context.Context, dsn, driverName, path string, options
 
 	return engine, err
 }
+
+func openSource(params ConnectionParameters) (sources.Source, error) {
+	if params.Source != nil {
+		return params.Source, nil
+	}
+	if params.SourcePath == "" {
+		return nil, errors.New("the path or the url of the migrations is required")
+	}
+
+	return sources.Open(params.SourcePath)
+}
+
+func openDriver(dsn, driverName string) (drivers.Driver, error) {
+	if driverName == "" {
+		return drivers.Open(dsn)
+	}
+
+	return drivers.OpenByName(driverName, dsn)
+}
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/mattermost/morph"
 	"github.com/mattermost/morph/apply"
 	"github.com/mattermost/morph/models"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func ApplyCmd() *cobra.Command {
@@ -19,18 +23,14 @@ func ApplyCmd() *cobra.Command {
 	}
 
 	// Required flags
-	cmd.PersistentFlags().StringP("driver", "d", "", "the database driver of the migrations")
-	_ = cmd.MarkPersistentFlagRequired("driver")
+	cmd.PersistentFlags().StringP("driver", "d", "", "the database driver of the migrations, resolved from the scheme of the dsn if not set")
 	cmd.PersistentFlags().String("dsn", "", "the dsn of the database")
 	_ = cmd.MarkPersistentFlagRequired("dsn")
 	cmd.PersistentFlags().StringP("path", "p", "", "the source path of the migrations")
-	_ = cmd.MarkPersistentFlagRequired("path")
+	cmd.PersistentFlags().String("source", "", "the url of the migrations, e.g. tar://migrations.tar.gz, used instead of the path")
 
 	// Optional flags
-	cmd.PersistentFlags().IntP("timeout", "t", 60, "the timeout in seconds for each migration file to run")
-	cmd.PersistentFlags().StringP("migrations-table", "m", "db_migrations", "the name of the migrations table")
-	cmd.PersistentFlags().StringP("lock-key", "l", "mutex_migrations", "the name of the mutex key")
-	cmd.PersistentFlags().Bool("dry-run", false, "prints the plan without applying it")
+	addEngineFlags(cmd.PersistentFlags())
 
 	// Add subcommands
 	cmd.AddCommand(
@@ -38,6 +38,9 @@ func ApplyCmd() *cobra.Command {
 		DownApplyCmd(),
 		MigrateApplyCmd(),
 		PlanApplyCmd(),
+		JournalApplyCmd(),
+		BaselineApplyCmd(),
+		RedoApplyCmd(),
 	)
 
 	return cmd
@@ -68,13 +71,16 @@ func DownApplyCmd() *cobra.Command {
 }
 
 func MigrateApplyCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:           "migrate",
 		Short:         "Apply all migrations",
 		RunE:          migrateApplyCmdF,
 		SilenceUsage:  true,
 		SilenceErrors: false,
 	}
+	cmd.Flags().String("schema-file", "", "writes the resulting schema to the file, as JSON if it has a .json extension and as SQL otherwise")
+
+	return cmd
 }
 
 func PlanApplyCmd() *cobra.Command {
@@ -87,10 +93,47 @@ func PlanApplyCmd() *cobra.Command {
 		Args:          cobra.MinimumNArgs(1),
 	}
 	cmd.Flags().Bool("revert", false, "reverts an existing plan")
+	cmd.Flags().Bool("resume", false, "resumes a partially applied plan from the first incomplete step")
+
+	return cmd
+}
+
+func JournalApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "journal",
+		Short:         "Shows the last plan runs recorded in the journal",
+		RunE:          journalApplyCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+	cmd.Flags().Int("number", 10, "show the last N plan runs")
 
 	return cmd
 }
 
+func BaselineApplyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:           "baseline <version>",
+		Short:         "Marks the migrations up to the version as applied without running them",
+		RunE:          baselineApplyCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		Args:          cobra.ExactArgs(1),
+	}
+}
+
+func RedoApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "redo",
+		Short:         "Rolls back the last migrations and applies them again",
+		RunE:          redoApplyCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+	cmd.Flags().Int("number", 1, "redo the last N migrations")
+	return cmd
+}
+
 func upApplyCmdF(cmd *cobra.Command, _ []string) error {
 	steps, _ := cmd.Flags().GetInt("number")
 	ctx, cancel := context.WithCancel(context.Background())
@@ -125,15 +168,81 @@ func migrateApplyCmdF(cmd *cobra.Command, _ []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	schemaFile, _ := cmd.Flags().GetString("schema-file")
+
 	morph.InfoLogger.Println("Applying all pending migrations...")
-	if err := apply.Migrate(ctx, parseEssentialFlags(cmd), parseEngineFlags(cmd)...); err != nil {
+	if schemaFile == "" {
+		if err := apply.Migrate(ctx, parseEssentialFlags(cmd), parseEngineFlags(cmd)...); err != nil {
+			return err
+		}
+		morph.SuccessLogger.Println("Pending migrations applied.")
+		return nil
+	}
+
+	schema, err := apply.MigrateAndDumpSchema(ctx, parseEssentialFlags(cmd), parseEngineFlags(cmd)...)
+	if err != nil {
 		return err
 	}
 	morph.SuccessLogger.Println("Pending migrations applied.")
 
+	if err := writeSchema(schemaFile, schema); err != nil {
+		return err
+	}
+	morph.SuccessLogger.Printf("Schema written to %s.\n", schemaFile)
+
+	return nil
+}
+
+func writeSchema(fileName string, schema *models.Schema) error {
+	b := []byte(schema.String())
+	if filepath.Ext(fileName) == ".json" {
+		var err error
+		if b, err = json.MarshalIndent(schema, "", " "); err != nil {
+			return fmt.Errorf("could not encode the schema: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(fileName, b, 0644); err != nil {
+		return fmt.Errorf("could not write the schema file: %w", err)
+	}
+
 	return nil
 }
 
+func redoApplyCmdF(cmd *cobra.Command, _ []string) error {
+	steps, _ := cmd.Flags().GetInt("number")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	morph.InfoLogger.Printf("Attempting to redo %d migrations...\n", steps)
+	n, err := apply.Redo(ctx, steps, parseEssentialFlags(cmd), parseEngineFlags(cmd)...)
+	if n > 0 {
+		morph.SuccessLogger.Printf("%d migrations re-applied.\n", n)
+	} else if n == 0 {
+		morph.InfoLogger.Println("no migrations re-applied.")
+	}
+	return err
+}
+
+func baselineApplyCmdF(cmd *cobra.Command, args []string) error {
+	version, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version %q: %w", args[0], err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	morph.InfoLogger.Printf("Marking migrations up to version %d as applied...\n", version)
+	n, err := apply.Baseline(ctx, version, parseEssentialFlags(cmd), parseEngineFlags(cmd)...)
+	if n > 0 {
+		morph.SuccessLogger.Printf("%d migrations marked as applied.\n", n)
+	} else if n == 0 {
+		morph.InfoLogger.Println("no migrations marked as applied.")
+	}
+	return err
+}
+
 func planApplyCmdF(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -150,13 +259,14 @@ func planApplyCmdF(cmd *cobra.Command, args []string) error {
 	}
 
 	revert, _ := cmd.Flags().GetBool("revert")
+	resume, _ := cmd.Flags().GetBool("resume")
 
 	if revert {
 		morph.SwapPlanDirection(&plan)
 	}
 
 	morph.InfoLogger.Printf("Attempting to apply plan...\n")
-	err = apply.Plan(ctx, &plan, parseEssentialFlags(cmd), parseEngineFlags(cmd)...)
+	err = apply.Plan(ctx, &plan, parseEssentialFlags(cmd), append(parseEngineFlags(cmd), morph.SetPlanResume(resume))...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error applying plan: %s", err.Error())
 		return err
@@ -166,12 +276,89 @@ func planApplyCmdF(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func journalApplyCmdF(cmd *cobra.Command, _ []string) error {
+	limit, _ := cmd.Flags().GetInt("number")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs, err := apply.PlanRuns(ctx, limit, parseEssentialFlags(cmd), parseEngineFlags(cmd)...)
+	if err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		morph.InfoLogger.Println("no plan runs recorded.")
+		return nil
+	}
+
+	for _, run := range runs {
+		morph.InfoLogger.Printf("== run %s of plan %s, started at %s: %s\n", run.RunID, run.PlanID, run.StartedAt().Format(time.RFC3339), run.Status())
+		for _, step := range run.Steps {
+			kind := "apply"
+			if step.Rollback {
+				kind = "rollback"
+			}
+			morph.InfoLoggerLight.Printf("\t%d. %s %s (%s): %s", step.Sequence, kind, step.Name, step.Direction, step.Outcome)
+			if !step.FinishedAt.IsZero() {
+				morph.InfoLoggerLight.Printf(" in %s", step.FinishedAt.Sub(step.StartedAt))
+			}
+			if step.Error != "" {
+				morph.InfoLoggerLight.Printf(", error: %s", step.Error)
+			}
+			morph.InfoLoggerLight.Println()
+		}
+	}
+
+	return nil
+}
+
+// addEngineFlags adds the optional engine flags read by parseEngineFlags.
+func addEngineFlags(flags *pflag.FlagSet) {
+	flags.IntP("timeout", "t", 60, "the timeout in seconds for each migration file to run")
+	flags.StringP("migrations-table", "m", "db_migrations", "the name of the migrations table")
+	flags.StringP("lock-key", "l", "mutex_migrations", "the name of the mutex key")
+	flags.Bool("dry-run", false, "prints the plan without applying it")
+	flags.String("env", "", "the environment, migrations restricted to other environments are recorded without running")
+	flags.StringSlice("tags", nil, "the active tags, tagged migrations without an active tag are recorded without running")
+	flags.String("out-of-order", string(morph.OutOfOrderAllow), "what to do with pending migrations older than the latest applied one: strict, allow or ignore")
+	flags.BoolP("yes", "y", false, "applies the migrations that require a confirmation without asking")
+	addMetadataFlags(flags)
+	addTemplateFlags(flags)
+}
+
+// addMetadataFlags adds the flags read by parseMetadataFlags, which locate the
+// tables morph keeps its state in.
+func addMetadataFlags(flags *pflag.FlagSet) {
+	flags.String("metadata-schema", "", "the schema, or the database on MySQL, of the migrations and mutex tables, the current one if empty")
+	flags.String("mutex-table", "", "the name of the mutex table, db_lock if empty")
+}
+
+// parseMetadataFlags parses the flags added by addMetadataFlags.
+func parseMetadataFlags(cmd *cobra.Command) []morph.EngineOption {
+	metadataSchema, _ := cmd.Flags().GetString("metadata-schema")
+	mutexTable, _ := cmd.Flags().GetString("mutex-table")
+
+	// the drivers that do not support these settings only fail if they are set
+	var options []morph.EngineOption
+	if metadataSchema != "" {
+		options = append(options, morph.SetMetadataSchema(metadataSchema))
+	}
+	if mutexTable != "" {
+		options = append(options, morph.SetMutexTableName(mutexTable))
+	}
+
+	return options
+}
+
 // parseEssentialFlags parses the essential flags for the apply command.
-// which are the DSN, the driver and the source path.
+// which are the DSN, the driver and the source path or url.
 func parseEssentialFlags(cmd *cobra.Command) apply.ConnectionParameters {
 	dsn, _ := cmd.Flags().GetString("dsn")
 	driverName, _ := cmd.Flags().GetString("driver")
 	path, _ := cmd.Flags().GetString("path")
+	if source, _ := cmd.Flags().GetString("source"); source != "" {
+		path = source
+	}
 
 	return apply.ConnectionParameters{
 		DSN:        dsn,
@@ -186,11 +373,25 @@ func parseEngineFlags(cmd *cobra.Command) []morph.EngineOption {
 	tableName, _ := cmd.Flags().GetString("migrations-table")
 	mutexKey, _ := cmd.Flags().GetString("lock-key")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	env, _ := cmd.Flags().GetString("env")
+	tags, _ := cmd.Flags().GetStringSlice("tags")
+	outOfOrder, _ := cmd.Flags().GetString("out-of-order")
 
-	return []morph.EngineOption{
+	options := []morph.EngineOption{
 		morph.SetMigrationTableName(tableName),
 		morph.SetStatementTimeoutInSeconds(timeout),
 		morph.WithLock(mutexKey),
 		morph.SetDryRun(dryRun),
+		morph.SetEnvironment(env),
+		morph.WithTags(tags...),
+		parseConfirmFlags(cmd),
+	}
+
+	if outOfOrder != "" {
+		options = append(options, morph.SetOutOfOrderPolicy(morph.OutOfOrderPolicy(outOfOrder)))
 	}
+
+	options = append(options, parseMetadataFlags(cmd)...)
+
+	return append(options, parseTemplateFlags(cmd)...)
 }
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/models"
+	"github.com/spf13/cobra"
+)
+
+// parseConfirmFlags returns the engine option that confirms the migrations
+// with the confirm directive, either by asking on the terminal or, if the
+// --yes flag is set, without asking.
+func parseConfirmFlags(cmd *cobra.Command) morph.EngineOption {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return morph.WithConfirmation(func(*models.Migration) (bool, error) {
+			return true, nil
+		})
+	}
+
+	return morph.WithConfirmation(confirmOnTerminal)
+}
+
+func confirmOnTerminal(migration *models.Migration) (bool, error) {
+	morph.InfoLogger.Printf("Migration %s requires a confirmation. Apply it? [y/N] ", migration.Name)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("could not read the answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/apply"
+	"github.com/spf13/cobra"
+)
+
+func CheckDriftCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-drift",
+		Short: "Compares the schema of the database with the schema its migrations produce",
+		Long: "Compares the schema of the database with the schema obtained by applying the same migrations\n" +
+			"to a scratch database, and reports added, removed and changed objects.\n" +
+			"The command fails if the schemas differ.",
+		Example:       "morph check-drift --driver postgres --dsn postgres://localhost:5432/morph --path db/migrations/postgres",
+		RunE:          checkDriftCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	cmd.Flags().StringP("driver", "d", "", "the database driver of the migrations, resolved from the scheme of the dsn if not set")
+	cmd.Flags().String("dsn", "", "the dsn of the database")
+	_ = cmd.MarkFlagRequired("dsn")
+	cmd.Flags().StringP("path", "p", "", "the source path of the migrations")
+	cmd.Flags().String("source", "", "the url of the migrations, e.g. tar://migrations.tar.gz, used instead of the path")
+
+	cmd.Flags().IntP("timeout", "t", 60, "the timeout in seconds for each migration file to run")
+	cmd.Flags().StringP("migrations-table", "m", "db_migrations", "the name of the migrations table")
+	cmd.Flags().String("env", "", "the environment, migrations restricted to other environments are not replayed")
+	addMetadataFlags(cmd.Flags())
+	addTemplateFlags(cmd.Flags())
+
+	return cmd
+}
+
+func checkDriftCmdF(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timeout, _ := cmd.Flags().GetInt("timeout")
+	tableName, _ := cmd.Flags().GetString("migrations-table")
+	env, _ := cmd.Flags().GetString("env")
+	options := append([]morph.EngineOption{
+		morph.SetMigrationTableName(tableName),
+		morph.SetStatementTimeoutInSeconds(timeout),
+		morph.SetEnvironment(env),
+	}, parseMetadataFlags(cmd)...)
+	options = append(options, parseTemplateFlags(cmd)...)
+
+	morph.InfoLogger.Println("Checking the database schema for drift...")
+	diff, err := apply.CheckDrift(ctx, parseEssentialFlags(cmd), options...)
+	if err != nil {
+		return err
+	}
+
+	if diff.HasChanges() {
+		morph.ErrorLogger.Print(diff.String())
+		return fmt.Errorf("the schema of the database has drifted: %d objects differ", len(diff.Changes))
+	}
+	morph.SuccessLogger.Println("The schema of the database matches its migrations.")
+
+	return nil
+}
@@ -98,17 +98,21 @@ func NewPlanCmd() *cobra.Command {
 	cmd.Flags().IntP("number", "n", 0, "plan for only N migrations")
 	cmd.Flags().Bool("auto", true, "generate plan with auto revert steps")
 
-	cmd.Flags().StringP("driver", "d", "", "the database driver of the migrations")
-	_ = cmd.MarkFlagRequired("driver")
+	cmd.Flags().StringP("driver", "d", "", "the database driver of the migrations, resolved from the scheme of the dsn if not set")
 	cmd.Flags().String("dsn", "", "the dsn of the database")
 	_ = cmd.MarkFlagRequired("dsn")
 
 	cmd.Flags().StringP("path", "p", "", "the source path of the migrations")
-	_ = cmd.MarkFlagRequired("path")
+	cmd.Flags().String("source", "", "the url of the migrations, e.g. tar://migrations.tar.gz, used instead of the path")
 
 	cmd.Flags().IntP("timeout", "t", 60, "the timeout in seconds for each migration file to run")
 	cmd.Flags().StringP("migrations-table", "m", "db_migrations", "the name of the migrations table")
 	cmd.Flags().StringP("lock-key", "l", "mutex_migrations", "the name of the mutex key")
+	cmd.Flags().String("env", "", "the environment, migrations restricted to other environments are left out of the plan")
+	cmd.Flags().StringSlice("tags", nil, "the active tags, tagged migrations without an active tag are left out of the plan")
+	cmd.Flags().String("out-of-order", string(morph.OutOfOrderAllow), "what to do with pending migrations older than the latest applied one: strict, allow or ignore")
+	addMetadataFlags(cmd.Flags())
+	addTemplateFlags(cmd.Flags())
 
 	return cmd
 }
@@ -129,116 +133,292 @@ func generateNewDriver(driverName string) error {
 	morph.InfoLoggerLight.Printf("== CreateDriver: %q: generating driver files\n", driverName)
 	morph.InfoLoggerLight.Println("=================================================")
 
-	f := NewFile(driverName)
+	driverDir := filepath.Join(baseDriverPath, driverName)
+	files := []struct {
+		name string
+		file *File
+	}{
+		{name: driverName + ".go", file: newDriverFile(driverName)},
+		{name: driverName + "_test.go", file: newDriverTestFile(driverName)},
+	}
+
+	morph.InfoLoggerLight.Printf("\t-- create_dir(%s)\n", driverDir)
+	if err := os.Mkdir(driverDir, 0755); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		fileName := filepath.Join(driverDir, f.name)
+		morph.InfoLoggerLight.Printf("\t-- create_file(%s)\n", fileName)
+		if err := f.file.Save(fileName); err != nil {
+			return err
+		}
+	}
+
+	morph.InfoLoggerLight.Printf("== CreateDriver: %q: driver files generated\n", driverName)
+	morph.InfoLoggerLight.Println("=================================================")
+
+	morph.SuccessLogger.Printf("Now you can start implementing your new driver under %q.\nThanks in advance, for contributing.\n", driverDir)
+
+	return nil
+}
 
+// newDriverFile generates the skeleton of a driver that implements the
+// drivers.Driver and drivers.Lockable interfaces, registers itself and parses
+// the configuration parameters of its connection URL like the in-tree drivers.
+func newDriverFile(driverName string) *File {
+	const (
+		driversPkg = "github.com/mattermost/morph/drivers"
+		modelsPkg  = "github.com/mattermost/morph/models"
+	)
+
+	f := NewFile(driverName)
 	f.ImportNames(map[string]string{
-		"github.com/mattermost/morph/drivers": "drivers",
-		"github.com/mattermost/morph/models":  "models",
+		driversPkg: "drivers",
+		modelsPkg:  "models",
 	})
 
-	f.Var().Add(Id("driverName")).Op("=").Add(Lit(driverName))
-	f.Var().Add(Id("defaultConfig")).Op("=").Add(Op("&")).Id("Config").Values(Dict{
-		Id("MigrationsTable"): Lit("db_migrations"),
-	})
+	receiver := func() *Statement {
+		return Params(Id("driver").Op("*").Id(driverName))
+	}
+	appError := func(message string) *Statement {
+		return Op("&").Qual(driversPkg, "AppError").Values(Dict{
+			Id("Driver"):  Id("driverName"),
+			Id("OrigErr"): Err(),
+			Id("Message"): Lit(message),
+		})
+	}
+
+	f.Const().Id("driverName").Op("=").Lit(driverName)
+	f.Const().Id("defaultMigrationMaxSize").Op("=").Lit(10).Op("*").Lit(1).Op("<<").Lit(20).Comment("10 MB")
+	f.Line()
 	f.Comment("add here any custom driver configuration")
-	f.Var().Add(Id("configParams")).Op("=").Add(Index().String().Values())
+	f.Var().Id("configParams").Op("=").Index().String().Values(
+		Line().Lit("x-migration-max-size"),
+		Line().Lit("x-migrations-table"),
+		Line().Lit("x-statement-timeout").Op(",").Line(),
+	)
 
+	f.Line()
+	f.Var().Defs(
+		Id("_").Qual(driversPkg, "Driver").Op("=").Op("&").Id(driverName).Values(),
+		Id("_").Qual(driversPkg, "Lockable").Op("=").Op("&").Id(driverName).Values(),
+	)
+
+	f.Line()
 	f.Func().Id("init").Params().Block(
-		Qual("github.com/mattermost/morph/drivers", "Register").Call(Lit(driverName), Id("&"+driverName).Values()),
+		Qual(driversPkg, "Register").Call(Id("driverName"), Id("Open")),
 	)
 
 	f.Line()
-	f.Type().Id("Config").Struct(
-		Id("MigrationsTable").String(),
+	f.Type().Id("driverConfig").Struct(
+		Qual(driversPkg, "Config"),
 		Comment("Add more properties here"),
 	)
 
 	f.Line()
 	f.Type().Id(driverName).Struct(
-		Id("config").Add(Id("*Config")),
-		Comment("Add more properties here"),
+		Id("config").Op("*").Id("driverConfig"),
+		Comment("Add more properties here, such as the connection to the storage"),
 	)
 
 	f.Line()
-	f.Func().Id("WithInstance").Params(Id("dbInstance").Interface(), Id("config").Add(Id("*Config"))).Params(Id("drivers.Driver"), Id("error")).BlockFunc(func(g *Group) {
-		g.Return(Op("&").Id(driverName).Values(Dict{
-			Id("config"): Id("config"),
-		}),
-			Nil(),
-		)
-	})
+	f.Func().Id("getDefaultConfig").Params().Op("*").Id("driverConfig").Block(
+		Return(Op("&").Id("driverConfig").Values(Dict{
+			Id("Config"): Qual(driversPkg, "Config").Values(Dict{
+				Id("MigrationsTable"):        Lit("db_migrations"),
+				Id("StatementTimeoutInSecs"): Lit(60),
+				Id("MigrationMaxSize"):       Id("defaultMigrationMaxSize"),
+			}),
+		})),
+	)
 
 	f.Line()
-	f.Comment("Implement bellow all the methods of the driver interface in order")
-	f.Comment("to complete the driver functionality")
+	f.Comment("WithInstance creates the driver from an existing connection to the storage.")
+	f.Func().Id("WithInstance").Params(Id("dbInstance").Interface()).Params(Qual(driversPkg, "Driver"), Error()).Block(
+		Comment("Keep the connection to the storage in the driver."),
+		Return(Op("&").Id(driverName).Values(Dict{
+			Id("config"): Id("getDefaultConfig").Call(),
+		}), Nil()),
+	)
 
-	f.Func().Params(Id("driver").Id("*"+driverName)).Id("Open").Params(Id("connURL").String()).Params(Id("drivers.Driver"), Id("error")).BlockFunc(func(g *Group) {
-		g.Comment("Implement creation of the driver based on a connection URL.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Line()
+	f.Comment("Open creates the driver from a connection URL. The custom parameters of the")
+	f.Comment("URL override the default configuration and are removed from the URL.")
+	f.Func().Id("Open").Params(Id("connURL").String()).Params(Qual(driversPkg, "Driver"), Error()).Block(
+		List(Id("customParams"), Err()).Op(":=").Qual(driversPkg, "ExtractCustomParams").Call(Id("connURL"), Id("configParams")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), appError("failed to parse custom parameters from url")),
+		),
+		Line(),
+		List(Id("sanitizedConnURL"), Err()).Op(":=").Qual(driversPkg, "RemoveParamsFromURL").Call(Id("connURL"), Id("configParams")),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), appError("failed to sanitize url from custom parameters")),
+		),
+		Line(),
+		List(Id("driverConfig"), Err()).Op(":=").Id("mergeConfigWithParams").Call(Id("customParams"), Id("getDefaultConfig").Call()),
+		If(Err().Op("!=").Nil()).Block(
+			Return(Nil(), appError("failed to merge custom params to driver config")),
+		),
+		Line(),
+		Id("driver").Op(":=").Op("&").Id(driverName).Values(Dict{
+			Id("config"): Id("driverConfig"),
+		}),
+		If(Err().Op(":=").Id("driver").Dot("connect").Call(Id("sanitizedConnURL")), Err().Op("!=").Nil()).Block(
+			Return(Nil(), Err()),
+		),
+		Line(),
+		Return(Id("driver"), Nil()),
+	)
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*" + driverName)).Id("Ping").Params().Error().BlockFunc(func(g *Group) {
-		g.Comment("Implement storage connection health check functionality.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Func().Id("mergeConfigWithParams").Params(Id("params").Map(String()).String(), Id("config").Op("*").Id("driverConfig")).Params(Op("*").Id("driverConfig"), Error()).Block(
+		Var().Err().Error(),
+		Line(),
+		For(List(Id("_"), Id("configKey")).Op(":=").Range().Id("configParams")).Block(
+			If(List(Id("v"), Id("ok")).Op(":=").Id("params").Index(Id("configKey")), Id("ok")).Block(
+				Switch(Id("configKey")).Block(
+					Case(Lit("x-migration-max-size")).Block(
+						If(List(Id("config").Dot("MigrationMaxSize"), Err()).Op("=").Qual("strconv", "Atoi").Call(Id("v")), Err().Op("!=").Nil()).Block(
+							Return(Nil(), Qual("fmt", "Errorf").Call(Lit("failed to cast config param %s of %s"), Id("configKey"), Id("v"))),
+						),
+					),
+					Case(Lit("x-migrations-table")).Block(
+						If(Err().Op("=").Qual(driversPkg, "ValidateTableName").Call(Id("v")), Err().Op("!=").Nil()).Block(
+							Return(Nil(), Err()),
+						),
+						Id("config").Dot("MigrationsTable").Op("=").Id("v"),
+					),
+					Case(Lit("x-statement-timeout")).Block(
+						If(List(Id("config").Dot("StatementTimeoutInSecs"), Err()).Op("=").Qual("strconv", "Atoi").Call(Id("v")), Err().Op("!=").Nil()).Block(
+							Return(Nil(), Qual("fmt", "Errorf").Call(Lit("failed to cast config param %s of %s"), Id("configKey"), Id("v"))),
+						),
+					),
+				),
+			),
+		),
+		Line(),
+		Return(Id("config"), Nil()),
+	)
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*" + driverName)).Id("Close").Params().Error().BlockFunc(func(g *Group) {
-		g.Comment("Implement functionality for tearing down the driver.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Comment("Implement below all the methods of the driver interface in order")
+	f.Comment("to complete the driver functionality")
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*" + driverName)).Id("Lock").Params().Error().BlockFunc(func(g *Group) {
-		g.Comment("Implement functionality that ensures atomicity of the driver operations.")
-		g.Comment("If the target storage does not need these guarantees, you can void this method.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Func().Add(receiver()).Id("connect").Params(Id("connURL").String()).Error().Block(
+		Comment("Implement connecting to the storage with the sanitized connection URL."),
+		Panic(Lit("implement me")),
+	)
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*" + driverName)).Id("Unlock").Params().Error().BlockFunc(func(g *Group) {
-		g.Comment("The inverse of the Lock function above.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Func().Add(receiver()).Id("Ping").Params().Error().Block(
+		Comment("Implement storage connection health check functionality."),
+		Panic(Lit("implement me")),
+	)
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*" + driverName)).Id("CreateSchemaTableIfNotExists").Params().Error().BlockFunc(func(g *Group) {
-		g.Comment("Implement the creation of the migrations table onto the storage.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Func().Add(receiver()).Id("Close").Params().Error().Block(
+		Comment("Implement functionality for tearing down the driver."),
+		Panic(Lit("implement me")),
+	)
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*" + driverName)).Id("Apply").Params(Id("migration").Op("*").Qual("github.com/mattermost/morph/models", "Migration")).Error().BlockFunc(func(g *Group) {
-		g.Comment("Implement the functionality for apply the migration onto the storage.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Func().Add(receiver()).Id("Apply").Params(Id("migration").Op("*").Qual(modelsPkg, "Migration"), Id("saveVersion").Bool()).Error().Block(
+		Comment("Implement applying the migration onto the storage. The query of the migration"),
+		Comment("is not executed if migration.RecordOnly is set, and its version is saved, or"),
+		Comment("deleted for down migrations, if saveVersion is set. Both should happen in a"),
+		Comment("single transaction unless the migration has the nontransactional directive."),
+		Comment("Create the migrations table named driver.config.MigrationsTable if it does not exist."),
+		Comment("Quote the table name and bind the version and the name of the migration as"),
+		Comment("parameters of the queries of the migrations table."),
+		Panic(Lit("implement me")),
+	)
 
 	f.Line()
-	f.Func().Params(Id("driver").Id("*"+driverName)).Id("AppliedMigrations").Params().Params(Op("[]*").Qual("github.com/mattermost/morph/models", "Migration"), Error()).BlockFunc(func(g *Group) {
-		g.Comment("Implement the functionality that returns which migrations has already been applied in storage.")
-		g.Panic(Lit("implement me"))
-	})
+	f.Func().Add(receiver()).Id("AppliedMigrations").Params().Params(Index().Op("*").Qual(modelsPkg, "Migration"), Error()).Block(
+		Comment("Implement the functionality that returns which migrations has already been applied in storage,"),
+		Comment("sorted by version."),
+		Panic(Lit("implement me")),
+	)
 
-	driverDir := filepath.Join(baseDriverPath, driverName)
-	driverFile := filepath.Join(driverDir, driverName+".go")
+	f.Line()
+	f.Func().Add(receiver()).Id("SetConfig").Params(Id("key").String(), Id("value").Interface()).Error().Block(
+		If(Id("driver").Dot("config").Op("!=").Nil()).Block(
+			Switch(Id("key")).Block(
+				Case(Lit("StatementTimeoutInSecs")).Block(
+					List(Id("n"), Id("ok")).Op(":=").Id("value").Assert(Int()),
+					If(Id("ok")).Block(
+						Id("driver").Dot("config").Dot("StatementTimeoutInSecs").Op("=").Id("n"),
+						Return(Nil()),
+					),
+					Return(Qual("fmt", "Errorf").Call(Lit("incorrect value type for %s"), Id("key"))),
+				),
+				Case(Lit("MigrationsTable")).Block(
+					List(Id("n"), Id("ok")).Op(":=").Id("value").Assert(String()),
+					If(Id("ok")).Block(
+						If(Err().Op(":=").Qual(driversPkg, "ValidateTableName").Call(Id("n")), Err().Op("!=").Nil()).Block(
+							Return(Err()),
+						),
+						Id("driver").Dot("config").Dot("MigrationsTable").Op("=").Id("n"),
+						Return(Nil()),
+					),
+					Return(Qual("fmt", "Errorf").Call(Lit("incorrect value type for %s"), Id("key"))),
+				),
+			),
+		),
+		Line(),
+		Return(Qual("fmt", "Errorf").Call(Lit("incorrect key name %q"), Id("key"))),
+	)
 
-	morph.InfoLoggerLight.Printf("\t-- create_dir(%s)\n", driverDir)
-	if err := os.Mkdir(driverDir, 0755); err != nil {
-		return err
-	}
+	f.Line()
+	f.Func().Add(receiver()).Id("NewMutex").Params(Id("key").String(), Id("logger").Qual(driversPkg, "Logger")).Params(Qual(driversPkg, "Locker"), Error()).Block(
+		Comment("Implement a mutex that ensures the migrations are applied from a single instance."),
+		Comment("If the target storage does not need these guarantees, remove this method, since"),
+		Comment("the drivers.Lockable interface is optional."),
+		Panic(Lit("implement me")),
+	)
 
-	morph.InfoLoggerLight.Printf("\t-- create_file(%s)\n", driverFile)
-	if err := f.Save(driverFile); err != nil {
-		return err
-	}
+	return f
+}
 
-	morph.InfoLoggerLight.Printf("== CreateDriver: %q: driver files generated\n", driverName)
-	morph.InfoLoggerLight.Println("=================================================")
+// newDriverTestFile generates the test of a driver, which runs against the
+// storage at the connection URL set in an environment variable.
+func newDriverTestFile(driverName string) *File {
+	envVar := "MORPH_" + strings.ToUpper(driverName) + "_TEST_URL"
 
-	morph.SuccessLogger.Printf("Now you can start implementing your new driver under %q.\nThanks in advance, for contributing.\n", driverDir)
+	f := NewFile(driverName)
+	f.HeaderComment("//go:build !sources && drivers\n// +build !sources,drivers")
+	f.ImportNames(map[string]string{
+		"github.com/mattermost/morph/drivers":            "drivers",
+		"github.com/mattermost/morph/drivers/drivertest": "drivertest",
+		"github.com/stretchr/testify/require":            "require",
+	})
 
-	return nil
+	f.Comment("TestConformance runs the driver conformance suite against the storage at the")
+	f.Comment("connection URL set in " + envVar + ". The database is expected to be empty.")
+	f.Func().Id("TestConformance").Params(Id("t").Op("*").Qual("testing", "T")).Block(
+		Id("connURL").Op(":=").Qual("os", "Getenv").Call(Lit(envVar)),
+		If(Id("connURL").Op("==").Lit("")).Block(
+			Id("t").Dot("Skip").Call(Lit(envVar+" is not set")),
+		),
+		Line(),
+		Qual("github.com/mattermost/morph/drivers/drivertest", "RunConformance").Call(
+			Id("t"),
+			Func().Params(Id("t").Op("*").Qual("testing", "T")).Qual("github.com/mattermost/morph/drivers", "Driver").Block(
+				List(Id("driver"), Err()).Op(":=").Qual("github.com/mattermost/morph/drivers", "OpenByName").Call(Id("driverName"), Id("connURL")),
+				Qual("github.com/stretchr/testify/require", "NoError").Call(Id("t"), Err()),
+				Id("t").Dot("Cleanup").Call(Func().Params().Block(
+					Qual("github.com/stretchr/testify/require", "NoError").Call(Id("t"), Id("driver").Dot("Close").Call()),
+				)),
+				Line(),
+				Return(Id("driver")),
+			),
+			Qual("github.com/mattermost/morph/drivers/drivertest", "WithConnURL").Call(Id("driverName"), Id("connURL")),
+		),
+	)
+
+	return f
 }
 
 func generateScriptCmdF(cmd *cobra.Command, args []string) {
@@ -1,14 +1,23 @@
 package commands
 
 import (
+	"bytes"
 	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/mattermost/morph/commands/testlib"
+	"github.com/mattermost/morph/drivers"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
+
+	. "github.com/dave/jennifer/jen"
 )
 
 func TestGenerateCMD(t *testing.T) {
@@ -81,6 +90,14 @@ func TestGenerateCMD(t *testing.T) {
 		}
 	})
 
+	t.Run("should require the driver", func(t *testing.T) {
+		_, err := testlib.ExecuteCommand(t, NewScriptCmd(), "create_saiyans", "--sequence")
+		require.EqualError(t, err, `required flag(s) "driver" not set`)
+
+		_, err = os.Stat(dir)
+		require.True(t, errors.Is(err, os.ErrNotExist))
+	})
+
 	t.Run("should correctly return extension", func(t *testing.T) {
 		ext := getExtension("postgres")
 		require.Equal(t, ext, "sql")
@@ -95,3 +112,67 @@ func TestGenerateCMD(t *testing.T) {
 		require.Equal(t, ext, "txt")
 	})
 }
+
+func TestGenerateDriver(t *testing.T) {
+	// methods returns the names of the methods declared in the rendered file
+	methods := func(t *testing.T, f *File) map[string]bool {
+		var b bytes.Buffer
+		require.NoError(t, f.Render(&b))
+
+		file, err := parser.ParseFile(token.NewFileSet(), "", b.Bytes(), 0)
+		require.NoError(t, err)
+
+		methods := make(map[string]bool)
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil {
+				methods[fn.Name.Name] = true
+			}
+		}
+		return methods
+	}
+
+	t.Run("should implement the methods of the driver interfaces", func(t *testing.T) {
+		declared := methods(t, newDriverFile("foo"))
+
+		for _, iface := range []reflect.Type{
+			reflect.TypeOf((*drivers.Driver)(nil)).Elem(),
+			reflect.TypeOf((*drivers.Lockable)(nil)).Elem(),
+		} {
+			for i := 0; i < iface.NumMethod(); i++ {
+				require.Truef(t, declared[iface.Method(i).Name], "method %s of %s is missing", iface.Method(i).Name, iface.Name())
+			}
+		}
+	})
+
+	t.Run("should render the test file", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, newDriverTestFile("foo").Render(&b))
+		require.Contains(t, b.String(), "//go:build !sources && drivers")
+		require.Contains(t, b.String(), `os.Getenv("MORPH_FOO_TEST_URL")`)
+		require.Contains(t, b.String(), "drivertest.RunConformance(t, func(t *testing.T) drivers.Driver {")
+	})
+}
+
+func TestMetadataFlags(t *testing.T) {
+	commands := map[string]func() *cobra.Command{
+		"drift":  CheckDriftCmd,
+		"plan":   NewPlanCmd,
+		"seed":   SeedCmd,
+		"squash": SquashCmd,
+		"test":   RoundTripCmd,
+	}
+
+	for name, newCmd := range commands {
+		t.Run("should set the metadata tables of "+name, func(t *testing.T) {
+			cmd := newCmd()
+			require.NoError(t, cmd.ParseFlags([]string{"--metadata-schema", "morph", "--mutex-table", "morph_lock"}))
+			require.Len(t, parseMetadataFlags(cmd), 2)
+		})
+	}
+
+	t.Run("should not set the metadata tables without the flags", func(t *testing.T) {
+		cmd := SquashCmd()
+		require.NoError(t, cmd.ParseFlags(nil))
+		require.Empty(t, parseMetadataFlags(cmd))
+	})
+}
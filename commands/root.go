@@ -17,6 +17,10 @@ func RootCmd() *cobra.Command {
 		ApplyCmd(),
 		NewCmd(),
 		NewGenerateCmd(),
+		RoundTripCmd(),
+		CheckDriftCmd(),
+		SquashCmd(),
+		SeedCmd(),
 	)
 
 	return cmd
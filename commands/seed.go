@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/apply"
+	"github.com/spf13/cobra"
+)
+
+func SeedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Applies the seeds after the pending migrations",
+		Long: "Applies all pending migrations, then the seeds of the seeds directory that are new or have changed\n" +
+			"since they were last applied, in the order of their file names. Seeds are either SQL scripts or JSON\n" +
+			"files with rows to upsert, and can be restricted to environments and tags like migrations.",
+		Example:       "morph seed --driver postgres --dsn postgres://localhost:5432/morph --path db/migrations/postgres --seeds db/seeds/postgres --env dev",
+		RunE:          seedCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	cmd.Flags().StringP("driver", "d", "", "the database driver of the migrations, resolved from the scheme of the dsn if not set")
+	cmd.Flags().String("dsn", "", "the dsn of the database")
+	_ = cmd.MarkFlagRequired("dsn")
+	cmd.Flags().StringP("path", "p", "", "the source path of the migrations")
+	cmd.Flags().String("source", "", "the url of the migrations, e.g. tar://migrations.tar.gz, used instead of the path")
+	cmd.Flags().String("seeds", "", "the source path of the seeds")
+	_ = cmd.MarkFlagRequired("seeds")
+
+	addEngineFlags(cmd.Flags())
+
+	return cmd
+}
+
+func seedCmdF(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	seedsPath, _ := cmd.Flags().GetString("seeds")
+
+	morph.InfoLogger.Println("Applying all pending migrations and seeds...")
+	n, err := apply.Seed(ctx, seedsPath, parseEssentialFlags(cmd), parseEngineFlags(cmd)...)
+	if n > 0 {
+		morph.SuccessLogger.Printf("%d seeds applied.\n", n)
+	} else if n == 0 {
+		morph.InfoLogger.Println("no seeds applied.")
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/apply"
+	"github.com/mattermost/morph/models"
+	"github.com/spf13/cobra"
+)
+
+func SquashCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "squash",
+		Short: "Consolidates the migrations up to a version into a single migration",
+		Long: "Applies the migrations up to and including the given version to a scratch database, and writes\n" +
+			"a squashed migration that creates the resulting schema at once next to them. Fresh databases run\n" +
+			"only the squashed migration, while databases past the squash point are consistent with it.\n" +
+			"Keep the replaced migrations until every database has been migrated past the squash point.\n" +
+			"If no dsn is given, a temporary SQLite database is used.",
+		Example:       "morph squash --driver postgres --dsn postgres://localhost:5432/morph_scratch --path db/migrations/postgres --upto 120",
+		RunE:          squashCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	cmd.Flags().StringP("driver", "d", "", "the database driver of the migrations, resolved from the scheme of the dsn if not set, sqlite without a dsn")
+	cmd.Flags().String("dsn", "", "the dsn of the database providing the scratch database, a temporary database is used for sqlite if not set")
+	cmd.Flags().StringP("path", "p", "", "the source path of the migrations")
+	_ = cmd.MarkFlagRequired("path")
+	cmd.Flags().Uint64("upto", 0, "the version of the last migration to squash")
+	_ = cmd.MarkFlagRequired("upto")
+
+	cmd.Flags().IntP("timeout", "t", 60, "the timeout in seconds for each migration file to run")
+	cmd.Flags().StringP("migrations-table", "m", "db_migrations", "the name of the migrations table")
+	cmd.Flags().String("env", "", "the environment, migrations restricted to other environments are not squashed")
+	cmd.Flags().StringSlice("tags", nil, "the active tags, tagged migrations without an active tag are not squashed")
+	addMetadataFlags(cmd.Flags())
+	addTemplateFlags(cmd.Flags())
+
+	return cmd
+}
+
+func squashCmdF(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	params := parseEssentialFlags(cmd)
+	if params.DSN == "" {
+		if params.DriverName != "" && params.DriverName != "sqlite" {
+			return fmt.Errorf("a dsn is required for driver %s", params.DriverName)
+		}
+		params.DriverName = "sqlite"
+
+		f, err := os.CreateTemp("", "morph-squash-*.db")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+
+		params.DSN = f.Name()
+	}
+
+	version, _ := cmd.Flags().GetUint64("upto")
+	timeout, _ := cmd.Flags().GetInt("timeout")
+	tableName, _ := cmd.Flags().GetString("migrations-table")
+	env, _ := cmd.Flags().GetString("env")
+	tags, _ := cmd.Flags().GetStringSlice("tags")
+	options := append([]morph.EngineOption{
+		morph.SetMigrationTableName(tableName),
+		morph.SetStatementTimeoutInSeconds(timeout),
+		morph.SetEnvironment(env),
+		morph.WithTags(tags...),
+	}, parseMetadataFlags(cmd)...)
+	options = append(options, parseTemplateFlags(cmd)...)
+
+	morph.InfoLogger.Printf("Squashing the migrations up to version %d...\n", version)
+	up, down, err := apply.Squash(ctx, version, params, options...)
+	if err != nil {
+		return err
+	}
+
+	migrations := []*models.Migration{up, down}
+	for _, migration := range migrations {
+		fileName := filepath.Join(params.SourcePath, migration.RawName)
+		if _, err := os.Stat(fileName); err == nil {
+			return fmt.Errorf("file %s already exists", fileName)
+		}
+	}
+
+	for _, migration := range migrations {
+		fileName := filepath.Join(params.SourcePath, migration.RawName)
+		if err := os.WriteFile(fileName, migration.Bytes, 0644); err != nil {
+			return err
+		}
+		morph.InfoLoggerLight.Printf("== Squash: created %s\n", fileName)
+	}
+	morph.SuccessLogger.Printf("Migrations up to version %d have been squashed into %s.\n", version, up.Name)
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattermost/morph"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configFile is the content of the file given with the --config flag.
+type configFile struct {
+	// Variables are the template variables the migrations are rendered with.
+	Variables map[string]string `json:"variables"`
+}
+
+func addTemplateFlags(flags *pflag.FlagSet) {
+	flags.String("config", "", "a JSON file with the template variables of the migrations, e.g. {\"variables\": {\"prefix\": \"app_\"}}")
+	flags.StringArray("var", nil, "a template variable of the migrations in the key=value form, overrides the config file")
+	flags.Bool("strict-vars", false, "fails if a migration refers to an undefined template variable")
+}
+
+// parseTemplateFlags returns the engine options for rendering the migrations
+// as templates. The error of reading the flags is returned by the option, so
+// that it is reported when the engine is created.
+func parseTemplateFlags(cmd *cobra.Command) []morph.EngineOption {
+	vars, err := templateVariables(cmd)
+	if err != nil {
+		return []morph.EngineOption{func(*morph.Morph) error {
+			return err
+		}}
+	}
+
+	strict, _ := cmd.Flags().GetBool("strict-vars")
+	if vars == nil {
+		return []morph.EngineOption{morph.SetStrictTemplates(strict)}
+	}
+
+	return []morph.EngineOption{
+		morph.WithTemplateVariables(vars),
+		morph.SetStrictTemplates(strict),
+	}
+}
+
+func templateVariables(cmd *cobra.Command) (map[string]string, error) {
+	var vars map[string]string
+
+	if fileName, _ := cmd.Flags().GetString("config"); fileName != "" {
+		b, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("could not read config file: %w", err)
+		}
+
+		var config configFile
+		if err := json.Unmarshal(b, &config); err != nil {
+			return nil, fmt.Errorf("could not parse config file %s: %w", fileName, err)
+		}
+
+		vars = config.Variables
+		if vars == nil {
+			vars = map[string]string{}
+		}
+	}
+
+	flagVars, _ := cmd.Flags().GetStringArray("var")
+	for _, v := range flagVars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid template variable %q, expected key=value", v)
+		}
+
+		if vars == nil {
+			vars = map[string]string{}
+		}
+		vars[key] = value
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/apply"
+	"github.com/mattermost/morph/models"
+	"github.com/spf13/cobra"
+)
+
+func RoundTripCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Applies each migration up, down and up again to verify that it can be reverted",
+		Long: "Applies each migration up, down and up again to verify that it can be reverted.\n" +
+			"If no dsn is given, the migrations are tested against a temporary SQLite database.",
+		Example:       "morph test --driver postgres --dsn postgres://localhost:5432/morph_test --path db/migrations/postgres",
+		RunE:          roundTripCmdF,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	cmd.Flags().StringP("driver", "d", "", "the database driver of the migrations, resolved from the scheme of the dsn if not set, sqlite without a dsn")
+	cmd.Flags().String("dsn", "", "the dsn of the database, a temporary database is used for sqlite if not set")
+	cmd.Flags().StringP("path", "p", "", "the source path of the migrations")
+	_ = cmd.MarkFlagRequired("path")
+
+	cmd.Flags().IntP("timeout", "t", 60, "the timeout in seconds for each migration file to run")
+	cmd.Flags().StringP("migrations-table", "m", "db_migrations", "the name of the migrations table")
+	cmd.Flags().String("env", "", "the environment, migrations restricted to other environments are recorded without running")
+	cmd.Flags().StringSlice("tags", nil, "the active tags, tagged migrations without an active tag are recorded without running")
+	addMetadataFlags(cmd.Flags())
+	addTemplateFlags(cmd.Flags())
+
+	return cmd
+}
+
+func roundTripCmdF(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	params := parseEssentialFlags(cmd)
+	if params.DSN == "" {
+		if params.DriverName != "" && params.DriverName != "sqlite" {
+			return fmt.Errorf("a dsn is required for driver %s", params.DriverName)
+		}
+		params.DriverName = "sqlite"
+
+		f, err := os.CreateTemp("", "morph-test-*.db")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+
+		params.DSN = f.Name()
+	}
+
+	timeout, _ := cmd.Flags().GetInt("timeout")
+	tableName, _ := cmd.Flags().GetString("migrations-table")
+	env, _ := cmd.Flags().GetString("env")
+	tags, _ := cmd.Flags().GetStringSlice("tags")
+	options := append([]morph.EngineOption{
+		morph.SetMigrationTableName(tableName),
+		morph.SetStatementTimeoutInSeconds(timeout),
+		morph.SetEnvironment(env),
+		morph.WithTags(tags...),
+		// the round trip runs against a test database, nothing to confirm
+		morph.WithConfirmation(func(*models.Migration) (bool, error) {
+			return true, nil
+		}),
+	}, parseMetadataFlags(cmd)...)
+	options = append(options, parseTemplateFlags(cmd)...)
+
+	morph.InfoLogger.Println("Testing migrations up, down and up again...")
+	err := apply.RoundTrip(ctx, params, options...)
+	if err != nil {
+		return err
+	}
+	morph.SuccessLogger.Println("All migrations can be applied and reverted.")
+
+	return nil
+}
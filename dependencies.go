@@ -0,0 +1,247 @@
+package morph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/morph/models"
+)
+
+// dependencyGraph orders the migrations of the source by the dependencies they
+// declare with the depends-on directive.
+type dependencyGraph struct {
+	// rank is the position of each migration in the order they apply in.
+	rank      map[string]int
+	dependsOn map[string][]string
+}
+
+// dependencyGraph builds the graph of the up migrations of the source. The
+// migrations are ordered topologically, and the migrations that do not depend on
+// each other keep the order of their versions.
+func (m *Morph) dependencyGraph(set *migrationSet) (*dependencyGraph, error) {
+	var migrations []*models.Migration
+	for _, migration := range set.source {
+		if migration.Direction == models.Up {
+			migrations = append(migrations, migration)
+		}
+	}
+
+	g := &dependencyGraph{
+		rank:      make(map[string]int, len(migrations)),
+		dependsOn: make(map[string][]string),
+	}
+
+	known := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Name] = true
+	}
+
+	// the dependencies on migrations replaced by the squashed migration are met
+	replaced := make(map[string]bool)
+	if set.squash != nil {
+		for _, migration := range m.source.Migrations() {
+			if replaces(set.squash, migration) && !known[migration.Name] {
+				replaced[migration.Name] = true
+			}
+		}
+	}
+
+	for _, migration := range migrations {
+		directives, err := migration.Directives()
+		if err != nil {
+			return nil, err
+		}
+
+		var dependencies []string
+		for _, dependency := range directives.DependsOn {
+			if dependency == migration.Name {
+				return nil, fmt.Errorf("migration %s depends on itself", migration.Name)
+			}
+			if known[dependency] {
+				dependencies = append(dependencies, dependency)
+				continue
+			}
+			if replaced[dependency] {
+				continue
+			}
+			return nil, fmt.Errorf("migration %s depends on unknown migration %s", migration.Name, dependency)
+		}
+		if len(dependencies) > 0 {
+			g.dependsOn[migration.Name] = dependencies
+		}
+	}
+
+	// the ranking starts from the order of the versions, the names only break
+	// ties, so that 10_x comes after 9_y
+	remaining := migrations
+	sort.SliceStable(remaining, func(i, j int) bool {
+		if remaining[i].Version != remaining[j].Version {
+			return remaining[i].Version < remaining[j].Version
+		}
+		return remaining[i].RawName < remaining[j].RawName
+	})
+	for len(remaining) > 0 {
+		next := -1
+		for i, migration := range remaining {
+			if g.resolved(migration.Name) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("dependency cycle between migrations %s", strings.Join(g.cycle(remaining), " -> "))
+		}
+
+		g.rank[remaining[next].Name] = len(g.rank)
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+
+	return g, nil
+}
+
+// resolved reports whether all dependencies of the migration have been ranked.
+func (g *dependencyGraph) resolved(name string) bool {
+	for _, dependency := range g.dependsOn[name] {
+		if _, ok := g.rank[dependency]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cycle follows the unranked dependencies of the remaining migrations until a
+// migration is visited twice and returns the path of the cycle.
+func (g *dependencyGraph) cycle(remaining []*models.Migration) []string {
+	visited := make(map[string]int)
+	var path []string
+
+	name := remaining[0].Name
+	for {
+		if i, ok := visited[name]; ok {
+			return append(path[i:], name)
+		}
+		visited[name] = len(path)
+		path = append(path, name)
+
+		for _, dependency := range g.dependsOn[name] {
+			if _, ok := g.rank[dependency]; !ok {
+				name = dependency
+				break
+			}
+		}
+	}
+}
+
+// sort sorts the migrations in the order they apply in. Migrations that are not
+// part of the source come last, in the order of their versions.
+func (g *dependencyGraph) sort(migrations []*models.Migration) []*models.Migration {
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return g.less(migrations[i], migrations[j])
+	})
+	return migrations
+}
+
+// reverseSort sorts the applied migrations in the order they roll back in: the
+// reverse of the order they have been applied in, so that a migration applied
+// out of order is rolled back before the ones applied earlier. The migrations
+// without a recorded order, e.g. applied by earlier versions, roll back last,
+// in the reverse order of their dependencies and versions. Either way the
+// dependents are rolled back before their dependencies.
+func (g *dependencyGraph) reverseSort(migrations []*models.Migration) []*models.Migration {
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return g.less(migrations[j], migrations[i])
+	})
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].AppliedOrder > migrations[j].AppliedOrder
+	})
+	return migrations
+}
+
+func (g *dependencyGraph) less(a, b *models.Migration) bool {
+	rankA, okA := g.rank[a.Name]
+	rankB, okB := g.rank[b.Name]
+	switch {
+	case okA && okB:
+		return rankA < rankB
+	case okA != okB:
+		return okA
+	default:
+		return a.Version < b.Version
+	}
+}
+
+// outOfOrder returns the pending up migrations that sort before the latest
+// applied migration, along with the latter.
+func (g *dependencyGraph) outOfOrder(appliedMigrations, pendingMigrations []*models.Migration) ([]*models.Migration, *models.Migration) {
+	var latest *models.Migration
+	for _, migration := range appliedMigrations {
+		rank, ok := g.rank[migration.Name]
+		if ok && (latest == nil || rank > g.rank[latest.Name]) {
+			latest = migration
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	var outOfOrder []*models.Migration
+	for _, migration := range pendingMigrations {
+		rank, ok := g.rank[migration.Name]
+		if migration.Direction == models.Up && ok && rank < g.rank[latest.Name] {
+			outOfOrder = append(outOfOrder, migration)
+		}
+	}
+
+	return g.sort(outOfOrder), latest
+}
+
+// missingDependency returns the first dependency of the migration that is not
+// applied, or an empty string if there is none.
+func (g *dependencyGraph) missingDependency(name string, applied map[string]bool) string {
+	for _, dependency := range g.dependsOn[name] {
+		if !applied[dependency] {
+			return dependency
+		}
+	}
+
+	return ""
+}
+
+// appliedDependents returns the applied migrations that depend on the migration.
+func (g *dependencyGraph) appliedDependents(name string, applied map[string]bool) []string {
+	var dependents []string
+	for dependent, dependencies := range g.dependsOn {
+		if !applied[dependent] {
+			continue
+		}
+		for _, dependency := range dependencies {
+			if dependency == name {
+				dependents = append(dependents, dependent)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+
+	return dependents
+}
+
+// checkDependencies verifies that the dependencies of a migration are applied
+// before applying it, and that its dependents are rolled back before rolling it
+// back.
+func checkDependencies(g *dependencyGraph, migration *models.Migration, applied map[string]bool) error {
+	if migration.Direction == models.Down {
+		if dependents := g.appliedDependents(migration.Name, applied); len(dependents) > 0 {
+			return fmt.Errorf("cannot roll back migration %s, it is a dependency of the applied migrations %s", migration.Name, strings.Join(dependents, ", "))
+		}
+		return nil
+	}
+
+	if dependency := g.missingDependency(migration.Name, applied); dependency != "" {
+		return fmt.Errorf("migration %s depends on %s, which has not been applied", migration.Name, dependency)
+	}
+
+	return nil
+}
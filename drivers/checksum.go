@@ -0,0 +1,149 @@
+package drivers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChecksumColumns are the default names of the columns of a checksum table:
+// the name of the scripts, their checksum and the time they were applied at.
+var ChecksumColumns = []string{"name", "checksum", "applied_at"}
+
+// TableChecksumsConfig describes the SQL of a table that records the checksum
+// of the last applied version of named scripts, such as repeatable migrations
+// and seeds.
+type TableChecksumsConfig struct {
+	// Driver is the name of the driver reported in the errors.
+	Driver string
+	// Table is the quoted name of the checksum table, qualified with its schema
+	// if it is not in the current one.
+	Table string
+	// CreateTableQuery creates the checksum table if it does not exist. The
+	// primary key of the table is the name.
+	CreateTableQuery string
+	// Columns are the names of the columns of the table, in the order of
+	// ChecksumColumns, which is used if it is empty.
+	Columns []string
+	// UpsertClause follows the insert of a checksum, and updates the checksum
+	// and the time it was applied at if the name has been saved before, e.g.
+	// ON CONFLICT (name) DO UPDATE SET ...
+	UpsertClause string
+	// Placeholder is the style of the bind parameters of the database.
+	Placeholder PlaceholderStyle
+	// StatementTimeoutInSecs is the timeout of the queries.
+	StatementTimeoutInSecs int
+	// Noun names the scripts in the error messages, e.g. "repeatable
+	// migration", and Command in the commands of the errors, e.g. "repeatable".
+	Noun    string
+	Command string
+}
+
+// TableChecksums records the checksums of applied scripts in a table, so it is
+// supported by any SQL database.
+type TableChecksums struct {
+	conn   Queryer
+	config TableChecksumsConfig
+}
+
+// NewTableChecksums returns the checksums stored in the table through the
+// connection. The table is created on first use.
+func NewTableChecksums(conn Queryer, config TableChecksumsConfig) *TableChecksums {
+	if len(config.Columns) == 0 {
+		config.Columns = ChecksumColumns
+	}
+
+	return &TableChecksums{
+		conn:   conn,
+		config: config,
+	}
+}
+
+func (c *TableChecksums) createTableIfNotExists() error {
+	ctx, cancel := GetContext(c.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	if _, err := c.conn.ExecContext(ctx, c.config.CreateTableQuery); err != nil {
+		return &DatabaseError{
+			OrigErr: err,
+			Driver:  c.config.Driver,
+			Message: "failed while executing query",
+			Command: "create_" + c.config.Command + "_table_if_not_exists",
+			Query:   []byte(c.config.CreateTableQuery),
+		}
+	}
+
+	return nil
+}
+
+// Applied returns the checksums recorded in the table, keyed by name.
+func (c *TableChecksums) Applied() (map[string]string, error) {
+	if err := c.createTableIfNotExists(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := GetContext(c.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	query := fmt.Sprintf("SELECT %s, %s FROM %s", c.config.Columns[0], c.config.Columns[1], c.config.Table)
+	rows, err := c.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, &DatabaseError{
+			OrigErr: err,
+			Driver:  c.config.Driver,
+			Message: "failed to fetch applied " + c.config.Noun + "s",
+			Command: "select_" + c.config.Command + "s",
+			Query:   []byte(query),
+		}
+	}
+	defer rows.Close()
+
+	checksums := make(map[string]string)
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, &DatabaseError{
+				OrigErr: err,
+				Driver:  c.config.Driver,
+				Message: "failed to scan " + c.config.Noun + " row",
+				Command: "scan_" + c.config.Command + "s",
+			}
+		}
+		checksums[name] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &DatabaseError{
+			OrigErr: err,
+			Driver:  c.config.Driver,
+			Message: "failed to iterate " + c.config.Noun + " rows",
+			Command: "scan_" + c.config.Command + "s",
+			Query:   []byte(query),
+		}
+	}
+
+	return checksums, nil
+}
+
+// Save records the checksum of an applied script.
+func (c *TableChecksums) Save(name, checksum string) error {
+	if err := c.createTableIfNotExists(); err != nil {
+		return err
+	}
+
+	ctx, cancel := GetContext(c.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	columns := c.config.Columns
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s", c.config.Table, strings.Join(columns, ", "), c.config.Placeholder.list(len(columns)), c.config.UpsertClause)
+	if _, err := c.conn.ExecContext(ctx, query, name, checksum, ToMillis(time.Now())); err != nil {
+		return &DatabaseError{
+			OrigErr: err,
+			Driver:  c.config.Driver,
+			Message: "failed to save " + c.config.Noun,
+			Command: "save_" + c.config.Command,
+			Query:   []byte(query),
+		}
+	}
+
+	return nil
+}
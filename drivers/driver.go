@@ -7,6 +7,14 @@ import (
 type Config struct {
 	// MigrationsTableName is the name of the table that will store the migrations.
 	MigrationsTable string
+	// MetadataSchema is the schema, or the database on MySQL, of the tables
+	// morph keeps its state in, i.e. the migrations table, the tables named
+	// after it and the mutex table. It must exist. The current schema is used
+	// if it is empty.
+	MetadataSchema string
+	// MutexTable is the name of the table that stores the mutexes of the
+	// drivers implementing Lockable. Defaults to MutexTableName.
+	MutexTable string
 	// StatementTimeoutInSecs is used to set a timeout for each migration file.
 	// Set below zero to disable timeout. Zero value will result in default value, which is 60 seconds.
 	StatementTimeoutInSecs int
@@ -32,5 +40,7 @@ type Driver interface {
 	// This method is being used by the morph engine to apply configurations such as:
 	// StatementTimeoutInSecs
 	// MigrationsTableName
+	// MetadataSchema
+	// MutexTable
 	SetConfig(key string, value interface{}) error
 }
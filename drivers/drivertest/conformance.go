@@ -0,0 +1,412 @@
+// Package drivertest provides a conformance test suite for the morph drivers,
+// so that every driver, including third-party ones, can prove that it behaves
+// the way the engine expects.
+package drivertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// Factory returns a driver connected to an empty database, with the default
+// configuration. It should register the closing of the driver, and anything
+// else the cleanup of the database needs, with t.Cleanup.
+type Factory func(t *testing.T) drivers.Driver
+
+// Option configures the conformance suite.
+type Option func(*suite)
+
+// WithSlowQuery sets a query that runs for at least two seconds, e.g.
+// SELECT pg_sleep(2), to verify that the statement timeout is enforced. The
+// timeout is not tested without it.
+func WithSlowQuery(query string) Option {
+	return func(s *suite) {
+		s.slowQuery = query
+	}
+}
+
+// WithConnURL sets the connection URL of the database and the name the driver
+// is registered with, to verify that the driver is registered and that invalid
+// configuration parameters of the URL are reported as drivers.AppError.
+func WithConnURL(driverName, connURL string) Option {
+	return func(s *suite) {
+		s.driverName = driverName
+		s.connURL = connURL
+	}
+}
+
+// WithLockable sets the mutexes of a driver that has them without implementing
+// drivers.Lockable, such as the postgres and mysql drivers whose NewMutex
+// returns their own type. The mutex is not tested for the drivers that have
+// neither.
+func WithLockable(lockable func(driver drivers.Driver) drivers.Lockable) Option {
+	return func(s *suite) {
+		s.lockable = lockable
+	}
+}
+
+// LockableFunc adapts a NewMutex function to drivers.Lockable.
+type LockableFunc func(key string, logger drivers.Logger) (drivers.Locker, error)
+
+// NewMutex calls f.
+func (f LockableFunc) NewMutex(key string, logger drivers.Logger) (drivers.Locker, error) {
+	return f(key, logger)
+}
+
+type suite struct {
+	factory    Factory
+	slowQuery  string
+	driverName string
+	connURL    string
+	lockable   func(driver drivers.Driver) drivers.Lockable
+}
+
+// migrationsTable is the migrations table set by the suite, so it knows which
+// tables it has to drop.
+const migrationsTable = "conformance_migrations"
+
+// RunConformance runs the conformance suite against the drivers returned by the
+// factory. It covers applying migrations with and without saving their version,
+// listing the applied migrations, the configuration keys and the validation of
+// the table names, the error types, the statement timeout and, if the driver
+// implements drivers.Lockable or its mutexes are set with WithLockable, the
+// mutex.
+// The migrations use SQL that is common to the relational databases.
+func RunConformance(t *testing.T, factory Factory, options ...Option) {
+	s := &suite{factory: factory}
+	for _, option := range options {
+		option(s)
+	}
+
+	t.Run("Ping", s.testPing)
+	t.Run("ApplySaveVersion", s.testApplySaveVersion)
+	t.Run("ApplyWithoutSaveVersion", s.testApplyWithoutSaveVersion)
+	t.Run("ApplyDown", s.testApplyDown)
+	t.Run("ApplyRecordOnly", s.testApplyRecordOnly)
+	t.Run("ApplyNonTransactional", s.testApplyNonTransactional)
+	t.Run("ApplyQuotedName", s.testApplyQuotedName)
+	t.Run("ApplyLargeVersion", s.testApplyLargeVersion)
+	t.Run("AppliedOrder", s.testAppliedOrder)
+	t.Run("ApplyError", s.testApplyError)
+	t.Run("SetConfig", s.testSetConfig)
+	t.Run("StatementTimeout", s.testStatementTimeout)
+	t.Run("Open", s.testOpen)
+	t.Run("Lockable", s.testLockable)
+}
+
+// driver returns a new driver that keeps its migrations in the table of the
+// suite, and drops the tables of the suite at cleanup. Like the engine, it lists
+// the applied migrations first so the driver creates its migrations table.
+func (s *suite) driver(t *testing.T, tables ...string) drivers.Driver {
+	driver := s.factory(t)
+	require.NoError(t, driver.SetConfig("MigrationsTable", migrationsTable), "should set the migrations table")
+	_, err := driver.AppliedMigrations()
+	require.NoError(t, err, "should create the migrations table")
+
+	t.Cleanup(func() {
+		for _, table := range append(tables, migrationsTable) {
+			drop := &models.Migration{Name: "drop_" + table, Direction: models.Down, Bytes: []byte("DROP TABLE IF EXISTS " + table + ";")}
+			require.NoError(t, driver.Apply(drop, false), "should drop table %s", table)
+		}
+	})
+
+	return driver
+}
+
+func migration(version uint64, name string, direction models.Direction, query string) *models.Migration {
+	return &models.Migration{
+		Version:   version,
+		Name:      name,
+		RawName:   name + "." + string(direction) + ".sql",
+		Direction: direction,
+		Bytes:     []byte(query),
+	}
+}
+
+func appliedVersions(t *testing.T, driver drivers.Driver) []uint64 {
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err, "should list the applied migrations")
+
+	versions := make([]uint64, 0, len(applied))
+	for _, m := range applied {
+		versions = append(versions, m.Version)
+	}
+
+	return versions
+}
+
+func (s *suite) testPing(t *testing.T) {
+	driver := s.driver(t)
+	require.NoError(t, driver.Ping())
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err, "should list the applied migrations of an empty database")
+	require.Empty(t, applied)
+}
+
+func (s *suite) testApplySaveVersion(t *testing.T) {
+	driver := s.driver(t, "conformance_users")
+
+	require.NoError(t, driver.Apply(migration(2, "create_users", models.Up, "CREATE TABLE conformance_users (id integer);"), true))
+	require.NoError(t, driver.Apply(migration(3, "insert_users", models.Up, "INSERT INTO conformance_users (id) VALUES (1);\nINSERT INTO conformance_users (id) VALUES (2);"), true))
+	require.NoError(t, driver.Apply(migration(1, "noop", models.Up, "SELECT 1;"), true))
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	require.ElementsMatch(t, []uint64{1, 2, 3}, appliedVersions(t, driver))
+
+	for _, m := range applied {
+		switch m.Version {
+		case 1:
+			require.Equal(t, "noop", m.Name)
+		case 2:
+			require.Equal(t, "create_users", m.Name)
+		case 3:
+			require.Equal(t, "insert_users", m.Name)
+		}
+	}
+}
+
+func (s *suite) testApplyWithoutSaveVersion(t *testing.T) {
+	driver := s.driver(t, "conformance_teams")
+
+	require.NoError(t, driver.Apply(migration(1, "create_teams", models.Up, "CREATE TABLE conformance_teams (id integer);"), false))
+	require.Empty(t, appliedVersions(t, driver), "should not save the version")
+
+	// the table has been created
+	require.NoError(t, driver.Apply(migration(2, "insert_teams", models.Up, "INSERT INTO conformance_teams (id) VALUES (1);"), false))
+}
+
+func (s *suite) testApplyDown(t *testing.T) {
+	driver := s.driver(t, "conformance_posts")
+
+	require.NoError(t, driver.Apply(migration(1, "create_posts", models.Up, "CREATE TABLE conformance_posts (id integer);"), true))
+	require.NoError(t, driver.Apply(migration(2, "noop", models.Up, "SELECT 1;"), true))
+	require.ElementsMatch(t, []uint64{1, 2}, appliedVersions(t, driver))
+
+	require.NoError(t, driver.Apply(migration(1, "create_posts", models.Down, "DROP TABLE conformance_posts;"), true))
+	require.Equal(t, []uint64{2}, appliedVersions(t, driver), "should delete the version of the rolled back migration")
+}
+
+func (s *suite) testApplyRecordOnly(t *testing.T) {
+	driver := s.driver(t)
+
+	m := migration(1, "skipped", models.Up, "SELECT * FROM conformance_missing;")
+	m.RecordOnly = true
+	m.SkipReason = "environment test is not one of production"
+	require.NoError(t, driver.Apply(m, true), "should not run the query of a record only migration")
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, "skipped", applied[0].Name)
+	require.Equal(t, m.SkipReason, applied[0].SkipReason, "should record the skip reason")
+}
+
+func (s *suite) testApplyNonTransactional(t *testing.T) {
+	driver := s.driver(t, "conformance_channels")
+
+	m := migration(1, "create_channels", models.Up, models.DirectivePrefix+models.DirectiveNonTransactional+"\nCREATE TABLE conformance_channels (id integer);")
+	require.NoError(t, driver.Apply(m, true))
+	require.Equal(t, []uint64{1}, appliedVersions(t, driver))
+}
+
+func (s *suite) testApplyQuotedName(t *testing.T) {
+	driver := s.driver(t)
+
+	m := migration(1, "it's_quoted", models.Up, "SELECT 1;")
+	m.RecordOnly = true
+	m.SkipReason = "it's skipped"
+	require.NoError(t, driver.Apply(m, true), "should save the version of a migration whose name has a quote")
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, m.Name, applied[0].Name)
+	require.Equal(t, m.SkipReason, applied[0].SkipReason)
+
+	require.NoError(t, driver.Apply(migration(1, "it's_quoted", models.Down, "SELECT 1;"), true))
+	require.Empty(t, appliedVersions(t, driver), "should delete the version of a migration whose name has a quote")
+}
+
+func (s *suite) testApplyLargeVersion(t *testing.T) {
+	driver := s.driver(t)
+
+	// timestamp versions, such as 20060102150405 or unix nanoseconds, overflow 32 bits
+	versions := []uint64{20240102150405, 1700000000000000000}
+	for _, version := range versions {
+		require.NoError(t, driver.Apply(migration(version, "noop", models.Up, "SELECT 1;"), true))
+	}
+	require.ElementsMatch(t, versions, appliedVersions(t, driver), "should save 64-bit versions")
+
+	require.NoError(t, driver.Apply(migration(versions[1], "noop", models.Down, "SELECT 1;"), true))
+	require.Equal(t, versions[:1], appliedVersions(t, driver), "should delete 64-bit versions")
+}
+
+func (s *suite) testAppliedOrder(t *testing.T) {
+	driver := s.driver(t)
+
+	// the second migration is applied out of order
+	for _, version := range []uint64{1, 3, 2} {
+		require.NoError(t, driver.Apply(migration(version, fmt.Sprintf("noop_%d", version), models.Up, "SELECT 1;"), true))
+	}
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err, "should list the applied migrations")
+	require.Len(t, applied, 3)
+
+	order := make(map[uint64]uint64, len(applied))
+	for _, m := range applied {
+		order[m.Version] = m.AppliedOrder
+	}
+	if order[1] == 0 && order[2] == 0 && order[3] == 0 {
+		t.Skip("driver does not record the order the migrations are applied in")
+	}
+	require.Less(t, order[1], order[3], "should record the order the migrations are applied in")
+	require.Less(t, order[3], order[2], "should record the order the migrations are applied in")
+
+	require.NoError(t, driver.Apply(migration(3, "noop_3", models.Down, "SELECT 1;"), true))
+	require.NoError(t, driver.Apply(migration(3, "noop_3", models.Up, "SELECT 1;"), true))
+
+	applied, err = driver.AppliedMigrations()
+	require.NoError(t, err, "should list the applied migrations")
+	for _, m := range applied {
+		order[m.Version] = m.AppliedOrder
+	}
+	require.Less(t, order[2], order[3], "should record a re-applied migration last")
+}
+
+func (s *suite) testApplyError(t *testing.T) {
+	driver := s.driver(t)
+
+	require.NoError(t, driver.Apply(migration(1, "noop", models.Up, "SELECT 1;"), true))
+
+	err := driver.Apply(migration(2, "broken", models.Up, "SELECT * FROM conformance_missing;"), true)
+	require.Error(t, err)
+
+	var dbErr *drivers.DatabaseError
+	require.True(t, errors.As(err, &dbErr), "should return a drivers.DatabaseError, got %T", err)
+	require.NotEmpty(t, dbErr.Driver)
+	require.NotNil(t, dbErr.OrigErr)
+
+	require.Equal(t, []uint64{1}, appliedVersions(t, driver), "should not save the version of a failed migration")
+}
+
+func (s *suite) testSetConfig(t *testing.T) {
+	driver := s.driver(t, "conformance_other_migrations")
+
+	require.NoError(t, driver.SetConfig("StatementTimeoutInSecs", 30))
+	require.Error(t, driver.SetConfig("StatementTimeoutInSecs", "30"), "should reject a timeout that is not an int")
+	require.Error(t, driver.SetConfig("MigrationsTable", 1), "should reject a table name that is not a string")
+	require.Error(t, driver.SetConfig("MigrationsTable", "conformance_migrations; DROP TABLE conformance_users"), "should reject a table name that is not an identifier")
+	require.Error(t, driver.SetConfig("Unknown", 1), "should reject unknown keys")
+
+	require.NoError(t, driver.Apply(migration(1, "noop", models.Up, "SELECT 1;"), true))
+
+	require.NoError(t, driver.SetConfig("MigrationsTable", "conformance_other_migrations"))
+	require.Empty(t, appliedVersions(t, driver), "should list the migrations of the new table")
+	require.NoError(t, driver.Apply(migration(2, "noop", models.Up, "SELECT 1;"), true))
+	require.Equal(t, []uint64{2}, appliedVersions(t, driver))
+
+	require.NoError(t, driver.SetConfig("MigrationsTable", migrationsTable))
+	require.Equal(t, []uint64{1}, appliedVersions(t, driver))
+}
+
+func (s *suite) testStatementTimeout(t *testing.T) {
+	if s.slowQuery == "" {
+		t.Skip("no slow query has been set with WithSlowQuery")
+	}
+
+	driver := s.driver(t)
+	require.NoError(t, driver.SetConfig("StatementTimeoutInSecs", 1))
+
+	start := time.Now()
+	err := driver.Apply(migration(1, "slow", models.Up, s.slowQuery), true)
+	require.Error(t, err, "should time out")
+	require.True(t, time.Since(start) < 2*time.Second, "should not wait for the query to finish")
+	require.Empty(t, appliedVersions(t, driver))
+
+	// the timeout directive overrides the configuration
+	require.NoError(t, driver.SetConfig("StatementTimeoutInSecs", -1))
+	m := migration(2, "slow_directive", models.Up, models.DirectivePrefix+models.DirectiveTimeout+"=1\n"+s.slowQuery)
+	require.Error(t, driver.Apply(m, true), "should time out with the timeout directive")
+}
+
+func (s *suite) testOpen(t *testing.T) {
+	if s.connURL == "" {
+		t.Skip("no connection url has been set with WithConnURL")
+	}
+
+	require.Contains(t, drivers.Registered(), s.driverName, "should register the driver")
+
+	driver, err := drivers.OpenByName(s.driverName, s.connURL)
+	require.NoError(t, err, "should open the driver by name")
+	require.NoError(t, driver.Ping())
+	require.NoError(t, driver.Close())
+
+	separator := "?"
+	if strings.Contains(s.connURL, "?") {
+		separator = "&"
+	}
+
+	_, err = drivers.OpenByName(s.driverName, s.connURL+separator+"x-statement-timeout=invalid")
+	require.Error(t, err)
+
+	var appErr *drivers.AppError
+	require.True(t, errors.As(err, &appErr), "should return a drivers.AppError for invalid parameters, got %T", err)
+
+	_, err = drivers.OpenByName(s.driverName, s.connURL+separator+"x-migrations-table=1_migrations")
+	require.True(t, errors.As(err, &appErr), "should return a drivers.AppError for an invalid migrations table, got %T", err)
+}
+
+func (s *suite) testLockable(t *testing.T) {
+	driver := s.driver(t)
+
+	lockable, ok := driver.(drivers.Lockable)
+	if s.lockable != nil {
+		lockable, ok = s.lockable(driver), true
+	}
+	if !ok {
+		t.Skip("the driver does not implement drivers.Lockable")
+	}
+
+	logger := log.New(io.Discard, "", 0)
+
+	_, err := lockable.NewMutex("", logger)
+	require.Error(t, err, "should reject an empty key")
+
+	first, err := lockable.NewMutex("conformance", logger)
+	require.NoError(t, err)
+	second, err := lockable.NewMutex("conformance", logger)
+	require.NoError(t, err)
+	other, err := lockable.NewMutex("conformance_other", logger)
+	require.NoError(t, err)
+
+	require.NoError(t, first.Lock(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.Error(t, second.Lock(ctx), "should not lock a mutex that is locked with the same key")
+
+	require.NoError(t, other.Lock(context.Background()), "should lock a mutex with another key")
+	require.NoError(t, other.Unlock())
+
+	require.NoError(t, first.Unlock())
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, second.Lock(ctx), "should lock the mutex once it is unlocked")
+	require.NoError(t, second.Unlock())
+}
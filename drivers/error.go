@@ -1,6 +1,9 @@
 package drivers
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type AppError struct {
 	OrigErr error
@@ -14,6 +17,16 @@ type DatabaseError struct {
 	Message string
 	Command string
 	Query   []byte
+
+	// SQLState is the SQLSTATE code of the error, e.g. 42P01, if the driver
+	// reports it.
+	SQLState string
+	// Position is the position of the error in the query, starting at 1, or
+	// zero if unknown.
+	Position int
+	// Detail and Hint are the secondary messages of the database, if any.
+	Detail string
+	Hint   string
 }
 
 func (ae *AppError) Error() string {
@@ -21,5 +34,25 @@ func (ae *AppError) Error() string {
 }
 
 func (de *DatabaseError) Error() string {
-	return fmt.Sprintf("driver: %s, message: %s, command: %s, originalError: %v, query: \n\n%s\n", de.Driver, de.Message, de.Command, de.OrigErr, string(de.Query))
+	return fmt.Sprintf("driver: %s, message: %s, command: %s, originalError: %v, %squery: \n\n%s\n", de.Driver, de.Message, de.Command, de.OrigErr, de.details(), string(de.Query))
+}
+
+// details formats the details of the error reported by the database, each
+// followed by a separator, or returns an empty string if there are none.
+func (de *DatabaseError) details() string {
+	var b strings.Builder
+	if de.SQLState != "" {
+		fmt.Fprintf(&b, "sqlState: %s, ", de.SQLState)
+	}
+	if de.Position > 0 {
+		fmt.Fprintf(&b, "position: %d, ", de.Position)
+	}
+	if de.Detail != "" {
+		fmt.Fprintf(&b, "detail: %s, ", de.Detail)
+	}
+	if de.Hint != "" {
+		fmt.Fprintf(&b, "hint: %s, ", de.Hint)
+	}
+
+	return b.String()
 }
@@ -0,0 +1,30 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseError(t *testing.T) {
+	t.Run("without details", func(t *testing.T) {
+		err := &DatabaseError{OrigErr: errors.New("boom"), Driver: "postgres", Message: "failed to execute migration", Command: "executing_query", Query: []byte("SELECT 1;")}
+		require.EqualError(t, err, "driver: postgres, message: failed to execute migration, command: executing_query, originalError: boom, query: \n\nSELECT 1;\n")
+	})
+
+	t.Run("with details", func(t *testing.T) {
+		err := &DatabaseError{
+			OrigErr:  errors.New("boom"),
+			Driver:   "pgx",
+			Message:  "failed to execute migration",
+			Command:  "executing_query",
+			Query:    []byte("SELECT * FROM missing;"),
+			SQLState: "42P01",
+			Position: 15,
+			Detail:   "the detail",
+			Hint:     "the hint",
+		}
+		require.EqualError(t, err, "driver: pgx, message: failed to execute migration, command: executing_query, originalError: boom, sqlState: 42P01, position: 15, detail: the detail, hint: the hint, query: \n\nSELECT * FROM missing;\n")
+	})
+}
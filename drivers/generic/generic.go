@@ -0,0 +1,464 @@
+// Package generic provides a driver for the databases that have a database/sql
+// driver but no morph driver. The SQL that differs between databases is
+// described by a Dialect. Unlike the other drivers, it does not record the
+// order the migrations are applied in, so they roll back in the reverse order
+// of their versions.
+package generic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+const defaultMigrationMaxSize = 10 * 1 << 20 // 10 MB
+
+// Dialect describes the SQL of a database.
+type Dialect struct {
+	// Name identifies the database in the errors of the driver, e.g. oracle.
+	Name string
+	// Placeholder is the style of the bind parameters of the database.
+	Placeholder drivers.PlaceholderStyle
+	// IdentifierQuote is the character quoting identifiers, e.g. a double quote
+	// or a backtick. The driver quotes the migrations table with it in its
+	// queries, and CreateMigrationsTable should do the same, as quoted names
+	// are case sensitive on most databases. Table names are not quoted if it is
+	// empty.
+	IdentifierQuote string
+	// CreateMigrationsTable returns the statement creating the migrations table
+	// if it does not exist. The table name is given unquoted. The table has a
+	// version column, a bigint primary key that holds 64-bit versions, a name
+	// column, a varchar, and a nullable skip_reason column, a text.
+	// The current database and schema are given to the databases that need them
+	// to look the table up in their catalog, they are empty unless the queries
+	// to select them are set. If a metadata schema is set, it is given instead
+	// of the current schema and the table has to be created in it.
+	CreateMigrationsTable func(database, schema, table string) string
+	// TransactionalDDL is set if schema changes can be rolled back. Without it,
+	// every migration runs outside of a transaction, as if it had the
+	// nontransactional directive.
+	TransactionalDDL bool
+	// CurrentDatabaseQuery selects the name of the current database. Optional.
+	CurrentDatabaseQuery string
+	// CurrentSchemaQuery selects the name of the current schema. Optional.
+	CurrentSchemaQuery string
+	// CreateMutexTable returns the statement creating the mutex table if it does
+	// not exist, see drivers.TableMutexConfig. The table name is given quoted
+	// with IdentifierQuote and qualified with the metadata schema. The driver implements drivers.Lockable only if it is
+	// set.
+	CreateMutexTable func(table string) string
+	// IsUniqueViolation reports whether an error is a violation of a primary
+	// key. It is optional and only used by the mutex for logging.
+	IsUniqueViolation func(err error) bool
+}
+
+type driverConfig struct {
+	drivers.Config
+	databaseName string
+	schemaName   string
+}
+
+// Generic is a driver applying migrations through a database/sql connection
+// with the SQL of its dialect.
+type Generic struct {
+	conn    *sql.Conn
+	db      *sql.DB
+	dialect *Dialect
+	config  *driverConfig
+}
+
+// LockableGeneric is the driver of the dialects that have a mutex table.
+type LockableGeneric struct {
+	*Generic
+}
+
+var (
+	_ drivers.Driver   = (*Generic)(nil)
+	_ drivers.Lockable = (*LockableGeneric)(nil)
+)
+
+// WithInstance returns a driver for the database with the SQL of the dialect.
+// The driver is a *LockableGeneric, which implements drivers.Lockable, if the
+// dialect has a mutex table, and a *Generic otherwise. Closing the driver does
+// not close the database.
+func WithInstance(dbInstance *sql.DB, dialect *Dialect) (drivers.Driver, error) {
+	if dialect == nil || dialect.Name == "" || dialect.CreateMigrationsTable == nil {
+		return nil, &drivers.AppError{Driver: "generic", OrigErr: errors.New("the name and the migrations table statement are required"), Message: "invalid dialect"}
+	}
+
+	conn, err := dbInstance.Conn(context.Background())
+	if err != nil {
+		return nil, &drivers.DatabaseError{Driver: dialect.Name, Command: "grabbing_connection", OrigErr: err, Message: "failed to grab connection to the database"}
+	}
+
+	driver := &Generic{
+		conn:    conn,
+		db:      dbInstance,
+		dialect: dialect,
+		config:  getDefaultConfig(),
+	}
+
+	if driver.config.databaseName, err = driver.queryName(dialect.CurrentDatabaseQuery, "current_database"); err != nil {
+		return nil, err
+	}
+
+	if driver.config.schemaName, err = driver.queryName(dialect.CurrentSchemaQuery, "current_schema"); err != nil {
+		return nil, err
+	}
+
+	if dialect.CreateMutexTable != nil {
+		return &LockableGeneric{Generic: driver}, nil
+	}
+
+	return driver, nil
+}
+
+func getDefaultConfig() *driverConfig {
+	return &driverConfig{
+		Config: drivers.Config{
+			MigrationsTable:        "db_migrations",
+			MutexTable:             drivers.MutexTableName,
+			StatementTimeoutInSecs: 60,
+			MigrationMaxSize:       defaultMigrationMaxSize,
+		},
+	}
+}
+
+// queryName returns the name selected by the query, or an empty name if the
+// query is not set.
+func (driver *Generic) queryName(query, command string) (string, error) {
+	if query == "" {
+		return "", nil
+	}
+
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	var name sql.NullString
+	if err := driver.conn.QueryRowContext(ctx, query).Scan(&name); err != nil {
+		return "", &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driver.dialect.Name,
+			Message: "failed to fetch " + command,
+			Command: command,
+			Query:   []byte(query),
+		}
+	}
+
+	return name.String, nil
+}
+
+func (driver *Generic) Ping() error {
+	if driver.conn == nil {
+		return driver.errMissingConnection()
+	}
+
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	return driver.conn.PingContext(ctx)
+}
+
+func (driver *Generic) Close() error {
+	if driver.conn != nil {
+		if err := driver.conn.Close(); err != nil {
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driver.dialect.Name,
+				Message: "failed to close database connection",
+				Command: "conn_close",
+				Query:   nil,
+			}
+		}
+	}
+
+	driver.conn = nil
+	return nil
+}
+
+func (driver *Generic) createSchemaTableIfNotExists() error {
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	schema := driver.config.schemaName
+	if driver.config.MetadataSchema != "" {
+		schema = driver.config.MetadataSchema
+	}
+
+	query := driver.dialect.CreateMigrationsTable(driver.config.databaseName, schema, driver.config.MigrationsTable)
+	if _, err := driver.conn.ExecContext(ctx, query); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driver.dialect.Name,
+			Message: "failed while executing query",
+			Command: "create_migrations_table_if_not_exists",
+			Query:   []byte(query),
+		}
+	}
+
+	return nil
+}
+
+// execer is implemented by both the connection and the transactions.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (driver *Generic) Apply(migration *models.Migration, saveVersion bool) error {
+	if driver.conn == nil {
+		return driver.errMissingConnection()
+	}
+
+	directives, err := migration.Directives()
+	if err != nil {
+		return &drivers.AppError{Driver: driver.dialect.Name, OrigErr: err, Message: "failed to parse migration directives"}
+	}
+
+	ctx, cancel := drivers.GetContext(drivers.MigrationTimeout(driver.config.StatementTimeoutInSecs, directives))
+	defer cancel()
+
+	// We wrap with a transaction only if the schema changes can be rolled back
+	// and there is no non-transactional directive.
+	if !driver.dialect.TransactionalDDL || directives.NonTransactional {
+		return driver.apply(ctx, driver.conn, migration, saveVersion)
+	}
+
+	transaction, err := driver.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driver.dialect.Name,
+			Message: "error while opening a transaction to the database",
+			Command: "begin_transaction",
+		}
+	}
+
+	if err := driver.apply(ctx, transaction, migration, saveVersion); err != nil {
+		_ = transaction.Rollback()
+		return err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driver.dialect.Name,
+			Message: "error while committing a transaction to the database",
+			Command: "commit_transaction",
+		}
+	}
+
+	return nil
+}
+
+// apply runs the migration and saves its version through the connection or
+// the transaction.
+func (driver *Generic) apply(ctx context.Context, exec execer, migration *models.Migration, saveVersion bool) error {
+	if !migration.RecordOnly {
+		query := migration.Query()
+		if _, err := exec.ExecContext(ctx, query, migration.Args...); err != nil {
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driver.dialect.Name,
+				Message: "failed to execute migration",
+				Command: "executing_query",
+				Query:   []byte(query),
+			}
+		}
+	}
+
+	if saveVersion {
+		query, args := driver.addMigrationQuery(migration)
+		if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driver.dialect.Name,
+				Message: "failed when updating migrations table with the new version",
+				Command: "update_version",
+				Query:   []byte(query),
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrationsTable returns the name of the migrations table, quoted if the
+// dialect has a quote character.
+func (driver *Generic) migrationsTable() string {
+	return driver.quoteTable(driver.config.MigrationsTable)
+}
+
+// quoteTable quotes the name of a table if the dialect has a quote character,
+// and qualifies it with the metadata schema if there is one.
+func (driver *Generic) quoteTable(name string) string {
+	if driver.dialect.IdentifierQuote == "" {
+		if driver.config.MetadataSchema == "" {
+			return name
+		}
+		return driver.config.MetadataSchema + "." + name
+	}
+
+	return drivers.QuoteTableName(driver.config.MetadataSchema, name, driver.dialect.IdentifierQuote)
+}
+
+// addMigrationQuery returns the query, and its arguments, that records the
+// version of an up migration or deletes the one of a down migration.
+func (driver *Generic) addMigrationQuery(migration *models.Migration) (string, []interface{}) {
+	p := driver.dialect.Placeholder
+	if migration.Direction == models.Down {
+		query := fmt.Sprintf("DELETE FROM %s WHERE version = %s AND name = %s", driver.migrationsTable(), p.Placeholder(1), p.Placeholder(2))
+		return query, []interface{}{migration.Version, migration.Name}
+	}
+
+	skipReason := sql.NullString{String: migration.SkipReason, Valid: migration.SkipReason != ""}
+	query := fmt.Sprintf("INSERT INTO %s (version, name, skip_reason) VALUES (%s, %s, %s)", driver.migrationsTable(), p.Placeholder(1), p.Placeholder(2), p.Placeholder(3))
+	return query, []interface{}{migration.Version, migration.Name, skipReason}
+}
+
+func (driver *Generic) AppliedMigrations() (migrations []*models.Migration, err error) {
+	if driver.conn == nil {
+		return nil, driver.errMissingConnection()
+	}
+
+	if err := driver.createSchemaTableIfNotExists(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT version, name, skip_reason FROM %s ORDER BY version", driver.migrationsTable())
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+	var appliedMigrations []*models.Migration
+	var version uint64
+	var name string
+	var skipReason sql.NullString
+
+	rows, err := driver.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driver.dialect.Name,
+			Message: "failed to fetch applied migrations",
+			Command: "select_applied_migrations",
+			Query:   []byte(query),
+		}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(&version, &name, &skipReason); err != nil {
+			return nil, &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driver.dialect.Name,
+				Message: "failed to scan applied migration row",
+				Command: "scan_applied_migrations",
+			}
+		}
+
+		appliedMigrations = append(appliedMigrations, &models.Migration{
+			Name:       name,
+			Version:    version,
+			Direction:  models.Up,
+			SkipReason: skipReason.String,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driver.dialect.Name,
+			Message: "failed to fetch applied migrations",
+			Command: "select_applied_migrations",
+			Query:   []byte(query),
+		}
+	}
+
+	return appliedMigrations, nil
+}
+
+func (driver *Generic) SetConfig(key string, value interface{}) error {
+	if driver.config != nil {
+		switch key {
+		case "StatementTimeoutInSecs":
+			n, ok := value.(int)
+			if ok {
+				driver.config.StatementTimeoutInSecs = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MigrationsTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				driver.config.MigrationsTable = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MetadataSchema":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateSchemaName(n); err != nil {
+					return err
+				}
+				driver.config.MetadataSchema = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MutexTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				driver.config.MutexTable = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		}
+	}
+
+	return fmt.Errorf("incorrect key name %q", key)
+}
+
+func (driver *Generic) errMissingConnection() error {
+	return &drivers.AppError{
+		OrigErr: errors.New("driver has no connection established"),
+		Message: "database connection is missing",
+		Driver:  driver.dialect.Name,
+	}
+}
+
+// NewMutex creates a mutex with the given key name, stored in the mutex table
+// of the dialect.
+//
+// returns error if key is empty.
+func (driver *LockableGeneric) NewMutex(key string, logger drivers.Logger) (drivers.Locker, error) {
+	if _, err := drivers.MakeLockKey(key); err != nil {
+		return nil, err
+	}
+
+	conn, err := driver.db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	table := driver.quoteTable(driver.config.MutexTable)
+	mutex, err := drivers.NewTableMutex(conn, key, logger, drivers.TableMutexConfig{
+		Table:             table,
+		CreateTableQuery:  driver.dialect.CreateMutexTable(table),
+		Placeholder:       driver.dialect.Placeholder,
+		IsUniqueViolation: driver.dialect.IsUniqueViolation,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return mutex, nil
+}
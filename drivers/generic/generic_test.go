@@ -0,0 +1,150 @@
+package generic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/drivers/drivertest"
+	"github.com/mattermost/morph/models"
+)
+
+// sqliteDialect describes SQLite, which has a morph driver, to test the generic
+// driver without a database server.
+func sqliteDialect() *Dialect {
+	return &Dialect{
+		Name:            "sqlite",
+		Placeholder:     drivers.QuestionPlaceholder,
+		IdentifierQuote: `"`,
+		CreateMigrationsTable: func(_, schema, table string) string {
+			return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint not null primary key, name varchar not null, skip_reason text)", drivers.QuoteTableName(schema, table, `"`))
+		},
+		TransactionalDDL:     true,
+		CurrentDatabaseQuery: "SELECT 'main'",
+		CreateMutexTable: func(table string) string {
+			return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id varchar(64) PRIMARY KEY, expireat bigint)", table)
+		},
+	}
+}
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "morph-generic.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	db := openDB(t)
+
+	drivertest.RunConformance(t, func(t *testing.T) drivers.Driver {
+		driver, err := WithInstance(db, sqliteDialect())
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, driver.Close())
+		})
+
+		return driver
+	})
+}
+
+func TestWithInstance(t *testing.T) {
+	db := openDB(t)
+
+	t.Run("the dialect requires a name and a migrations table", func(t *testing.T) {
+		_, err := WithInstance(db, &Dialect{Name: "sqlite"})
+		require.EqualError(t, err, "driver: generic, message: invalid dialect, originalError: the name and the migrations table statement are required ")
+	})
+
+	t.Run("the driver is lockable only if the dialect has a mutex table", func(t *testing.T) {
+		driver, err := WithInstance(db, sqliteDialect())
+		require.NoError(t, err)
+		defer driver.Close()
+
+		_, ok := driver.(drivers.Lockable)
+		require.True(t, ok)
+		require.Equal(t, "main", driver.(*LockableGeneric).config.databaseName)
+
+		dialect := sqliteDialect()
+		dialect.CreateMutexTable = nil
+		driver, err = WithInstance(db, dialect)
+		require.NoError(t, err)
+		defer driver.Close()
+
+		_, ok = driver.(drivers.Lockable)
+		require.False(t, ok)
+	})
+}
+
+func TestApply(t *testing.T) {
+	query := "CREATE TABLE users (id integer);\nSELECT * FROM missing;"
+
+	tableExists := func(t *testing.T, db *sql.DB) bool {
+		var count int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&count))
+		return count > 0
+	}
+
+	t.Run("a failing migration is rolled back with transactional DDL", func(t *testing.T) {
+		db := openDB(t)
+		driver, err := WithInstance(db, sqliteDialect())
+		require.NoError(t, err)
+		defer driver.Close()
+
+		err = driver.Apply(&models.Migration{Version: 1, Name: "create_users", Direction: models.Up, Bytes: []byte(query)}, true)
+		require.Error(t, err)
+		require.False(t, tableExists(t, db))
+	})
+
+	t.Run("migrations run outside of a transaction without transactional DDL", func(t *testing.T) {
+		db := openDB(t)
+		dialect := sqliteDialect()
+		dialect.TransactionalDDL = false
+		driver, err := WithInstance(db, dialect)
+		require.NoError(t, err)
+		defer driver.Close()
+
+		err = driver.Apply(&models.Migration{Version: 1, Name: "create_users", Direction: models.Up, Bytes: []byte(query)}, true)
+		require.Error(t, err)
+		require.True(t, tableExists(t, db))
+	})
+}
+
+func TestMetadataSchema(t *testing.T) {
+	driver, err := WithInstance(openDB(t), sqliteDialect())
+	require.NoError(t, err)
+	defer driver.Close()
+
+	generic := driver.(*LockableGeneric).Generic
+	_, err = generic.conn.ExecContext(context.Background(), "ATTACH DATABASE ? AS morph", filepath.Join(t.TempDir(), "morph-metadata.db"))
+	require.NoError(t, err, "should attach the metadata database")
+
+	require.NoError(t, driver.SetConfig("MetadataSchema", "morph"))
+	require.Error(t, driver.SetConfig("MetadataSchema", "morph; DROP TABLE users"), "should reject a schema name that is not an identifier")
+	require.Equal(t, `"morph"."db_lock"`, generic.quoteTable(generic.config.MutexTable), "should qualify the mutex table")
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err, "should create the migrations table in the metadata schema")
+	require.Empty(t, applied)
+
+	require.NoError(t, driver.Apply(&models.Migration{Version: 1, Name: "noop", Direction: models.Up, Bytes: []byte("SELECT 1;")}, true), "should save the version in the metadata schema")
+
+	applied, err = driver.AppliedMigrations()
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+
+	var count int
+	require.NoError(t, generic.conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM morph.sqlite_master WHERE name = 'db_migrations'").Scan(&count))
+	require.Equal(t, 1, count, "should create the migrations table in the metadata schema")
+	require.NoError(t, generic.conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM main.sqlite_master WHERE name = 'db_migrations'").Scan(&count))
+	require.Zero(t, count, "should not create the migrations table in the main schema")
+}
@@ -0,0 +1,234 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/morph/models"
+)
+
+// JournalTableSuffix is appended to the migrations table name to name the table
+// that stores the plan execution journal.
+const JournalTableSuffix = "_journal"
+
+// JournalColumns are the default names of the columns of a journal table: the
+// run ID, the step, the plan ID, the name, the version, the direction, the
+// rollback flag, the start, the finish, the outcome and the error message of
+// the steps.
+var JournalColumns = []string{"run_id", "step", "plan_id", "name", "version", "direction", "is_rollback", "started_at", "finished_at", "outcome", "error_message"}
+
+// Journal is implemented by drivers that can persist the steps taken while a plan
+// is being executed, so that the outcome of a run can be inspected afterwards.
+type Journal interface {
+	// SavePlanStep inserts the step into the journal, or updates the existing
+	// entry if the step with the same run ID and sequence has been saved before.
+	SavePlanStep(step *models.PlanStep) error
+	// PlanRuns returns the last limit plan runs, the most recent run first.
+	PlanRuns(limit int) ([]*models.PlanRun, error)
+}
+
+// Queryer is the connection that the journal and checksum tables are read and
+// written through, such as a *sql.Conn or a *sql.DB.
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// TableJournalConfig describes the SQL of a journal stored in a table.
+type TableJournalConfig struct {
+	// Driver is the name of the driver reported in the errors.
+	Driver string
+	// Table is the quoted name of the journal table, qualified with its schema
+	// if it is not in the current one.
+	Table string
+	// CreateTableQuery creates the journal table if it does not exist. The
+	// primary key of the table is the run ID and the step.
+	CreateTableQuery string
+	// Columns are the names of the columns of the table, in the order of
+	// JournalColumns, which is used if it is empty.
+	Columns []string
+	// UpsertClause follows the insert of a step, and updates the finish, the
+	// outcome and the error message of the step if it has been saved before,
+	// e.g. ON CONFLICT (run_id, step) DO UPDATE SET ...
+	UpsertClause string
+	// Placeholder is the style of the bind parameters of the database.
+	Placeholder PlaceholderStyle
+	// StatementTimeoutInSecs is the timeout of the queries.
+	StatementTimeoutInSecs int
+}
+
+// TableJournal is a Journal stored in a table, so it is supported by any SQL
+// database.
+type TableJournal struct {
+	conn   Queryer
+	config TableJournalConfig
+}
+
+// NewTableJournal returns a journal that stores the plan steps in the table
+// through the connection. The table is created on first use.
+func NewTableJournal(conn Queryer, config TableJournalConfig) *TableJournal {
+	if len(config.Columns) == 0 {
+		config.Columns = JournalColumns
+	}
+
+	return &TableJournal{
+		conn:   conn,
+		config: config,
+	}
+}
+
+func (j *TableJournal) createTableIfNotExists() error {
+	ctx, cancel := GetContext(j.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	if _, err := j.conn.ExecContext(ctx, j.config.CreateTableQuery); err != nil {
+		return &DatabaseError{
+			OrigErr: err,
+			Driver:  j.config.Driver,
+			Message: "failed while executing query",
+			Command: "create_journal_table_if_not_exists",
+			Query:   []byte(j.config.CreateTableQuery),
+		}
+	}
+
+	return nil
+}
+
+// SavePlanStep inserts or updates the journal entry of a plan step.
+func (j *TableJournal) SavePlanStep(step *models.PlanStep) error {
+	if err := j.createTableIfNotExists(); err != nil {
+		return err
+	}
+
+	ctx, cancel := GetContext(j.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	columns := j.config.Columns
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s", j.config.Table, strings.Join(columns, ", "), j.config.Placeholder.list(len(columns)), j.config.UpsertClause)
+	_, err := j.conn.ExecContext(ctx, query,
+		step.RunID,
+		step.Sequence,
+		step.PlanID,
+		step.Name,
+		step.Version,
+		string(step.Direction),
+		step.Rollback,
+		ToMillis(step.StartedAt),
+		ToMillis(step.FinishedAt),
+		string(step.Outcome),
+		step.Error,
+	)
+	if err != nil {
+		return &DatabaseError{
+			OrigErr: err,
+			Driver:  j.config.Driver,
+			Message: "failed to save plan step",
+			Command: "save_plan_step",
+			Query:   []byte(query),
+		}
+	}
+
+	return nil
+}
+
+// PlanRuns returns the last limit plan runs from the journal.
+func (j *TableJournal) PlanRuns(limit int) ([]*models.PlanRun, error) {
+	if err := j.createTableIfNotExists(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := GetContext(j.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	columns := j.config.Columns
+	runsQuery := fmt.Sprintf("SELECT %[2]s FROM %[1]s GROUP BY %[2]s ORDER BY MIN(%[3]s) DESC LIMIT %[4]s", j.config.Table, columns[0], columns[7], j.config.Placeholder.Placeholder(1))
+	rows, err := j.conn.QueryContext(ctx, runsQuery, limit)
+	if err != nil {
+		return nil, &DatabaseError{
+			OrigErr: err,
+			Driver:  j.config.Driver,
+			Message: "failed to fetch plan runs",
+			Command: "select_plan_runs",
+			Query:   []byte(runsQuery),
+		}
+	}
+	defer rows.Close()
+
+	var runIDs []string
+	for rows.Next() {
+		var runID string
+		if err := rows.Scan(&runID); err != nil {
+			return nil, &DatabaseError{
+				OrigErr: err,
+				Driver:  j.config.Driver,
+				Message: "failed to scan plan run row",
+				Command: "scan_plan_runs",
+			}
+		}
+		runIDs = append(runIDs, runID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &DatabaseError{
+			OrigErr: err,
+			Driver:  j.config.Driver,
+			Message: "failed to iterate plan run rows",
+			Command: "scan_plan_runs",
+			Query:   []byte(runsQuery),
+		}
+	}
+	rows.Close()
+
+	stepsQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s ORDER BY %s", strings.Join(columns, ", "), j.config.Table, columns[0], j.config.Placeholder.Placeholder(1), columns[1])
+	runs := make([]*models.PlanRun, 0, len(runIDs))
+	for _, runID := range runIDs {
+		run := &models.PlanRun{RunID: runID}
+		stepRows, err := j.conn.QueryContext(ctx, stepsQuery, runID)
+		if err != nil {
+			return nil, &DatabaseError{
+				OrigErr: err,
+				Driver:  j.config.Driver,
+				Message: "failed to fetch plan steps",
+				Command: "select_plan_steps",
+				Query:   []byte(stepsQuery),
+			}
+		}
+
+		for stepRows.Next() {
+			var step models.PlanStep
+			var direction, outcome string
+			var startedAt, finishedAt int64
+			if err := stepRows.Scan(&step.RunID, &step.Sequence, &step.PlanID, &step.Name, &step.Version, &direction, &step.Rollback, &startedAt, &finishedAt, &outcome, &step.Error); err != nil {
+				stepRows.Close()
+				return nil, &DatabaseError{
+					OrigErr: err,
+					Driver:  j.config.Driver,
+					Message: "failed to scan plan step row",
+					Command: "scan_plan_steps",
+				}
+			}
+			step.Direction = models.Direction(direction)
+			step.Outcome = models.StepOutcome(outcome)
+			step.StartedAt = FromMillis(startedAt)
+			step.FinishedAt = FromMillis(finishedAt)
+			run.PlanID = step.PlanID
+			run.Steps = append(run.Steps, &step)
+		}
+		err = stepRows.Err()
+		stepRows.Close()
+		if err != nil {
+			return nil, &DatabaseError{
+				OrigErr: err,
+				Driver:  j.config.Driver,
+				Message: "failed to iterate plan step rows",
+				Command: "scan_plan_steps",
+				Query:   []byte(stepsQuery),
+			}
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
@@ -8,7 +8,7 @@ import (
 )
 
 const (
-	// MutexTableName is the name being used for the mutex table
+	// MutexTableName is the default name of the mutex table, see Config.MutexTable.
 	MutexTableName = "db_lock"
 
 	// minWaitInterval is the minimum amount of time to wait between locking attempts
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// Mutex is a mutex of an in-memory database. It is shared by the drivers that
+// opened the same database, like the mutexes of the other drivers are shared by
+// the processes connected to the same database.
+type Mutex struct {
+	key string
+	db  *database
+
+	// lock guards locked, and is not itself related to the database lock.
+	lock   sync.Mutex
+	locked bool
+
+	logger drivers.Logger
+}
+
+// NewMutex creates a mutex with the given key name. Like the mutexes of the
+// other drivers, mutexes with the same key are distinct if they are stored in
+// different mutex tables.
+//
+// returns error if key is empty.
+func (driver *Memory) NewMutex(key string, logger drivers.Logger) (drivers.Locker, error) {
+	key, err := drivers.MakeLockKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mutex{
+		key:    driver.metadataTable(driver.config.MutexTable) + ":" + key,
+		db:     driver.db,
+		logger: logger,
+	}, nil
+}
+
+// tryLock makes a single attempt to lock the mutex. If the key is already
+// locked, it returns a channel that is closed on the next unlock.
+func (m *Mutex) tryLock() (bool, <-chan struct{}) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	if m.db.locks[m.key] {
+		return false, m.db.unlocked
+	}
+
+	m.db.locks[m.key] = true
+	return true, nil
+}
+
+// Lock locks m unless the context is canceled. If the mutex is already locked by any other
+// instance, including the current one, the calling goroutine blocks until the mutex can be locked,
+// or the context is canceled.
+//
+// The mutex is locked only if a nil error is returned.
+func (m *Mutex) Lock(ctx context.Context) error {
+	for {
+		locked, unlocked := m.tryLock()
+		if locked {
+			m.lock.Lock()
+			m.locked = true
+			m.lock.Unlock()
+			return nil
+		}
+
+		m.logger.Println("DB is locked, going to wait until it is unlocked.")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-unlocked:
+		}
+	}
+}
+
+// Unlock unlocks m. It is a run-time error if m is not locked on entry to Unlock.
+func (m *Mutex) Unlock() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.locked {
+		panic("mutex has not been acquired")
+	}
+	m.locked = false
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	delete(m.db.locks, m.key)
+	close(m.db.unlocked)
+	m.db.unlocked = make(chan struct{})
+
+	return nil
+}
@@ -0,0 +1,450 @@
+// Package memory provides a driver that keeps the applied migrations in memory
+// instead of a database, so that the behavior of the engine, interceptors,
+// plans and rollbacks can be unit tested without any database.
+//
+// The driver does not interpret SQL. It splits the migrations into statements,
+// records the statements it has run and the applied versions, and can be told
+// to fail specific migrations to exercise the error paths.
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+const driverName = "memory"
+const defaultMigrationMaxSize = 10 * 1 << 20 // 10 MB
+
+// add here any custom driver configuration
+var configParams = []string{
+	"x-metadata-schema",
+	"x-migration-max-size",
+	"x-migrations-table",
+	"x-mutex-table",
+	"x-statement-timeout",
+}
+
+var (
+	_ drivers.Driver   = (*Memory)(nil)
+	_ drivers.Lockable = (*Memory)(nil)
+)
+
+var (
+	databasesLock sync.Mutex
+	// databases are the named databases opened with Open, shared by the drivers
+	// opening the same name.
+	databases = make(map[string]*database)
+)
+
+func init() {
+	drivers.Register(driverName, func(connURL string) (drivers.Driver, error) {
+		return Open(connURL)
+	})
+}
+
+// database is the state of an in-memory database, which may be shared by
+// several drivers.
+type database struct {
+	mu sync.Mutex
+	// tables are the applied migrations by migrations table, qualified with
+	// the metadata schema.
+	tables map[string]map[uint64]*models.Migration
+	// statements are the statements that have been run, in order.
+	statements []string
+	// locks are the keys of the locked mutexes, prefixed with their table.
+	locks map[string]bool
+	// unlocked is closed whenever a mutex is unlocked, to wake up the waiters.
+	unlocked chan struct{}
+}
+
+func newDatabase() *database {
+	return &database{
+		tables:   make(map[string]map[uint64]*models.Migration),
+		locks:    make(map[string]bool),
+		unlocked: make(chan struct{}),
+	}
+}
+
+type failure struct {
+	statement int
+	err       error
+}
+
+// Memory is a driver that applies the migrations to an in-memory database.
+type Memory struct {
+	db     *database
+	config *drivers.Config
+	closed bool
+
+	// failuresLock guards failures, which are the failures set with
+	// FailMigration by migration name and direction.
+	failuresLock sync.Mutex
+	failures     map[string]failure
+}
+
+// New returns a driver with a new, empty, database.
+func New() *Memory {
+	return &Memory{
+		db:       newDatabase(),
+		config:   getDefaultConfig(),
+		failures: make(map[string]failure),
+	}
+}
+
+// Open returns a driver for the database named in the connection URL, e.g.
+// memory://tests. Drivers opened with the same name share the database, as
+// long as the process runs, which allows to simulate concurrent deployments.
+// A URL without a name opens a new database. The custom parameters of the other
+// drivers, such as x-migrations-table, are supported.
+func Open(connURL string) (*Memory, error) {
+	customParams, err := drivers.ExtractCustomParams(connURL, configParams)
+	if err != nil {
+		return nil, &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to parse custom parameters from url"}
+	}
+
+	sanitizedConnURL, err := drivers.RemoveParamsFromURL(connURL, configParams)
+	if err != nil {
+		return nil, &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to sanitize url from custom parameters"}
+	}
+
+	config, err := mergeConfigWithParams(customParams, getDefaultConfig())
+	if err != nil {
+		return nil, &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to merge custom params to driver config"}
+	}
+
+	name := strings.TrimPrefix(sanitizedConnURL, driverName+"://")
+	name = strings.TrimSuffix(name, "?")
+
+	driver := New()
+	driver.config = config
+	if name == "" {
+		return driver, nil
+	}
+
+	databasesLock.Lock()
+	defer databasesLock.Unlock()
+
+	db, ok := databases[name]
+	if !ok {
+		db = newDatabase()
+		databases[name] = db
+	}
+	driver.db = db
+
+	return driver, nil
+}
+
+func getDefaultConfig() *drivers.Config {
+	return &drivers.Config{
+		MigrationsTable:        "db_migrations",
+		MutexTable:             drivers.MutexTableName,
+		StatementTimeoutInSecs: 60,
+		MigrationMaxSize:       defaultMigrationMaxSize,
+	}
+}
+
+func mergeConfigWithParams(params map[string]string, config *drivers.Config) (*drivers.Config, error) {
+	var err error
+
+	for _, configKey := range configParams {
+		if v, ok := params[configKey]; ok {
+			switch configKey {
+			case "x-migration-max-size":
+				if config.MigrationMaxSize, err = strconv.Atoi(v); err != nil {
+					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
+				}
+			case "x-migrations-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
+				config.MigrationsTable = v
+			case "x-metadata-schema":
+				if err = drivers.ValidateSchemaName(v); err != nil {
+					return nil, err
+				}
+				config.MetadataSchema = v
+			case "x-mutex-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
+				config.MutexTable = v
+			case "x-statement-timeout":
+				if config.StatementTimeoutInSecs, err = strconv.Atoi(v); err != nil {
+					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
+				}
+			}
+		}
+	}
+
+	return config, nil
+}
+
+func (driver *Memory) connected() error {
+	if driver.closed {
+		return &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	return nil
+}
+
+func (driver *Memory) Ping() error {
+	return driver.connected()
+}
+
+// Close closes the driver. The database stays available to the other drivers
+// opened with the same name.
+func (driver *Memory) Close() error {
+	driver.closed = true
+	return nil
+}
+
+// FailMigration makes Apply return err, wrapped in a drivers.DatabaseError, for
+// the migration with the name and direction, until ClearFailures is called. The
+// migration fails when running its statement at index statement: the statements
+// before it are run, and are rolled back with the rest of the migration unless
+// the migration is non-transactional. A negative index, or one past the last
+// statement, fails the migration while saving its version, hence not at all if
+// its version is not saved.
+func (driver *Memory) FailMigration(name string, direction models.Direction, statement int, err error) {
+	driver.failuresLock.Lock()
+	defer driver.failuresLock.Unlock()
+
+	driver.failures[failureKey(name, direction)] = failure{statement: statement, err: err}
+}
+
+// ClearFailures removes the failures set with FailMigration.
+func (driver *Memory) ClearFailures() {
+	driver.failuresLock.Lock()
+	defer driver.failuresLock.Unlock()
+
+	driver.failures = make(map[string]failure)
+}
+
+func failureKey(name string, direction models.Direction) string {
+	return name + "." + string(direction)
+}
+
+// Statements returns the statements run on the database, in order. The
+// statements of the migrations that have been rolled back are not included.
+func (driver *Memory) Statements() []string {
+	driver.db.mu.Lock()
+	defer driver.db.mu.Unlock()
+
+	return append([]string(nil), driver.db.statements...)
+}
+
+func (driver *Memory) Apply(migration *models.Migration, saveVersion bool) error {
+	if err := driver.connected(); err != nil {
+		return err
+	}
+
+	directives, err := migration.Directives()
+	if err != nil {
+		return &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to parse migration directives"}
+	}
+
+	var statements []string
+	if !migration.RecordOnly {
+		statements = splitStatements(migration.Query())
+	}
+
+	driver.failuresLock.Lock()
+	f, fails := driver.failures[failureKey(migration.Name, migration.Direction)]
+	driver.failuresLock.Unlock()
+
+	driver.db.mu.Lock()
+	defer driver.db.mu.Unlock()
+
+	// Without a transaction the statements are applied as they run.
+	var executed []string
+	commit := func() {
+		driver.db.statements = append(driver.db.statements, executed...)
+		executed = nil
+	}
+
+	for i, statement := range statements {
+		if fails && f.statement == i {
+			if directives.NonTransactional {
+				commit()
+			}
+			return &drivers.DatabaseError{
+				OrigErr: f.err,
+				Driver:  driverName,
+				Message: "failed to execute migration",
+				Command: "executing_query",
+				Query:   []byte(statement),
+			}
+		}
+
+		executed = append(executed, statement)
+		if directives.NonTransactional {
+			commit()
+		}
+	}
+
+	if saveVersion {
+		if fails && (f.statement < 0 || f.statement >= len(statements)) {
+			err = f.err
+		} else {
+			err = driver.saveVersion(migration)
+		}
+
+		if err != nil {
+			if directives.NonTransactional {
+				commit()
+			}
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driverName,
+				Message: "failed when updating migrations table with the new version",
+				Command: "update_version",
+			}
+		}
+	}
+
+	commit()
+
+	return nil
+}
+
+// metadataTable returns the name of a table morph keeps its state in,
+// qualified with the metadata schema if there is one.
+func (driver *Memory) metadataTable(name string) string {
+	if driver.config.MetadataSchema == "" {
+		return name
+	}
+
+	return driver.config.MetadataSchema + "." + name
+}
+
+// saveVersion records the version of an up migration, or deletes the one of a
+// down migration. It must be called with the lock of the database held.
+func (driver *Memory) saveVersion(migration *models.Migration) error {
+	name := driver.metadataTable(driver.config.MigrationsTable)
+	table, ok := driver.db.tables[name]
+	if !ok {
+		table = make(map[uint64]*models.Migration)
+		driver.db.tables[name] = table
+	}
+
+	if migration.Direction == models.Down {
+		delete(table, migration.Version)
+		return nil
+	}
+
+	if _, ok := table[migration.Version]; ok {
+		return fmt.Errorf("version %d has already been applied", migration.Version)
+	}
+
+	var appliedOrder uint64
+	for _, applied := range table {
+		if applied.AppliedOrder > appliedOrder {
+			appliedOrder = applied.AppliedOrder
+		}
+	}
+
+	table[migration.Version] = &models.Migration{
+		Name:         migration.Name,
+		Version:      migration.Version,
+		Direction:    models.Up,
+		SkipReason:   migration.SkipReason,
+		AppliedOrder: appliedOrder + 1,
+	}
+
+	return nil
+}
+
+// AppliedMigrations returns the migrations recorded in the migrations table,
+// sorted by version.
+func (driver *Memory) AppliedMigrations() ([]*models.Migration, error) {
+	if err := driver.connected(); err != nil {
+		return nil, err
+	}
+
+	driver.db.mu.Lock()
+	defer driver.db.mu.Unlock()
+
+	table := driver.db.tables[driver.metadataTable(driver.config.MigrationsTable)]
+	appliedMigrations := make([]*models.Migration, 0, len(table))
+	for _, migration := range table {
+		m := *migration
+		appliedMigrations = append(appliedMigrations, &m)
+	}
+
+	sort.Slice(appliedMigrations, func(i, j int) bool {
+		return appliedMigrations[i].Version < appliedMigrations[j].Version
+	})
+
+	return appliedMigrations, nil
+}
+
+func (driver *Memory) SetConfig(key string, value interface{}) error {
+	if driver.config != nil {
+		switch key {
+		case "StatementTimeoutInSecs":
+			n, ok := value.(int)
+			if ok {
+				driver.config.StatementTimeoutInSecs = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MigrationsTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				driver.config.MigrationsTable = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MetadataSchema":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateSchemaName(n); err != nil {
+					return err
+				}
+				driver.config.MetadataSchema = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MutexTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				driver.config.MutexTable = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		}
+	}
+
+	return fmt.Errorf("incorrect key name %q", key)
+}
+
+// splitStatements splits a query into its statements, dropping the empty ones.
+// Semicolons are not parsed out of string literals or comments.
+func splitStatements(query string) []string {
+	var statements []string
+	for _, statement := range strings.Split(query, ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	return statements
+}
@@ -0,0 +1,269 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/drivers/drivertest"
+	"github.com/mattermost/morph/models"
+)
+
+var errBroken = errors.New("relation conformance_missing does not exist")
+
+func newMigration(t *testing.T, fileName, query string) *models.Migration {
+	m, err := models.NewMigration(io.NopCloser(strings.NewReader(query)), fileName)
+	require.NoError(t, err)
+
+	return m
+}
+
+func TestConformance(t *testing.T) {
+	drivertest.RunConformance(t, func(t *testing.T) drivers.Driver {
+		driver := New()
+		// the driver does not interpret SQL, so it is told which migration fails
+		driver.FailMigration("broken", models.Up, 0, errBroken)
+		t.Cleanup(func() {
+			require.NoError(t, driver.Close())
+		})
+
+		return driver
+	}, drivertest.WithConnURL(driverName, "memory://conformance"))
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("drivers opened with the same name share the database", func(t *testing.T) {
+		first, err := Open("memory://shared")
+		require.NoError(t, err)
+		second, err := Open("memory://shared?x-migrations-table=db_migrations")
+		require.NoError(t, err)
+		other, err := Open("memory://other")
+		require.NoError(t, err)
+
+		require.NoError(t, first.Apply(newMigration(t, "000001_create_users.up.sql", "CREATE TABLE users (id integer);"), true))
+
+		applied, err := second.AppliedMigrations()
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		require.Equal(t, []string{"CREATE TABLE users (id integer)"}, second.Statements())
+
+		applied, err = other.AppliedMigrations()
+		require.NoError(t, err)
+		require.Empty(t, applied)
+	})
+
+	t.Run("drivers opened without a name have their own database", func(t *testing.T) {
+		first, err := Open("memory://")
+		require.NoError(t, err)
+		second, err := Open("memory://")
+		require.NoError(t, err)
+
+		require.NoError(t, first.Apply(newMigration(t, "000001_create_users.up.sql", "CREATE TABLE users (id integer);"), true))
+		require.Empty(t, second.Statements())
+	})
+
+	t.Run("custom parameters are applied to the configuration", func(t *testing.T) {
+		driver, err := Open("memory://params?x-migrations-table=custom_migrations&x-statement-timeout=10&x-metadata-schema=morph&x-mutex-table=custom_lock")
+		require.NoError(t, err)
+		require.Equal(t, "custom_migrations", driver.config.MigrationsTable)
+		require.Equal(t, 10, driver.config.StatementTimeoutInSecs)
+		require.Equal(t, "morph", driver.config.MetadataSchema)
+		require.Equal(t, "custom_lock", driver.config.MutexTable)
+
+		_, err = Open("memory://params?x-statement-timeout=invalid")
+		require.EqualError(t, err, "driver: memory, message: failed to merge custom params to driver config, originalError: failed to cast config param x-statement-timeout of invalid ")
+
+		_, err = Open("memory://params?x-migrations-table=custom_migrations;drop&x-statement-timeout=10")
+		require.Error(t, err, "should reject the whole value of a parameter rather than a prefix of it")
+	})
+
+	t.Run("closed drivers cannot be used", func(t *testing.T) {
+		driver := New()
+		require.NoError(t, driver.Close())
+
+		require.Error(t, driver.Ping())
+		_, err := driver.AppliedMigrations()
+		require.EqualError(t, err, "driver: memory, message: database connection is missing, originalError: driver has no connection established ")
+	})
+}
+
+func TestApply(t *testing.T) {
+	query := "CREATE TABLE users (id integer);\nCREATE INDEX idx_users ON users (id);\nINSERT INTO users (id) VALUES (1);"
+
+	t.Run("a failing transactional migration is rolled back", func(t *testing.T) {
+		driver := New()
+		driver.FailMigration("create_users", models.Up, 1, errBroken)
+
+		err := driver.Apply(newMigration(t, "000001_create_users.up.sql", query), true)
+		require.EqualError(t, err, "driver: memory, message: failed to execute migration, command: executing_query, originalError: relation conformance_missing does not exist, query: \n\nCREATE INDEX idx_users ON users (id)\n")
+		require.Equal(t, errBroken, err.(*drivers.DatabaseError).OrigErr)
+		require.Empty(t, driver.Statements())
+
+		applied, err := driver.AppliedMigrations()
+		require.NoError(t, err)
+		require.Empty(t, applied)
+	})
+
+	t.Run("a failing non-transactional migration keeps the statements that ran", func(t *testing.T) {
+		driver := New()
+		driver.FailMigration("create_users", models.Up, 1, errBroken)
+
+		err := driver.Apply(newMigration(t, "000001_create_users.up.sql", "-- morph:nontransactional\n"+query), true)
+		require.Error(t, err)
+		require.Equal(t, []string{"-- morph:nontransactional\nCREATE TABLE users (id integer)"}, driver.Statements())
+
+		applied, err := driver.AppliedMigrations()
+		require.NoError(t, err)
+		require.Empty(t, applied)
+	})
+
+	t.Run("a failure past the last statement fails to save the version", func(t *testing.T) {
+		driver := New()
+		driver.FailMigration("create_users", models.Up, -1, errBroken)
+
+		require.NoError(t, driver.Apply(newMigration(t, "000001_create_users.up.sql", query), false))
+		require.Len(t, driver.Statements(), 3)
+
+		err := driver.Apply(newMigration(t, "000001_create_users.up.sql", query), true)
+		require.EqualError(t, err, "driver: memory, message: failed when updating migrations table with the new version, command: update_version, originalError: relation conformance_missing does not exist, query: \n\n\n")
+		require.Len(t, driver.Statements(), 3, "should roll back the statements of the migration")
+
+		driver.ClearFailures()
+		require.NoError(t, driver.Apply(newMigration(t, "000001_create_users.up.sql", query), true))
+		require.Len(t, driver.Statements(), 6)
+	})
+
+	t.Run("an applied version cannot be saved twice", func(t *testing.T) {
+		driver := New()
+
+		require.NoError(t, driver.Apply(newMigration(t, "000001_create_users.up.sql", query), true))
+		err := driver.Apply(newMigration(t, "000001_create_users.up.sql", query), true)
+		require.EqualError(t, err, "driver: memory, message: failed when updating migrations table with the new version, command: update_version, originalError: version 1 has already been applied, query: \n\n\n")
+		require.Len(t, driver.Statements(), 3)
+	})
+}
+
+func TestLock(t *testing.T) {
+	driver := New()
+	logger := log.New(io.Discard, "", 0)
+
+	first, err := driver.NewMutex("test-lock-key", logger)
+	require.NoError(t, err)
+	second, err := driver.NewMutex("test-lock-key", logger)
+	require.NoError(t, err)
+
+	require.NoError(t, first.Lock(context.Background()))
+
+	locked := make(chan error)
+	go func() {
+		locked <- second.Lock(context.Background())
+	}()
+
+	select {
+	case <-locked:
+		require.FailNow(t, "should wait until the mutex is unlocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Unlock())
+	require.NoError(t, <-locked, "should lock the mutex once it is unlocked")
+	require.NoError(t, second.Unlock())
+
+	require.Panics(t, func() { _ = second.Unlock() }, "should panic when unlocking a mutex that is not locked")
+
+	require.NoError(t, driver.SetConfig("MutexTable", "other_lock"))
+	other, err := driver.NewMutex("test-lock-key", logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, first.Lock(ctx))
+	require.NoError(t, other.Lock(ctx), "should not share the mutexes of another mutex table")
+	require.NoError(t, other.Unlock())
+	require.NoError(t, first.Unlock())
+}
+
+type testSource struct {
+	migrations []*models.Migration
+}
+
+func (s *testSource) Migrations() []*models.Migration {
+	return s.migrations
+}
+
+func TestEngine(t *testing.T) {
+	src := &testSource{migrations: []*models.Migration{
+		newMigration(t, "000001_create_users.up.sql", "CREATE TABLE users (id integer);"),
+		newMigration(t, "000001_create_users.down.sql", "DROP TABLE users;"),
+		newMigration(t, "000002_create_teams.up.sql", "CREATE TABLE teams (id integer);"),
+		newMigration(t, "000002_create_teams.down.sql", "DROP TABLE teams;"),
+		newMigration(t, "000003_create_posts.up.sql", "CREATE TABLE posts (id integer);"),
+		newMigration(t, "000003_create_posts.down.sql", "DROP TABLE posts;"),
+	}}
+
+	driver := New()
+	engine, err := morph.New(context.Background(), driver, src, morph.WithLogger(log.New(io.Discard, "", 0)), morph.WithLock("morph-test"))
+	require.NoError(t, err)
+	defer engine.Close()
+
+	migrations, err := engine.Diff(models.Up)
+	require.NoError(t, err)
+	plan, err := engine.GeneratePlan(migrations, true)
+	require.NoError(t, err)
+
+	driver.FailMigration("create_posts", models.Up, 0, errBroken)
+	require.Error(t, engine.ApplyPlan(plan), "should fail to apply the plan")
+
+	applied, err := driver.AppliedMigrations()
+	require.NoError(t, err)
+	require.Empty(t, applied, "should roll back the plan, including the failed migration")
+	require.Equal(t, []string{
+		"CREATE TABLE users (id integer)",
+		"CREATE TABLE teams (id integer)",
+		"DROP TABLE posts",
+		"DROP TABLE teams",
+		"DROP TABLE users",
+	}, driver.Statements())
+
+	driver.ClearFailures()
+	require.NoError(t, engine.ApplyAll())
+
+	applied, err = driver.AppliedMigrations()
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+}
+
+func TestLargeVersions(t *testing.T) {
+	src := &testSource{migrations: []*models.Migration{
+		newMigration(t, "1700000000000000000_create_users.up.sql", "CREATE TABLE users (id integer);"),
+		newMigration(t, "1700000000000000000_create_users.down.sql", "DROP TABLE users;"),
+		newMigration(t, "1700000000500000000_create_teams.up.sql", "CREATE TABLE teams (id integer);"),
+		newMigration(t, "1700000000500000000_create_teams.down.sql", "DROP TABLE teams;"),
+	}}
+	require.Equal(t, uint64(1700000000500000000), src.migrations[2].Version, "should not truncate the version")
+
+	driver := New()
+	engine, err := morph.New(context.Background(), driver, src, morph.WithLogger(log.New(io.Discard, "", 0)))
+	require.NoError(t, err)
+	defer engine.Close()
+
+	var intercepted bool
+	engine.AddInterceptor(1700000000500000000, models.Up, func() error {
+		intercepted = true
+		return nil
+	})
+	require.NoError(t, engine.ApplyAll())
+	require.True(t, intercepted, "should call the interceptor of the version")
+	require.Equal(t, []string{"CREATE TABLE users (id integer)", "CREATE TABLE teams (id integer)"}, driver.Statements(), "should apply the migrations in order")
+
+	_, err = models.NewMigration(io.NopCloser(strings.NewReader("SELECT 1;")), "9223372036854775808_overflow.up.sql")
+	require.Error(t, err, "should reject a version that does not fit in a bigint")
+}
@@ -0,0 +1,277 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TableMutexConfig describes the SQL of a mutex stored in a table.
+type TableMutexConfig struct {
+	// Table is the quoted name of the mutex table, qualified with its schema if
+	// it is not in the current one. MutexTableName is used if it is empty.
+	Table string
+	// CreateTableQuery creates the mutex table if it does not exist. The table
+	// has an id column, a varchar(64) primary key, and an expireat column, a
+	// bigint holding the expiry of the lock as a unix timestamp.
+	CreateTableQuery string
+	// Placeholder is the style of the bind parameters of the database.
+	Placeholder PlaceholderStyle
+	// IsUniqueViolation reports whether an error is a violation of the primary
+	// key of the table, which means that the mutex is locked. It is optional and
+	// only used for logging.
+	IsUniqueViolation func(err error) bool
+}
+
+// TableMutex is similar to sync.Mutex, except usable by morph to lock the db.
+// It is stored in a table, so it is supported by any SQL database.
+//
+// Pick a unique name for each mutex your plugin requires.
+//
+// A TableMutex must not be copied after first use.
+type TableMutex struct {
+	noCopy // nolint:unused
+	key    string
+	config TableMutexConfig
+
+	// lock guards the variables used to manage the refresh task, and is not itself related to
+	// the db lock.
+	lock        sync.Mutex
+	stopRefresh chan bool
+	refreshDone chan bool
+	conn        *sql.Conn
+
+	logger Logger
+}
+
+// NewTableMutex creates the mutex table if it does not exist, and a mutex with
+// the given key name that locks the table through the connection. The
+// connection is closed when the mutex is unlocked.
+//
+// returns error if key is empty.
+func NewTableMutex(conn *sql.Conn, key string, logger Logger, config TableMutexConfig) (*TableMutex, error) {
+	key, err := MakeLockKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), TTL)
+	defer cancel()
+
+	if _, err = conn.ExecContext(ctx, config.CreateTableQuery); err != nil {
+		return nil, err
+	}
+
+	return &TableMutex{
+		key:    key,
+		config: config,
+		conn:   conn,
+		logger: logger,
+	}, nil
+}
+
+// query formats a query of the mutex table with the placeholders of its n bind
+// parameters.
+func (m *TableMutex) query(format string, n int) string {
+	table := m.config.Table
+	if table == "" {
+		table = MutexTableName
+	}
+
+	args := append([]interface{}{table}, m.config.Placeholder.Placeholders(n)...)
+	return fmt.Sprintf(format, args...)
+}
+
+// tryLock makes a single attempt to lock the mutex, returning true only if successful.
+func (m *TableMutex) tryLock(ctx context.Context) (bool, error) {
+	now := time.Now()
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer m.finalizeTx(tx)
+
+	query := m.query("INSERT INTO %s (id, expireat) VALUES (%s, %s)", 2)
+	if _, err := tx.Exec(query, m.key, now.Add(TTL).Unix()); err != nil {
+		if m.config.IsUniqueViolation != nil && m.config.IsUniqueViolation(err) {
+			m.logger.Println("DB is locked, going to try acquire the lock if it is expired.")
+		}
+		m.finalizeTx(tx)
+
+		err2 := m.releaseLock(ctx, now)
+		if err2 == nil { // lock has been released due to expiration
+			return true, nil
+		} else {
+			m.logger.Printf("Failed to release lock: %v", err2)
+		}
+
+		return false, fmt.Errorf("failed to lock mutex: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m *TableMutex) releaseLock(ctx context.Context, t time.Time) error {
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer m.finalizeTx(tx)
+
+	e, err := m.getExpireAt(tx)
+	if err != nil {
+		return err
+	}
+
+	if t.Unix() < e {
+		return errors.New("could not release the lock")
+	}
+
+	query := m.query("UPDATE %s SET expireat = %s WHERE id = %s", 2)
+	if _, err = tx.Exec(query, t.Add(TTL).Unix(), m.key); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("unable to set new expireat for mutex: %w", err)
+	}
+
+	return nil
+}
+
+func (m *TableMutex) getExpireAt(tx *sql.Tx) (int64, error) {
+	var expireAt int64
+	query := m.query("SELECT expireat FROM %s WHERE id = %s", 1)
+	err := tx.QueryRow(query, m.key).Scan(&expireAt)
+	if err != nil {
+		return -1, fmt.Errorf("failed to fetch mutex from db: %w", err)
+	}
+
+	return expireAt, nil
+}
+
+// refreshLock rewrites the lock key value with a new expiry, returning nil only if successful.
+func (m *TableMutex) refreshLock(ctx context.Context) error {
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer m.finalizeTx(tx)
+
+	e, err := m.getExpireAt(tx)
+	if err != nil {
+		return err
+	}
+
+	tmp := time.Unix(e, 0)
+	query := m.query("UPDATE %s SET expireat = %s WHERE id = %s", 2)
+	if _, err = tx.Exec(query, tmp.Add(TTL).Unix(), m.key); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("unable to refresh expireat for mutex: %w", err)
+	}
+
+	return nil
+}
+
+// Lock locks m unless the context is canceled. If the mutex is already locked by any other
+// instance, including the current one, the calling goroutine blocks until the mutex can be locked,
+// or the context is canceled.
+//
+// The mutex is locked only if a nil error is returned.
+func (m *TableMutex) Lock(ctx context.Context) error {
+	var waitInterval time.Duration
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitInterval):
+		}
+
+		ok, err := m.tryLock(ctx)
+		if err != nil || !ok {
+			m.logger.Printf("Failed to acquire lock. Trying again: %v\n", err)
+			waitInterval = NextWaitInterval(waitInterval, err)
+			continue
+		}
+
+		break
+	}
+
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		t := time.NewTicker(RefreshInterval)
+		for {
+			select {
+			case <-t.C:
+				err := m.refreshLock(ctx)
+				if err != nil {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	m.lock.Lock()
+	m.stopRefresh = stop
+	m.refreshDone = done
+	m.lock.Unlock()
+
+	return nil
+}
+
+// Unlock unlocks m. It is a run-time error if m is not locked on entry to Unlock.
+//
+// Just like sync.Mutex, a locked Lock is not associated with a particular goroutine or a process.
+func (m *TableMutex) Unlock() error {
+	m.lock.Lock()
+	if m.stopRefresh == nil {
+		m.lock.Unlock()
+		panic("mutex has not been acquired")
+	}
+
+	close(m.stopRefresh)
+	m.stopRefresh = nil
+	<-m.refreshDone
+	m.lock.Unlock()
+
+	defer m.conn.Close()
+
+	// If an error occurs deleting, the mutex will still expire, allowing later retry.
+	query := m.query("DELETE FROM %s WHERE id = %s", 1)
+	_, err := m.conn.ExecContext(context.Background(), query, m.key)
+	return err
+}
+
+func (m *TableMutex) finalizeTx(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		m.logger.Printf("failed to rollback transaction: %s", err)
+	}
+}
+
+// noCopy may be embedded into structs which must not be copied
+// after the first use.
+//
+// See https://golang.org/issues/8005#issuecomment-190753527
+// for details.
+type noCopy struct{} // nolint:unused
+
+// Lock is a no-op used by -copylocks checker from `go vet`.
+func (*noCopy) Lock() {} // nolint:unused
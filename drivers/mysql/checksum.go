@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// checksumColumns are the names of the columns of the checksum tables.
+var checksumColumns = []string{"Name", "Checksum", "AppliedAt"}
+
+// checksums returns the table that records the checksum of the last applied
+// version of named scripts, such as repeatable migrations.
+func (driver *MySQL) checksums(table, noun, command string) (*drivers.TableChecksums, error) {
+	if driver.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	return drivers.NewTableChecksums(driver.conn, drivers.TableChecksumsConfig{
+		Driver:                 driverName,
+		Table:                  table,
+		CreateTableQuery:       fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (Name varchar(255) NOT NULL, Checksum varchar(64) NOT NULL, AppliedAt bigint(20) NOT NULL, PRIMARY KEY (Name)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4", table),
+		Columns:                checksumColumns,
+		UpsertClause:           "ON DUPLICATE KEY UPDATE Checksum = VALUES(Checksum), AppliedAt = VALUES(AppliedAt)",
+		Placeholder:            drivers.QuestionPlaceholder,
+		StatementTimeoutInSecs: driver.config.StatementTimeoutInSecs,
+		Noun:                   noun,
+		Command:                command,
+	}), nil
+}
+
+// appliedChecksums returns the checksums recorded in the table, keyed by name.
+func (driver *MySQL) appliedChecksums(table, noun, command string) (map[string]string, error) {
+	checksums, err := driver.checksums(table, noun, command)
+	if err != nil {
+		return nil, err
+	}
+
+	return checksums.Applied()
+}
+
+// saveChecksum records the checksum of an applied script in the table.
+func (driver *MySQL) saveChecksum(table, noun, command, name, checksum string) error {
+	checksums, err := driver.checksums(table, noun, command)
+	if err != nil {
+		return err
+	}
+
+	return checksums.Save(name, checksum)
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// journalColumns are the names of the columns of the journal table.
+var journalColumns = []string{"RunId", "Step", "PlanId", "Name", "Version", "Direction", "IsRollback", "StartedAt", "FinishedAt", "Outcome", "ErrorMessage"}
+
+func (driver *MySQL) journalTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable + drivers.JournalTableSuffix)
+}
+
+// journal returns the journal of the plan runs, which is stored in the journal
+// table.
+func (driver *MySQL) journal() (*drivers.TableJournal, error) {
+	if driver.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	table := driver.journalTable()
+	return drivers.NewTableJournal(driver.conn, drivers.TableJournalConfig{
+		Driver:                 driverName,
+		Table:                  table,
+		CreateTableQuery:       fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (RunId varchar(64) NOT NULL, Step int NOT NULL, PlanId varchar(64) NOT NULL, Name varchar(255) NOT NULL, Version bigint(20) NOT NULL, Direction varchar(8) NOT NULL, IsRollback tinyint(1) NOT NULL, StartedAt bigint(20) NOT NULL, FinishedAt bigint(20) NOT NULL, Outcome varchar(16) NOT NULL, ErrorMessage text NOT NULL, PRIMARY KEY (RunId, Step)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4", table),
+		Columns:                journalColumns,
+		UpsertClause:           "ON DUPLICATE KEY UPDATE FinishedAt = VALUES(FinishedAt), Outcome = VALUES(Outcome), ErrorMessage = VALUES(ErrorMessage)",
+		Placeholder:            drivers.QuestionPlaceholder,
+		StatementTimeoutInSecs: driver.config.StatementTimeoutInSecs,
+	}), nil
+}
+
+// SavePlanStep inserts or updates the journal entry of a plan step.
+func (driver *MySQL) SavePlanStep(step *models.PlanStep) error {
+	journal, err := driver.journal()
+	if err != nil {
+		return err
+	}
+
+	return journal.SavePlanStep(step)
+}
+
+// PlanRuns returns the last limit plan runs from the journal.
+func (driver *MySQL) PlanRuns(limit int) ([]*models.PlanRun, error) {
+	journal, err := driver.journal()
+	if err != nil {
+		return nil, err
+	}
+
+	return journal.PlanRuns(limit)
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -19,8 +20,10 @@ const defaultMigrationMaxSize = 10 * 1 << 20 // 10 MB
 
 // add here any custom driver configuration
 var configParams = []string{
+	"x-metadata-schema",
 	"x-migration-max-size",
 	"x-migrations-table",
+	"x-mutex-table",
 	"x-statement-timeout",
 }
 
@@ -36,6 +39,17 @@ type MySQL struct {
 	config *driverConfig
 }
 
+func init() {
+	drivers.Register(driverName, func(connURL string) (drivers.Driver, error) {
+		// the DSN of the mysql driver has no scheme
+		driver, err := Open(strings.TrimPrefix(connURL, driverName+"://"))
+		if err != nil {
+			return nil, err
+		}
+		return driver, nil
+	})
+}
+
 func WithInstance(dbInstance *sql.DB) (*MySQL, error) {
 	driverConfig := getDefaultConfig()
 
@@ -131,7 +145,7 @@ func (driver *MySQL) createSchemaTableIfNotExists() (err error) {
 	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
 	defer cancel()
 
-	createTableIfNotExistsQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (Version bigint(20) NOT NULL, Name varchar(64) NOT NULL, PRIMARY KEY (Version)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4", driver.config.MigrationsTable)
+	createTableIfNotExistsQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (Version bigint(20) NOT NULL, Name varchar(64) NOT NULL, SkipReason text, AppliedOrder bigint(20), PRIMARY KEY (Version)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4", driver.migrationsTable())
 	if _, err = driver.conn.ExecContext(ctx, createTableIfNotExistsQuery); err != nil {
 		return &drivers.DatabaseError{
 			OrigErr: err,
@@ -142,21 +156,109 @@ func (driver *MySQL) createSchemaTableIfNotExists() (err error) {
 		}
 	}
 
+	for _, column := range []struct{ name, definition string }{
+		{name: "SkipReason", definition: "text"},
+		{name: "AppliedOrder", definition: "bigint(20)"},
+	} {
+		if err = driver.addColumnIfNotExists(ctx, column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	return driver.widenVersionColumnIfNarrow(ctx)
+}
+
+// widenVersionColumnIfNarrow upgrades migrations tables whose version column
+// cannot hold 64-bit versions, such as timestamps, e.g. tables created by other
+// tools with an int column.
+func (driver *MySQL) widenVersionColumnIfNarrow(ctx context.Context) error {
+	columnTypeQuery := "SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND COLUMN_NAME = 'Version'"
+	var dataType string
+	if err := driver.conn.QueryRowContext(ctx, columnTypeQuery, driver.config.MetadataSchema, driver.config.MigrationsTable).Scan(&dataType); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed while executing query",
+			Command: "check_version_column",
+			Query:   []byte(columnTypeQuery),
+		}
+	}
+	switch strings.ToLower(dataType) {
+	case "tinyint", "smallint", "mediumint", "int":
+	default:
+		return nil
+	}
+
+	alterColumnQuery := fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN Version bigint(20) NOT NULL", driver.migrationsTable())
+	if _, err := driver.conn.ExecContext(ctx, alterColumnQuery); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed while executing query",
+			Command: "widen_version_column",
+			Query:   []byte(alterColumnQuery),
+		}
+	}
+
 	return nil
 }
 
-func (driver *MySQL) Apply(migration *models.Migration, saveVersion bool) (err error) {
-	query := migration.Query()
-	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
-	defer cancel()
+// addColumnIfNotExists adds a column to the migrations tables of earlier
+// versions, e.g. SkipReason to the tables created before skipped migrations
+// were recorded. MySQL has no ADD COLUMN IF NOT EXISTS, hence the lookup in
+// information_schema.
+func (driver *MySQL) addColumnIfNotExists(ctx context.Context, column, definition string) error {
+	columnExistsQuery := "SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	var count int
+	if err := driver.conn.QueryRowContext(ctx, columnExistsQuery, driver.config.MetadataSchema, driver.config.MigrationsTable, column).Scan(&count); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed while executing query",
+			Command: "check_column",
+			Query:   []byte(columnExistsQuery),
+		}
+	}
+	if count > 0 {
+		return nil
+	}
 
-	if _, err := driver.conn.ExecContext(ctx, query); err != nil {
+	addColumnQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", driver.migrationsTable(), column, definition)
+	if _, err := driver.conn.ExecContext(ctx, addColumnQuery); err != nil {
 		return &drivers.DatabaseError{
 			OrigErr: err,
 			Driver:  driverName,
-			Message: "failed when applying migration",
-			Command: "apply_migration",
-			Query:   []byte(query),
+			Message: "failed while executing query",
+			Command: "add_column",
+			Query:   []byte(addColumnQuery),
+		}
+	}
+
+	return nil
+}
+
+func (driver *MySQL) Apply(migration *models.Migration, saveVersion bool) (err error) {
+	query := migration.Query()
+
+	// MySQL commits DDL statements implicitly, so migrations are never wrapped
+	// in a transaction and the nontransactional directive has no effect.
+	directives, err := migration.Directives()
+	if err != nil {
+		return &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to parse migration directives"}
+	}
+
+	ctx, cancel := drivers.GetContext(drivers.MigrationTimeout(driver.config.StatementTimeoutInSecs, directives))
+	defer cancel()
+
+	if !migration.RecordOnly {
+		if _, err := driver.conn.ExecContext(ctx, query, migration.Args...); err != nil {
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driverName,
+				Message: "failed when applying migration",
+				Command: "apply_migration",
+				Query:   []byte(query),
+			}
 		}
 	}
 
@@ -167,8 +269,8 @@ func (driver *MySQL) Apply(migration *models.Migration, saveVersion bool) (err e
 		return nil
 	}
 
-	updateVersionQuery := driver.addMigrationQuery(migration)
-	res, err := driver.conn.ExecContext(updateVersionContext, updateVersionQuery)
+	updateVersionQuery, args := driver.addMigrationQuery(migration)
+	res, err := driver.conn.ExecContext(updateVersionContext, updateVersionQuery, args...)
 	if err != nil {
 		return &drivers.DatabaseError{
 			OrigErr: err,
@@ -215,12 +317,12 @@ func (driver *MySQL) AppliedMigrations() (migrations []*models.Migration, err er
 		return nil, err
 	}
 
-	query := fmt.Sprintf("SELECT version, name FROM %s", driver.config.MigrationsTable)
+	query := fmt.Sprintf("SELECT version, name, COALESCE(SkipReason, ''), COALESCE(AppliedOrder, 0) FROM %s", driver.migrationsTable())
 	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
 	defer cancel()
 	var appliedMigrations []*models.Migration
-	var version uint32
-	var name string
+	var version, appliedOrder uint64
+	var name, skipReason string
 
 	rows, err := driver.conn.QueryContext(ctx, query)
 	if err != nil {
@@ -235,7 +337,7 @@ func (driver *MySQL) AppliedMigrations() (migrations []*models.Migration, err er
 	defer rows.Close()
 
 	for rows.Next() {
-		if err := rows.Scan(&version, &name); err != nil {
+		if err := rows.Scan(&version, &name, &skipReason, &appliedOrder); err != nil {
 			return nil, &drivers.DatabaseError{
 				OrigErr: err,
 				Driver:  driverName,
@@ -245,9 +347,11 @@ func (driver *MySQL) AppliedMigrations() (migrations []*models.Migration, err er
 		}
 
 		appliedMigrations = append(appliedMigrations, &models.Migration{
-			Name:      name,
-			Version:   version,
-			Direction: models.Up,
+			Name:         name,
+			Version:      version,
+			Direction:    models.Up,
+			SkipReason:   skipReason,
+			AppliedOrder: appliedOrder,
 		})
 	}
 
@@ -285,7 +389,20 @@ func mergeConfigWithParams(params map[string]string, config *driverConfig) (*dri
 					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
 				}
 			case "x-migrations-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
 				config.MigrationsTable = v
+			case "x-metadata-schema":
+				if err = drivers.ValidateSchemaName(v); err != nil {
+					return nil, err
+				}
+				config.MetadataSchema = v
+			case "x-mutex-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
+				config.MutexTable = v
 			case "x-statement-timeout":
 				if config.StatementTimeoutInSecs, err = strconv.Atoi(v); err != nil {
 					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
@@ -297,11 +414,22 @@ func mergeConfigWithParams(params map[string]string, config *driverConfig) (*dri
 	return config, nil
 }
 
-func (driver *MySQL) addMigrationQuery(migration *models.Migration) string {
+func (driver *MySQL) migrationsTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable)
+}
+
+// addMigrationQuery returns the statement that records the migration in the
+// migrations table, or deletes it for a down migration, with its bind
+// parameters.
+func (driver *MySQL) addMigrationQuery(migration *models.Migration) (string, []interface{}) {
 	if migration.Direction == models.Down {
-		return fmt.Sprintf("DELETE FROM %s WHERE (Version=%d AND NAME='%s')", driver.config.MigrationsTable, migration.Version, migration.Name)
+		return fmt.Sprintf("DELETE FROM %s WHERE Version = ? AND Name = ?", driver.migrationsTable()), []interface{}{migration.Version, migration.Name}
 	}
-	return fmt.Sprintf("INSERT INTO %s (Version, Name) VALUES (%d, '%s')", driver.config.MigrationsTable, migration.Version, migration.Name)
+
+	// the order is the next one after the latest applied migration, MySQL does
+	// not allow a subquery on the inserted table in VALUES
+	skipReason := sql.NullString{String: migration.SkipReason, Valid: migration.SkipReason != ""}
+	return fmt.Sprintf("INSERT INTO %[1]s (Version, Name, SkipReason, AppliedOrder) SELECT ?, ?, ?, COALESCE(MAX(AppliedOrder), 0) + 1 FROM %[1]s", driver.migrationsTable()), []interface{}{migration.Version, migration.Name, skipReason}
 }
 
 func (driver *MySQL) SetConfig(key string, value interface{}) error {
@@ -317,10 +445,33 @@ func (driver *MySQL) SetConfig(key string, value interface{}) error {
 		case "MigrationsTable":
 			n, ok := value.(string)
 			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
 				driver.config.MigrationsTable = n
 				return nil
 			}
 			return fmt.Errorf("incorrect value type for %s", key)
+		case "MetadataSchema":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateSchemaName(n); err != nil {
+					return err
+				}
+				driver.config.MetadataSchema = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MutexTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				driver.config.MutexTable = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
 		}
 	}
 
@@ -13,8 +13,10 @@ import (
 	"time"
 
 	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/drivers/drivertest"
 	"github.com/mattermost/morph/models"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -378,6 +380,35 @@ func (suite *MysqlTestSuite) TestWithInstance() {
 	suite.Assert().Equal(databaseName, mysqlDriver.config.databaseName)
 }
 
+func (suite *MysqlTestSuite) TestScratch() {
+	connectedDriver, teardown := suite.InitializeDriver(testConnURL)
+	defer teardown()
+
+	// the connection of the scratch is the only one the pool can hand out
+	// besides the connection of the driver
+	connectedDriver.db.SetMaxOpenConns(2)
+
+	scratch, err := connectedDriver.Scratch()
+	suite.Require().NoError(err, "should not error when creating a scratch database")
+
+	scratchName := scratch.(*scratchMySQL).config.databaseName
+	suite.Require().NoError(scratch.Apply(&models.Migration{Version: 1, Name: "create_users", Direction: models.Up, Bytes: []byte("CREATE TABLE users (id integer);")}, true))
+	suite.Require().NoError(scratch.Close())
+
+	var count int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", scratchName).Scan(&count)
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count, "should drop the scratch database")
+
+	mutex, err := connectedDriver.NewMutex("test-lock-key", log.New(os.Stderr, "", 0))
+	suite.Require().NoError(err, "should use the database of the driver after the scratch is closed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	suite.Require().NoError(mutex.Lock(ctx))
+	suite.Require().NoError(mutex.Unlock())
+}
+
 func TestMysqlTestSuite(t *testing.T) {
 	suite.Run(t, new(MysqlTestSuite))
 }
@@ -494,3 +525,45 @@ func (suite *MysqlTestSuite) TestSaveVersion() {
 	}, true)
 	suite.Require().NoError(err, "should not error when downgrading in a valid versioning scenario")
 }
+
+func (suite *MysqlTestSuite) TestConformance() {
+	drivertest.RunConformance(suite.T(), func(t *testing.T) drivers.Driver {
+		connectedDriver, err := Open(testConnURL)
+		require.NoError(t, err, "should not error when connecting to database from url")
+		t.Cleanup(func() {
+			require.NoError(t, connectedDriver.Close(), "should not error when closing the database connection")
+		})
+
+		return connectedDriver
+	}, drivertest.WithSlowQuery("SELECT SLEEP(2);"), drivertest.WithConnURL(driverName, testConnURL), drivertest.WithLockable(func(driver drivers.Driver) drivers.Lockable {
+		return drivertest.LockableFunc(func(key string, logger drivers.Logger) (drivers.Locker, error) {
+			mutex, err := driver.(*MySQL).NewMutex(key, logger)
+			if err != nil {
+				return nil, err
+			}
+			return mutex, nil
+		})
+	}))
+}
+
+func (suite *MysqlTestSuite) TestWidenVersionColumn() {
+	_, err := suite.testDB.Exec("CREATE TABLE db_migrations (Version int NOT NULL, Name varchar(64) NOT NULL, PRIMARY KEY (Version))")
+	suite.Require().NoError(err, "should not error when creating a migrations table with a narrow version column")
+
+	connectedDriver, teardown := suite.InitializeDriver(testConnURL)
+	defer teardown()
+
+	_, err = connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when upgrading the migrations table")
+
+	var dataType string
+	err = suite.testDB.QueryRow("SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'db_migrations' AND COLUMN_NAME = 'Version'").Scan(&dataType)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("bigint", dataType, "should widen the version column")
+
+	suite.Require().NoError(connectedDriver.Apply(&models.Migration{Version: 1700000000000000000, Name: "noop", Direction: models.Up, Bytes: []byte("SELECT 1;")}, true))
+	applied, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err)
+	suite.Require().Len(applied, 1)
+	suite.Assert().Equal(uint64(1700000000000000000), applied[0].Version)
+}
@@ -0,0 +1,19 @@
+package mysql
+
+import (
+	"github.com/mattermost/morph/drivers"
+)
+
+func (driver *MySQL) repeatableTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable + drivers.RepeatableTableSuffix)
+}
+
+// AppliedRepeatables returns the checksums of the applied repeatable migrations.
+func (driver *MySQL) AppliedRepeatables() (map[string]string, error) {
+	return driver.appliedChecksums(driver.repeatableTable(), "repeatable migration", "repeatable")
+}
+
+// SaveRepeatable records the checksum of an applied repeatable migration.
+func (driver *MySQL) SaveRepeatable(name, checksum string) error {
+	return driver.saveChecksum(driver.repeatableTable(), "repeatable migration", "repeatable", name, checksum)
+}
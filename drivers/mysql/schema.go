@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// DumpSchema describes the tables of the current database by reading the
+// information schema. Indexes backing a constraint are reported as part of the
+// constraint. Check constraints are not included, as they are not available on
+// every supported server version.
+func (driver *MySQL) DumpSchema() (*models.Schema, error) {
+	if driver.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	tablesQuery := "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME"
+	names, err := queryStrings(ctx, driver.conn, tablesQuery, driver.config.databaseName)
+	if err != nil {
+		return nil, dumpError(err, tablesQuery)
+	}
+
+	schema := &models.Schema{}
+	for _, name := range names {
+		if drivers.IsInternalTable(name, &driver.config.Config) {
+			continue
+		}
+
+		table, err := driver.dumpTable(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+	schema.Normalize()
+
+	return schema, nil
+}
+
+func (driver *MySQL) dumpTable(ctx context.Context, name string) (*models.Table, error) {
+	table := &models.Table{Name: name}
+	database := driver.config.databaseName
+
+	columnsQuery := `SELECT COLUMN_NAME, COLUMN_TYPE, EXTRA, IS_NULLABLE = 'YES', COALESCE(COLUMN_DEFAULT, '')
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`
+	rows, err := driver.conn.QueryContext(ctx, columnsQuery, database, name)
+	if err != nil {
+		return nil, dumpError(err, columnsQuery)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column models.Column
+		var extra string
+		if err := rows.Scan(&column.Name, &column.Type, &extra, &column.Nullable, &column.Default); err != nil {
+			return nil, dumpError(err, columnsQuery)
+		}
+		if extra != "" {
+			column.Type += " " + extra
+		}
+		table.Columns = append(table.Columns, &column)
+	}
+	rows.Close()
+
+	constraintsQuery := `SELECT tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE,
+		GROUP_CONCAT(kcu.COLUMN_NAME ORDER BY kcu.ORDINAL_POSITION),
+		COALESCE(MAX(kcu.REFERENCED_TABLE_NAME), ''),
+		COALESCE(GROUP_CONCAT(kcu.REFERENCED_COLUMN_NAME ORDER BY kcu.ORDINAL_POSITION), ''),
+		COALESCE(MAX(rc.UPDATE_RULE), ''), COALESCE(MAX(rc.DELETE_RULE), '')
+		FROM information_schema.TABLE_CONSTRAINTS tc
+		JOIN information_schema.KEY_COLUMN_USAGE kcu ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND kcu.TABLE_NAME = tc.TABLE_NAME AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		LEFT JOIN information_schema.REFERENTIAL_CONSTRAINTS rc ON rc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND rc.TABLE_NAME = tc.TABLE_NAME AND rc.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ? AND tc.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+		GROUP BY tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE`
+	rows, err = driver.conn.QueryContext(ctx, constraintsQuery, database, name)
+	if err != nil {
+		return nil, dumpError(err, constraintsQuery)
+	}
+	defer rows.Close()
+
+	constraintNames := map[string]bool{}
+	for rows.Next() {
+		var constraint models.Constraint
+		var columns, refTable, refColumns, onUpdate, onDelete string
+		if err := rows.Scan(&constraint.Name, &constraint.Type, &columns, &refTable, &refColumns, &onUpdate, &onDelete); err != nil {
+			return nil, dumpError(err, constraintsQuery)
+		}
+		columns = strings.ReplaceAll(columns, ",", ", ")
+		constraint.Definition = fmt.Sprintf("%s (%s)", constraint.Type, columns)
+		if constraint.Type == "FOREIGN KEY" {
+			constraint.Definition += fmt.Sprintf(" REFERENCES %s (%s)", refTable, strings.ReplaceAll(refColumns, ",", ", "))
+			if onUpdate != "" && onUpdate != "NO ACTION" && onUpdate != "RESTRICT" {
+				constraint.Definition += " ON UPDATE " + onUpdate
+			}
+			if onDelete != "" && onDelete != "NO ACTION" && onDelete != "RESTRICT" {
+				constraint.Definition += " ON DELETE " + onDelete
+			}
+		}
+		constraintNames[constraint.Name] = true
+		table.Constraints = append(table.Constraints, &constraint)
+	}
+	rows.Close()
+
+	indexesQuery := `SELECT INDEX_NAME, MIN(NON_UNIQUE) = 0, GROUP_CONCAT(COALESCE(COLUMN_NAME, '') ORDER BY SEQ_IN_INDEX)
+		FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? GROUP BY INDEX_NAME`
+	rows, err = driver.conn.QueryContext(ctx, indexesQuery, database, name)
+	if err != nil {
+		return nil, dumpError(err, indexesQuery)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index models.Index
+		var columns string
+		if err := rows.Scan(&index.Name, &index.Unique, &columns); err != nil {
+			return nil, dumpError(err, indexesQuery)
+		}
+		if constraintNames[index.Name] {
+			continue
+		}
+		index.Columns = strings.Split(columns, ",")
+		table.Indexes = append(table.Indexes, &index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dumpError(err, indexesQuery)
+	}
+
+	return table, nil
+}
+
+func queryStrings(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
+
+func dumpError(err error, query string) error {
+	return &drivers.DatabaseError{
+		OrigErr: err,
+		Driver:  driverName,
+		Message: "failed to dump schema",
+		Command: "dump_schema",
+		Query:   []byte(query),
+	}
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// scratchMySQL is a driver bound to a temporary database, which is dropped
+// when the driver is closed.
+type scratchMySQL struct {
+	*MySQL
+}
+
+// Scratch creates a temporary database and returns a driver that uses it.
+func (driver *MySQL) Scratch() (drivers.Driver, error) {
+	if driver.db == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no database instance"),
+			Message: "database instance is missing",
+			Driver:  driverName,
+		}
+	}
+
+	conn, err := driver.db.Conn(context.Background())
+	if err != nil {
+		return nil, &drivers.DatabaseError{Driver: driverName, Command: "grabbing_connection", OrigErr: err, Message: "failed to grab connection to the database"}
+	}
+
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	databaseName := fmt.Sprintf("morph_scratch_%d", time.Now().UnixNano())
+	for _, query := range []string{
+		fmt.Sprintf("CREATE DATABASE %s", databaseName),
+		fmt.Sprintf("USE %s", databaseName),
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			_ = conn.Close()
+			return nil, &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driverName,
+				Message: "failed to create scratch database",
+				Command: "create_scratch_database",
+				Query:   []byte(query),
+			}
+		}
+	}
+
+	config := *driver.config
+	config.databaseName = databaseName
+	config.closeDBonClose = false
+	// the bookkeeping tables of the scratch database are dropped with it
+	config.MetadataSchema = ""
+
+	return &scratchMySQL{
+		MySQL: &MySQL{
+			conn:   conn,
+			db:     driver.db,
+			config: &config,
+		},
+	}, nil
+}
+
+// Close drops the scratch database. The connection is discarded rather than
+// returned to the pool of the driver that created the scratch, which would
+// otherwise be handed out with the dropped database selected.
+func (driver *scratchMySQL) Close() error {
+	if driver.conn == nil {
+		return driver.MySQL.Close()
+	}
+
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	query := fmt.Sprintf("DROP DATABASE IF EXISTS %s", driver.config.databaseName)
+	_, err := driver.conn.ExecContext(ctx, query)
+
+	_ = driver.conn.Raw(func(interface{}) error {
+		return sqldriver.ErrBadConn
+	})
+	driver.conn = nil
+
+	if err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed to drop scratch database",
+			Command: "drop_scratch_database",
+			Query:   []byte(query),
+		}
+	}
+
+	return driver.MySQL.Close()
+}
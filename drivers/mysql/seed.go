@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+func (driver *MySQL) seedTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable + drivers.SeedTableSuffix)
+}
+
+// AppliedSeeds returns the checksums of the applied seeds.
+func (driver *MySQL) AppliedSeeds() (map[string]string, error) {
+	return driver.appliedChecksums(driver.seedTable(), "seed", "seed")
+}
+
+// SaveSeed records the checksum of an applied seed.
+func (driver *MySQL) SaveSeed(name, checksum string) error {
+	return driver.saveChecksum(driver.seedTable(), "seed", "seed", name, checksum)
+}
+
+// UpsertQuery returns an INSERT ... ON DUPLICATE KEY UPDATE statement that
+// updates the rows whose key columns already exist. MySQL matches the rows by
+// any unique index of the table, the keys are expected to be one of them. The
+// values of the rows are the bind parameters of the statement.
+func (driver *MySQL) UpsertQuery(table string, keys, columns []string, rows [][]interface{}) (string, []interface{}, error) {
+	values, args, err := drivers.ValuesList(rows, drivers.QuestionPlaceholder)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// an existing row is left as it is if every column is a key
+	update := drivers.UpdateColumns(keys, columns)
+	if len(update) == 0 {
+		update = keys[:1]
+	}
+
+	set := make([]string, 0, len(update))
+	for _, column := range update {
+		set = append(set, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE %s", table, strings.Join(columns, ", "), values, strings.Join(set, ", "))
+	return query, args, nil
+}
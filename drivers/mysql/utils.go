@@ -27,8 +27,16 @@ func getDefaultConfig() *driverConfig {
 	return &driverConfig{
 		Config: drivers.Config{
 			MigrationsTable:        "db_migrations",
+			MutexTable:             drivers.MutexTableName,
 			StatementTimeoutInSecs: 300,
 			MigrationMaxSize:       defaultMigrationMaxSize,
 		},
 	}
 }
+
+// metadataTable returns the name of a table morph keeps its state in, qualified
+// with the metadata database if there is one. Names are quoted with backticks,
+// which unlike double quotes do not depend on the ANSI_QUOTES SQL mode.
+func (driver *MySQL) metadataTable(name string) string {
+	return drivers.QuoteTableName(driver.config.MetadataSchema, name, "`")
+}
@@ -0,0 +1,47 @@
+package pgx
+
+import (
+	"fmt"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// checksums returns the table that records the checksum of the last applied
+// version of named scripts, such as repeatable migrations.
+func (pg *Postgres) checksums(table, noun, command string) (*drivers.TableChecksums, error) {
+	if pg.conn == nil {
+		return nil, errMissingConnection()
+	}
+
+	return drivers.NewTableChecksums(pg.sqlDB(), drivers.TableChecksumsConfig{
+		Driver:                 driverName,
+		Table:                  table,
+		CreateTableQuery:       fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name varchar not null primary key, checksum varchar(64) not null, applied_at bigint not null)", table),
+		UpsertClause:           "ON CONFLICT (name) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = EXCLUDED.applied_at",
+		Placeholder:            drivers.DollarPlaceholder,
+		StatementTimeoutInSecs: pg.config.StatementTimeoutInSecs,
+		Noun:                   noun,
+		Command:                command,
+	}), nil
+}
+
+// appliedChecksums returns the checksums recorded in the table, keyed by name.
+func (pg *Postgres) appliedChecksums(table, noun, command string) (map[string]string, error) {
+	checksums, err := pg.checksums(table, noun, command)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := checksums.Applied()
+	return applied, withDetails(err)
+}
+
+// saveChecksum records the checksum of an applied script in the table.
+func (pg *Postgres) saveChecksum(table, noun, command, name, checksum string) error {
+	checksums, err := pg.checksums(table, noun, command)
+	if err != nil {
+		return err
+	}
+
+	return withDetails(checksums.Save(name, checksum))
+}
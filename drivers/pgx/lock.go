@@ -0,0 +1,74 @@
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/mattermost/morph/drivers"
+)
+
+var _ drivers.Lockable = (*Postgres)(nil)
+
+// mutexConfig returns the SQL of the mutexes, which are stored in the mutex
+// table of the metadata schema.
+func (pg *Postgres) mutexConfig() drivers.TableMutexConfig {
+	table := pg.metadataTable(pg.config.MutexTable)
+
+	return drivers.TableMutexConfig{
+		Table:            table,
+		CreateTableQuery: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id varchar(64) PRIMARY KEY, expireat bigint);", table),
+		Placeholder:      drivers.DollarPlaceholder,
+		IsUniqueViolation: func(err error) bool {
+			var pgErr *pgconn.PgError
+			return errors.As(err, &pgErr) && pgErr.Code == "23505"
+		},
+	}
+}
+
+// NewMutex creates a mutex with the given key name. The mutexes lock the
+// database through connections of their own, from the pool of the driver or,
+// if the driver has been created with WithConn, opened with the config of its
+// connection.
+//
+// returns error if key is empty.
+func (pg *Postgres) NewMutex(key string, logger drivers.Logger) (drivers.Locker, error) {
+	if _, err := drivers.MakeLockKey(key); err != nil {
+		return nil, err
+	}
+
+	if pg.conn == nil {
+		return nil, errMissingConnection()
+	}
+
+	conn, err := pg.sqlDB().Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	mutex, err := drivers.NewTableMutex(conn, key, logger, pg.mutexConfig())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return mutex, nil
+}
+
+// sqlDB returns the database/sql handle of the driver, opened on first use on
+// the pool of the driver or, if the driver has been created with WithConn, with
+// the config of its connection. The driver must have a connection.
+func (pg *Postgres) sqlDB() *sql.DB {
+	if pg.db == nil {
+		if pg.pool != nil {
+			pg.db = stdlib.OpenDBFromPool(pg.pool)
+		} else {
+			pg.db = stdlib.OpenDB(*pg.conn.Config())
+		}
+	}
+
+	return pg.db
+}
@@ -0,0 +1,57 @@
+package drivers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PlaceholderStyle is the syntax of the bind parameters of a database.
+type PlaceholderStyle int
+
+const (
+	// QuestionPlaceholder is the ? syntax of MySQL and SQLite.
+	QuestionPlaceholder PlaceholderStyle = iota
+	// DollarPlaceholder is the $1 syntax of PostgreSQL.
+	DollarPlaceholder
+	// ColonPlaceholder is the :1 syntax of Oracle.
+	ColonPlaceholder
+	// AtPlaceholder is the @p1 syntax of SQL Server.
+	AtPlaceholder
+)
+
+// Placeholder returns the placeholder of the nth bind parameter of a query,
+// starting at 1.
+func (s PlaceholderStyle) Placeholder(n int) string {
+	switch s {
+	case DollarPlaceholder:
+		return "$" + strconv.Itoa(n)
+	case ColonPlaceholder:
+		return ":" + strconv.Itoa(n)
+	case AtPlaceholder:
+		return "@p" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// Placeholders returns the placeholders of the first n bind parameters of a
+// query.
+func (s PlaceholderStyle) Placeholders(n int) []interface{} {
+	placeholders := make([]interface{}, n)
+	for i := range placeholders {
+		placeholders[i] = s.Placeholder(i + 1)
+	}
+
+	return placeholders
+}
+
+// list returns the comma separated placeholders of the first n bind parameters
+// of a query.
+func (s PlaceholderStyle) list(n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = s.Placeholder(i + 1)
+	}
+
+	return strings.Join(placeholders, ", ")
+}
@@ -0,0 +1,19 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholder(t *testing.T) {
+	require.Equal(t, []interface{}{"?", "?"}, QuestionPlaceholder.Placeholders(2))
+	require.Equal(t, []interface{}{"$1", "$2"}, DollarPlaceholder.Placeholders(2))
+	require.Equal(t, []interface{}{":1", ":2"}, ColonPlaceholder.Placeholders(2))
+	require.Equal(t, []interface{}{"@p1", "@p2"}, AtPlaceholder.Placeholders(2))
+}
+
+func TestPlaceholderList(t *testing.T) {
+	require.Equal(t, "?, ?, ?", QuestionPlaceholder.list(3))
+	require.Equal(t, "$1, $2, $3", DollarPlaceholder.list(3))
+}
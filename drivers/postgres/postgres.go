@@ -18,17 +18,14 @@ var (
 	driverName              = "postgres"
 	defaultMigrationMaxSize = 10 * 1 << 20 // 10 MB
 	configParams            = []string{
+		"x-metadata-schema",
 		"x-migration-max-size",
 		"x-migrations-table",
+		"x-mutex-table",
 		"x-statement-timeout",
 	}
 )
 
-// The format is morph: followed by a comma separated list of values.
-// For now, we are taking the whole string in a single constant.
-// Later, if we need more values, we can split "morph:" to a separate constant.
-const nonTransactionalPrefix = "morph:nontransactional"
-
 type driverConfig struct {
 	drivers.Config
 	databaseName   string
@@ -42,6 +39,19 @@ type Postgres struct {
 	config *driverConfig
 }
 
+func init() {
+	open := func(connURL string) (drivers.Driver, error) {
+		driver, err := Open(connURL)
+		if err != nil {
+			return nil, err
+		}
+		return driver, nil
+	}
+
+	drivers.Register(driverName, open)
+	drivers.Register("postgresql", open)
+}
+
 func WithInstance(dbInstance *sql.DB) (*Postgres, error) {
 	conn, err := dbInstance.Conn(context.Background())
 	if err != nil {
@@ -126,6 +136,10 @@ func currentSchema(conn *sql.Conn, config *driverConfig) (string, error) {
 	return schemaName, nil
 }
 
+// mergeConfigWithParams sets the parameters of the URL on the configuration.
+// The names of the metadata tables and schema are stored in lower case, the
+// case PostgreSQL folds unquoted names to, so that the tables created before
+// the names were quoted are still found.
 func mergeConfigWithParams(params map[string]string, config *driverConfig) (*driverConfig, error) {
 	var err error
 
@@ -137,7 +151,20 @@ func mergeConfigWithParams(params map[string]string, config *driverConfig) (*dri
 					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
 				}
 			case "x-migrations-table":
-				config.MigrationsTable = v
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
+				config.MigrationsTable = strings.ToLower(v)
+			case "x-metadata-schema":
+				if err = drivers.ValidateSchemaName(v); err != nil {
+					return nil, err
+				}
+				config.MetadataSchema = strings.ToLower(v)
+			case "x-mutex-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
+				config.MutexTable = strings.ToLower(v)
 			case "x-statement-timeout":
 				if config.StatementTimeoutInSecs, err = strconv.Atoi(v); err != nil {
 					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
@@ -160,7 +187,7 @@ func (pg *Postgres) createSchemaTableIfNotExists() (err error) {
 	ctx, cancel := drivers.GetContext(pg.config.StatementTimeoutInSecs)
 	defer cancel()
 
-	createTableIfNotExistsQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint not null primary key, name varchar not null)", pg.config.MigrationsTable)
+	createTableIfNotExistsQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint not null primary key, name varchar not null, skip_reason text, applied_order bigint)", pg.migrationsTable())
 	if _, err = pg.conn.ExecContext(ctx, createTableIfNotExistsQuery); err != nil {
 		return &drivers.DatabaseError{
 			OrigErr: err,
@@ -171,7 +198,21 @@ func (pg *Postgres) createSchemaTableIfNotExists() (err error) {
 		}
 	}
 
-	return nil
+	return drivers.UpgradePostgresMigrationsTable(ctx, metadataConn{conn: pg.conn}, driverName, pg.config.MetadataSchema, pg.config.MigrationsTable, pg.migrationsTable())
+}
+
+// metadataConn adapts the connection to the upgrade of the migrations table.
+type metadataConn struct {
+	conn *sql.Conn
+}
+
+func (c metadataConn) Exec(ctx context.Context, query string) error {
+	_, err := c.conn.ExecContext(ctx, query)
+	return err
+}
+
+func (c metadataConn) QueryRow(ctx context.Context, query string, args ...interface{}) drivers.Row {
+	return c.conn.QueryRowContext(ctx, query, args...)
 }
 
 func (pg *Postgres) Close() error {
@@ -207,12 +248,16 @@ func (pg *Postgres) Close() error {
 func (pg *Postgres) Apply(migration *models.Migration, saveVersion bool) (err error) {
 	query := migration.Query()
 
-	ctx, cancel := drivers.GetContext(pg.config.StatementTimeoutInSecs)
+	directives, err := migration.Directives()
+	if err != nil {
+		return &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to parse migration directives"}
+	}
+
+	ctx, cancel := drivers.GetContext(drivers.MigrationTimeout(pg.config.StatementTimeoutInSecs, directives))
 	defer cancel()
 
-	nonTransactional := strings.HasPrefix(query, "-- "+nonTransactionalPrefix)
-	// We wrap with a transaction only when there is no non-transactional prefix.
-	if !nonTransactional {
+	// We wrap with a transaction only when there is no non-transactional directive.
+	if !directives.NonTransactional {
 		transaction, err := pg.conn.BeginTx(ctx, nil)
 		if err != nil {
 			return &drivers.DatabaseError{
@@ -223,12 +268,15 @@ func (pg *Postgres) Apply(migration *models.Migration, saveVersion bool) (err er
 			}
 		}
 
-		if err = executeQuery(ctx, transaction, query); err != nil {
-			return err
+		if !migration.RecordOnly {
+			if err = executeQuery(ctx, transaction, query, migration.Args...); err != nil {
+				return err
+			}
 		}
 
 		if saveVersion {
-			if err = executeQuery(ctx, transaction, pg.addMigrationQuery(migration)); err != nil {
+			versionQuery, args := pg.addMigrationQuery(migration)
+			if err = executeQuery(ctx, transaction, versionQuery, args...); err != nil {
 				return err
 			}
 		}
@@ -243,26 +291,29 @@ func (pg *Postgres) Apply(migration *models.Migration, saveVersion bool) (err er
 			}
 		}
 	} else {
-		_, err := pg.conn.ExecContext(ctx, query)
-		if err != nil {
-			return &drivers.DatabaseError{
-				OrigErr: err,
-				Driver:  driverName,
-				Message: "failed to execute migration",
-				Command: "executing_query",
-				Query:   []byte(query),
+		if !migration.RecordOnly {
+			_, err := pg.conn.ExecContext(ctx, query, migration.Args...)
+			if err != nil {
+				return &drivers.DatabaseError{
+					OrigErr: err,
+					Driver:  driverName,
+					Message: "failed to execute migration",
+					Command: "executing_query",
+					Query:   []byte(query),
+				}
 			}
 		}
 
 		if saveVersion {
-			_, err = pg.conn.ExecContext(ctx, pg.addMigrationQuery(migration))
+			versionQuery, args := pg.addMigrationQuery(migration)
+			_, err = pg.conn.ExecContext(ctx, versionQuery, args...)
 			if err != nil {
 				return &drivers.DatabaseError{
 					OrigErr: err,
 					Driver:  driverName,
 					Message: "failed to save version",
 					Command: "executing_query",
-					Query:   []byte(query),
+					Query:   []byte(versionQuery),
 				}
 			}
 		}
@@ -284,12 +335,12 @@ func (pg *Postgres) AppliedMigrations() (migrations []*models.Migration, err err
 		return nil, err
 	}
 
-	query := fmt.Sprintf("SELECT version, name FROM %s", pg.config.MigrationsTable)
+	query := fmt.Sprintf("SELECT version, name, COALESCE(skip_reason, ''), COALESCE(applied_order, 0) FROM %s", pg.migrationsTable())
 	ctx, cancel := drivers.GetContext(pg.config.StatementTimeoutInSecs)
 	defer cancel()
 	var appliedMigrations []*models.Migration
-	var version uint32
-	var name string
+	var version, appliedOrder uint64
+	var name, skipReason string
 
 	rows, err := pg.conn.QueryContext(ctx, query)
 	if err != nil {
@@ -304,7 +355,7 @@ func (pg *Postgres) AppliedMigrations() (migrations []*models.Migration, err err
 	defer rows.Close()
 
 	for rows.Next() {
-		if err := rows.Scan(&version, &name); err != nil {
+		if err := rows.Scan(&version, &name, &skipReason, &appliedOrder); err != nil {
 			return nil, &drivers.DatabaseError{
 				OrigErr: err,
 				Driver:  driverName,
@@ -314,24 +365,36 @@ func (pg *Postgres) AppliedMigrations() (migrations []*models.Migration, err err
 		}
 
 		appliedMigrations = append(appliedMigrations, &models.Migration{
-			Name:      name,
-			Version:   version,
-			Direction: models.Up,
+			Name:         name,
+			Version:      version,
+			Direction:    models.Up,
+			SkipReason:   skipReason,
+			AppliedOrder: appliedOrder,
 		})
 	}
 
 	return appliedMigrations, nil
 }
 
-func (pg *Postgres) addMigrationQuery(migration *models.Migration) string {
+func (pg *Postgres) migrationsTable() string {
+	return pg.metadataTable(pg.config.MigrationsTable)
+}
+
+// addMigrationQuery returns the statement that records the migration in the
+// migrations table, or deletes it for a down migration, with its bind
+// parameters.
+func (pg *Postgres) addMigrationQuery(migration *models.Migration) (string, []interface{}) {
 	if migration.Direction == models.Down {
-		return fmt.Sprintf("DELETE FROM %s WHERE (Version=%d AND NAME='%s')", pg.config.MigrationsTable, migration.Version, migration.Name)
+		return fmt.Sprintf("DELETE FROM %s WHERE version = $1 AND name = $2", pg.migrationsTable()), []interface{}{migration.Version, migration.Name}
 	}
-	return fmt.Sprintf("INSERT INTO %s (version, name) VALUES (%d, '%s')", pg.config.MigrationsTable, migration.Version, migration.Name)
+
+	// the order is the next one after the latest applied migration
+	skipReason := sql.NullString{String: migration.SkipReason, Valid: migration.SkipReason != ""}
+	return fmt.Sprintf("INSERT INTO %[1]s (version, name, skip_reason, applied_order) VALUES ($1, $2, $3, (SELECT COALESCE(MAX(applied_order), 0) + 1 FROM %[1]s))", pg.migrationsTable()), []interface{}{migration.Version, migration.Name, skipReason}
 }
 
-func executeQuery(ctx context.Context, transaction *sql.Tx, query string) error {
-	if _, err := transaction.ExecContext(ctx, query); err != nil {
+func executeQuery(ctx context.Context, transaction *sql.Tx, query string, args ...interface{}) error {
+	if _, err := transaction.ExecContext(ctx, query, args...); err != nil {
 		if txErr := transaction.Rollback(); txErr != nil {
 			err = errors.Wrap(errors.New(err.Error()+txErr.Error()), "failed to execute query in migration transaction")
 
@@ -386,7 +449,30 @@ func (pg *Postgres) SetConfig(key string, value interface{}) error {
 		case "MigrationsTable":
 			n, ok := value.(string)
 			if ok {
-				pg.config.MigrationsTable = n
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				pg.config.MigrationsTable = strings.ToLower(n)
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MetadataSchema":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateSchemaName(n); err != nil {
+					return err
+				}
+				pg.config.MetadataSchema = strings.ToLower(n)
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MutexTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				pg.config.MutexTable = strings.ToLower(n)
 				return nil
 			}
 			return fmt.Errorf("incorrect value type for %s", key)
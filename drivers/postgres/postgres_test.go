@@ -13,9 +13,11 @@ import (
 	"time"
 
 	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/drivers/drivertest"
 	"github.com/mattermost/morph/models"
 	"github.com/pkg/errors"
 
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -228,7 +230,7 @@ func (suite *PostgresTestSuite) TestNonTransactionalMigrations() {
 	defer teardown()
 
 	err := connectedDriver.Apply(&models.Migration{
-		Version: uint32(1),
+		Version: uint64(1),
 		Name:    "create table",
 		RawName: "test.sql",
 		Bytes: []byte(`CREATE TABLE IF NOT EXISTS testtable (
@@ -241,7 +243,7 @@ func (suite *PostgresTestSuite) TestNonTransactionalMigrations() {
 	}, false)
 	suite.Require().NoError(err, "should not error while creating a table")
 	err = connectedDriver.Apply(&models.Migration{
-		Version: uint32(2),
+		Version: uint64(2),
 		Name:    "create index",
 		RawName: "test.sql",
 		Bytes: []byte(`-- morph:nontransactional
@@ -487,3 +489,150 @@ func (suite *PostgresTestSuite) TestLock() {
 		}
 	})
 }
+
+func (suite *PostgresTestSuite) TestConformance() {
+	drivertest.RunConformance(suite.T(), func(t *testing.T) drivers.Driver {
+		connectedDriver, err := Open(testConnURL)
+		require.NoError(t, err, "should not error when connecting to database from url")
+		t.Cleanup(func() {
+			require.NoError(t, connectedDriver.Close(), "should not error when closing the database connection")
+		})
+
+		return connectedDriver
+	}, drivertest.WithSlowQuery("SELECT pg_sleep(2);"), drivertest.WithConnURL(driverName, testConnURL), drivertest.WithLockable(func(driver drivers.Driver) drivers.Lockable {
+		return drivertest.LockableFunc(func(key string, logger drivers.Logger) (drivers.Locker, error) {
+			mutex, err := driver.(*Postgres).NewMutex(key, logger)
+			if err != nil {
+				return nil, err
+			}
+			return mutex, nil
+		})
+	}))
+}
+
+func (suite *PostgresTestSuite) TestMetadataSchema() {
+	_, err := suite.db.Exec("CREATE SCHEMA IF NOT EXISTS morph_metadata")
+	suite.Require().NoError(err, "should not error when creating the metadata schema")
+	defer func() {
+		_, err := suite.db.Exec("DROP SCHEMA morph_metadata CASCADE")
+		suite.Require().NoError(err, "should not error when dropping the metadata schema")
+	}()
+
+	connectedDriver, teardown := suite.InitializeDriver(testConnURL + "&x-metadata-schema=morph_metadata")
+	defer teardown()
+
+	_, err = connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when creating the migrations table")
+	suite.Require().NoError(connectedDriver.Apply(&models.Migration{Version: 1, Name: "noop", Direction: models.Up, Bytes: []byte("SELECT 1;")}, true))
+
+	mx, err := connectedDriver.NewMutex("test-lock-key", log.New(os.Stderr, "", 0))
+	suite.Require().NoError(err, "should not error when creating the mutex table")
+	suite.Require().NoError(mx.Lock(context.Background()))
+	suite.Require().NoError(mx.Unlock())
+
+	for _, table := range []string{"db_migrations", drivers.MutexTableName} {
+		var count int
+		err = suite.db.QueryRow("SELECT COUNT(*) FROM pg_tables WHERE schemaname = $1 AND tablename = $2", "morph_metadata", table).Scan(&count)
+		suite.Require().NoError(err)
+		suite.Assert().Equal(1, count, "should create %s in the metadata schema", table)
+
+		err = suite.db.QueryRow("SELECT COUNT(*) FROM pg_tables WHERE schemaname = current_schema() AND tablename = $1", table).Scan(&count)
+		suite.Require().NoError(err)
+		suite.Assert().Zero(count, "should not create %s in the current schema", table)
+	}
+
+	var version uint64
+	err = suite.db.QueryRow("SELECT version FROM morph_metadata.db_migrations").Scan(&version)
+	suite.Require().NoError(err)
+	suite.Assert().EqualValues(1, version)
+}
+
+func (suite *PostgresTestSuite) TestMixedCaseMigrationsTable() {
+	// a migrations table created when the names were not quoted, and folded
+	_, err := suite.db.Exec("CREATE TABLE MyMigrations (version bigint not null primary key, name varchar not null)")
+	suite.Require().NoError(err, "should not error when creating the migrations table")
+	_, err = suite.db.Exec("INSERT INTO MyMigrations (version, name) VALUES (1, 'noop')")
+	suite.Require().NoError(err, "should not error when inserting an applied migration")
+
+	connectedDriver, teardown := suite.InitializeDriver(testConnURL + "&x-migrations-table=MyMigrations")
+	defer teardown()
+
+	applied, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when fetching applied migrations")
+	suite.Require().Len(applied, 1, "should find the table PostgreSQL folded the name of")
+	suite.Assert().Equal("noop", applied[0].Name)
+}
+
+func TestMixedCaseTableNames(t *testing.T) {
+	config, err := mergeConfigWithParams(map[string]string{"x-migrations-table": "MyMigrations", "x-metadata-schema": "Morph"}, getDefaultConfig())
+	require.NoError(t, err)
+
+	pg := &Postgres{config: config}
+	require.Equal(t, `"morph"."mymigrations"`, pg.migrationsTable(), "should fold the names to lower case")
+
+	require.NoError(t, pg.SetConfig("MutexTable", "MyLock"))
+	require.Equal(t, `"morph"."mylock"`, pg.metadataTable(pg.config.MutexTable), "should fold the names to lower case")
+}
+
+func (suite *PostgresTestSuite) TestWidenVersionColumn() {
+	_, err := suite.db.Exec("CREATE TABLE db_migrations (version integer not null primary key, name varchar not null)")
+	suite.Require().NoError(err, "should not error when creating a migrations table with a narrow version column")
+
+	connectedDriver, teardown := suite.InitializeDriver(testConnURL)
+	defer teardown()
+
+	_, err = connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when upgrading the migrations table")
+
+	var dataType string
+	err = suite.db.QueryRow("SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'db_migrations' AND column_name = 'version'").Scan(&dataType)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("bigint", dataType, "should widen the version column")
+
+	suite.Require().NoError(connectedDriver.Apply(&models.Migration{Version: 1700000000000000000, Name: "noop", Direction: models.Up, Bytes: []byte("SELECT 1;")}, true))
+	applied, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err)
+	suite.Require().Len(applied, 1)
+	suite.Assert().Equal(uint64(1700000000000000000), applied[0].Version)
+}
+
+func (suite *PostgresTestSuite) TestScratch() {
+	_, err := suite.db.Exec("CREATE TABLE public.users (id integer)")
+	suite.Require().NoError(err, "should not error when creating a live table")
+
+	withInstance, err := WithInstance(suite.db)
+	suite.Require().NoError(err)
+	defer withInstance.Close()
+
+	// the connection of the scratch is the only one the pool can hand out
+	// besides the connection of the driver
+	suite.db.SetMaxOpenConns(2)
+	defer suite.db.SetMaxOpenConns(0)
+
+	scratch, err := withInstance.Scratch()
+	suite.Require().NoError(err, "should not error when creating a scratch schema")
+
+	scratchName := scratch.(*scratchPostgres).config.schemaName
+	suite.Assert().NotEqual("public", scratchName, "should use another schema")
+
+	// unqualified statements run against the scratch schema
+	suite.Require().NoError(scratch.Apply(&models.Migration{Version: 1, Name: "create_users", Direction: models.Up, Bytes: []byte("CREATE TABLE users (id integer, name text);")}, true))
+	suite.Require().NoError(scratch.Close())
+
+	var count int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = 'public' AND table_name = 'users'").Scan(&count)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(1, count, "should not change the live table")
+
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name = $1", scratchName).Scan(&count)
+	suite.Require().NoError(err)
+	suite.Assert().Zero(count, "should drop the scratch schema")
+
+	mutex, err := withInstance.NewMutex("test-lock-key", log.New(os.Stderr, "", 0))
+	suite.Require().NoError(err, "should use the schema of the driver after the scratch is closed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	suite.Require().NoError(mutex.Lock(ctx))
+	suite.Require().NoError(mutex.Unlock())
+}
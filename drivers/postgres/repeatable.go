@@ -0,0 +1,19 @@
+package postgres
+
+import (
+	"github.com/mattermost/morph/drivers"
+)
+
+func (pg *Postgres) repeatableTable() string {
+	return pg.metadataTable(pg.config.MigrationsTable + drivers.RepeatableTableSuffix)
+}
+
+// AppliedRepeatables returns the checksums of the applied repeatable migrations.
+func (pg *Postgres) AppliedRepeatables() (map[string]string, error) {
+	return pg.appliedChecksums(pg.repeatableTable(), "repeatable migration", "repeatable")
+}
+
+// SaveRepeatable records the checksum of an applied repeatable migration.
+func (pg *Postgres) SaveRepeatable(name, checksum string) error {
+	return pg.saveChecksum(pg.repeatableTable(), "repeatable migration", "repeatable", name, checksum)
+}
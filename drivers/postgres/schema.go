@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+var constraintTypes = map[string]string{
+	"p": "PRIMARY KEY",
+	"u": "UNIQUE",
+	"f": "FOREIGN KEY",
+	"c": "CHECK",
+	"x": "EXCLUDE",
+}
+
+// DumpSchema describes the tables of the current schema by reading the system
+// catalogs. Indexes backing a constraint are reported as part of the constraint.
+func (pg *Postgres) DumpSchema() (*models.Schema, error) {
+	if pg.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	ctx, cancel := drivers.GetContext(pg.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	tablesQuery := "SELECT table_name FROM information_schema.tables WHERE table_schema = $1 AND table_type = 'BASE TABLE' ORDER BY table_name"
+	names, err := queryStrings(ctx, pg.conn, tablesQuery, pg.config.schemaName)
+	if err != nil {
+		return nil, dumpError(err, tablesQuery)
+	}
+
+	schema := &models.Schema{}
+	for _, name := range names {
+		if drivers.IsInternalTable(name, &pg.config.Config) {
+			continue
+		}
+
+		table, err := pg.dumpTable(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+	schema.Normalize()
+
+	return schema, nil
+}
+
+func (pg *Postgres) dumpTable(ctx context.Context, name string) (*models.Table, error) {
+	table := &models.Table{Name: name}
+	relation := "(quote_ident($1) || '.' || quote_ident($2))::regclass"
+
+	columnsQuery := `SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull, COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = ` + relation + ` AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum`
+	rows, err := pg.conn.QueryContext(ctx, columnsQuery, pg.config.schemaName, name)
+	if err != nil {
+		return nil, dumpError(err, columnsQuery)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column models.Column
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &column.Default); err != nil {
+			return nil, dumpError(err, columnsQuery)
+		}
+		table.Columns = append(table.Columns, &column)
+	}
+	rows.Close()
+
+	constraintsQuery := "SELECT conname, contype, pg_get_constraintdef(oid) FROM pg_constraint WHERE conrelid = " + relation
+	rows, err = pg.conn.QueryContext(ctx, constraintsQuery, pg.config.schemaName, name)
+	if err != nil {
+		return nil, dumpError(err, constraintsQuery)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var constraint models.Constraint
+		var contype string
+		if err := rows.Scan(&constraint.Name, &contype, &constraint.Definition); err != nil {
+			return nil, dumpError(err, constraintsQuery)
+		}
+		constraint.Type = constraintTypes[contype]
+		table.Constraints = append(table.Constraints, &constraint)
+	}
+	rows.Close()
+
+	indexesQuery := `SELECT i.relname, ix.indisunique,
+		COALESCE((SELECT string_agg(COALESCE(a.attname, pg_get_indexdef(ix.indexrelid, k.ord::int, true)), ',' ORDER BY k.ord)
+			FROM unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+			LEFT JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum), '')
+		FROM pg_index ix JOIN pg_class i ON i.oid = ix.indexrelid
+		WHERE ix.indrelid = ` + relation + ` AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid AND c.conrelid = ix.indrelid)`
+	rows, err = pg.conn.QueryContext(ctx, indexesQuery, pg.config.schemaName, name)
+	if err != nil {
+		return nil, dumpError(err, indexesQuery)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index models.Index
+		var columns string
+		if err := rows.Scan(&index.Name, &index.Unique, &columns); err != nil {
+			return nil, dumpError(err, indexesQuery)
+		}
+		if columns != "" {
+			index.Columns = strings.Split(columns, ",")
+		}
+		table.Indexes = append(table.Indexes, &index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dumpError(err, indexesQuery)
+	}
+
+	return table, nil
+}
+
+func queryStrings(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
+
+func dumpError(err error, query string) error {
+	return &drivers.DatabaseError{
+		OrigErr: err,
+		Driver:  driverName,
+		Message: "failed to dump schema",
+		Command: "dump_schema",
+		Query:   []byte(query),
+	}
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// scratchPostgres is a driver bound to a temporary schema, which is dropped
+// when the driver is closed.
+type scratchPostgres struct {
+	*Postgres
+}
+
+// Scratch creates a temporary schema in the current database and returns a
+// driver that uses it through a connection of its own, whose search_path is
+// the scratch schema. The migrations replayed in the scratch must therefore
+// not qualify their objects with a schema, e.g. public.users, which would
+// reach the live data.
+func (pg *Postgres) Scratch() (drivers.Driver, error) {
+	if pg.db == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no database instance"),
+			Message: "database instance is missing",
+			Driver:  driverName,
+		}
+	}
+
+	conn, err := pg.db.Conn(context.Background())
+	if err != nil {
+		return nil, &drivers.DatabaseError{Driver: driverName, Command: "grabbing_connection", OrigErr: err, Message: "failed to grab connection to the database"}
+	}
+
+	ctx, cancel := drivers.GetContext(pg.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	schemaName := fmt.Sprintf("morph_scratch_%d", time.Now().UnixNano())
+	for _, query := range []string{
+		fmt.Sprintf("CREATE SCHEMA %s", schemaName),
+		fmt.Sprintf("SET search_path TO %s", schemaName),
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			_ = conn.Close()
+			return nil, &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driverName,
+				Message: "failed to create scratch schema",
+				Command: "create_scratch_schema",
+				Query:   []byte(query),
+			}
+		}
+	}
+
+	config := *pg.config
+	config.schemaName = schemaName
+	config.closeDBonClose = false
+	// the bookkeeping tables of the scratch schema are dropped with it
+	config.MetadataSchema = ""
+
+	return &scratchPostgres{
+		Postgres: &Postgres{
+			conn:   conn,
+			db:     pg.db,
+			config: &config,
+		},
+	}, nil
+}
+
+// Close drops the scratch schema. The connection is discarded rather than
+// returned to the pool of the driver that created the scratch, which would
+// otherwise be handed out with the dropped schema in its search_path.
+func (pg *scratchPostgres) Close() error {
+	if pg.conn == nil {
+		return pg.Postgres.Close()
+	}
+
+	ctx, cancel := drivers.GetContext(pg.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	query := fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", pg.config.schemaName)
+	_, err := pg.conn.ExecContext(ctx, query)
+
+	_ = pg.conn.Raw(func(interface{}) error {
+		return sqldriver.ErrBadConn
+	})
+	pg.conn = nil
+
+	if err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed to drop scratch schema",
+			Command: "drop_scratch_schema",
+			Query:   []byte(query),
+		}
+	}
+
+	return pg.Postgres.Close()
+}
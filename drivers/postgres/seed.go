@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"github.com/mattermost/morph/drivers"
+)
+
+func (pg *Postgres) seedTable() string {
+	return pg.metadataTable(pg.config.MigrationsTable + drivers.SeedTableSuffix)
+}
+
+// AppliedSeeds returns the checksums of the applied seeds.
+func (pg *Postgres) AppliedSeeds() (map[string]string, error) {
+	return pg.appliedChecksums(pg.seedTable(), "seed", "seed")
+}
+
+// SaveSeed records the checksum of an applied seed.
+func (pg *Postgres) SaveSeed(name, checksum string) error {
+	return pg.saveChecksum(pg.seedTable(), "seed", "seed", name, checksum)
+}
+
+// UpsertQuery returns an INSERT ... ON CONFLICT statement that updates the rows
+// whose key columns already exist, with the values of the rows as its bind
+// parameters.
+func (pg *Postgres) UpsertQuery(table string, keys, columns []string, rows [][]interface{}) (string, []interface{}, error) {
+	return drivers.OnConflictUpsertQuery(table, keys, columns, rows, drivers.DollarPlaceholder)
+}
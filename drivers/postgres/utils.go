@@ -19,8 +19,16 @@ func getDefaultConfig() *driverConfig {
 	return &driverConfig{
 		Config: drivers.Config{
 			MigrationsTable:        "db_migrations",
+			MutexTable:             drivers.MutexTableName,
 			StatementTimeoutInSecs: 300,
 			MigrationMaxSize:       defaultMigrationMaxSize,
 		},
 	}
 }
+
+// metadataTable returns the quoted name of a table morph keeps its state in,
+// qualified with the metadata schema if there is one. Names are quoted so that
+// they cannot be mistaken for keywords, they are already in lower case.
+func (pg *Postgres) metadataTable(name string) string {
+	return drivers.QuoteTableName(pg.config.MetadataSchema, name, `"`)
+}
@@ -0,0 +1,74 @@
+package drivers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OpenFunc opens a driver from a connection URL or DSN.
+type OpenFunc func(connURL string) (Driver, error)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]OpenFunc)
+)
+
+// Register makes a driver available by name to Open and OpenByName. The name is
+// also the scheme of the connection URLs the driver opens, e.g. postgres for
+// postgres://localhost:5432/morph. A driver accepting several schemes registers
+// itself once for each of them. Drivers are meant to be registered from the
+// init function of their package, Register panics if the name is already taken.
+func Register(name string, open OpenFunc) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if open == nil {
+		panic("drivers: Register open function is nil")
+	}
+	if _, ok := registry[name]; ok {
+		panic("drivers: Register called twice for driver " + name)
+	}
+
+	registry[name] = open
+}
+
+// Registered returns the names of the registered drivers in alphabetical order.
+func Registered() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Open opens the connection URL with the driver registered for its scheme. The
+// URL is passed to the driver as it is, drivers whose DSN does not have a scheme
+// strip it themselves.
+func Open(connURL string) (Driver, error) {
+	scheme, _, ok := strings.Cut(connURL, "://")
+	if !ok || scheme == "" {
+		return nil, fmt.Errorf("could not find the scheme of the connection url, expected one of %s", strings.Join(Registered(), ", "))
+	}
+
+	return OpenByName(scheme, connURL)
+}
+
+// OpenByName opens the connection URL or DSN with the named driver.
+func OpenByName(name, connURL string) (Driver, error) {
+	registryLock.RLock()
+	open, ok := registry[name]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver %s", name)
+	}
+
+	return open(connURL)
+}
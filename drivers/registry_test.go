@@ -0,0 +1,43 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	var opened string
+	Register("registry-test", func(connURL string) (Driver, error) {
+		opened = connURL
+		return nil, errors.New("not implemented")
+	})
+
+	t.Run("should open by the scheme of the url", func(t *testing.T) {
+		_, err := Open("registry-test://localhost/morph")
+		require.EqualError(t, err, "not implemented")
+		require.Equal(t, "registry-test://localhost/morph", opened)
+	})
+
+	t.Run("should open by name", func(t *testing.T) {
+		_, err := OpenByName("registry-test", "localhost/morph")
+		require.EqualError(t, err, "not implemented")
+		require.Equal(t, "localhost/morph", opened)
+	})
+
+	t.Run("should fail on unknown drivers", func(t *testing.T) {
+		_, err := Open("unknown://localhost/morph")
+		require.EqualError(t, err, "unsupported driver unknown")
+
+		_, err = Open("localhost/morph")
+		require.EqualError(t, err, "could not find the scheme of the connection url, expected one of registry-test")
+	})
+
+	t.Run("should not register a driver twice", func(t *testing.T) {
+		require.Panics(t, func() {
+			Register("registry-test", func(string) (Driver, error) { return nil, nil })
+		})
+		require.Contains(t, Registered(), "registry-test")
+	})
+}
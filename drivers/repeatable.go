@@ -0,0 +1,15 @@
+package drivers
+
+// RepeatableTableSuffix is appended to the migrations table name to name the
+// table that stores the checksums of the applied repeatable migrations.
+const RepeatableTableSuffix = "_repeatable"
+
+// RepeatableTracker is implemented by drivers that can record the checksum of
+// the last applied version of each repeatable migration.
+type RepeatableTracker interface {
+	// AppliedRepeatables returns the checksum of every repeatable migration
+	// that has been applied, keyed by the migration name.
+	AppliedRepeatables() (map[string]string, error)
+	// SaveRepeatable records the checksum of an applied repeatable migration.
+	SaveRepeatable(name, checksum string) error
+}
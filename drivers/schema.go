@@ -0,0 +1,40 @@
+package drivers
+
+import (
+	"github.com/mattermost/morph/models"
+)
+
+// SchemaDumper is implemented by drivers that can describe the schema of the
+// database they are connected to. The tables used by morph itself are left out
+// of the description.
+type SchemaDumper interface {
+	DumpSchema() (*models.Schema, error)
+}
+
+// InternalTables returns the names of the tables morph creates for its own
+// bookkeeping, given the configuration of the driver.
+func InternalTables(config *Config) []string {
+	mutexTable := config.MutexTable
+	if mutexTable == "" {
+		mutexTable = MutexTableName
+	}
+
+	return []string{
+		config.MigrationsTable,
+		config.MigrationsTable + JournalTableSuffix,
+		config.MigrationsTable + RepeatableTableSuffix,
+		config.MigrationsTable + SeedTableSuffix,
+		mutexTable,
+	}
+}
+
+// IsInternalTable reports whether the table is used by morph for its own bookkeeping.
+func IsInternalTable(name string, config *Config) bool {
+	for _, table := range InternalTables(config) {
+		if table == name {
+			return true
+		}
+	}
+
+	return false
+}
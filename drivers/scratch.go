@@ -0,0 +1,8 @@
+package drivers
+
+// Scratcher is implemented by drivers that can provide an empty, disposable
+// database of the same kind as the one they are connected to. The scratch
+// database is discarded when the returned driver is closed.
+type Scratcher interface {
+	Scratch() (Driver, error)
+}
@@ -0,0 +1,104 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SeedTableSuffix is appended to the migrations table name to name the table
+// that stores the checksums of the applied seeds.
+const SeedTableSuffix = "_seeds"
+
+// Seeder is implemented by drivers that can apply seeds, the reference data
+// that is kept apart from the schema migrations.
+type Seeder interface {
+	// AppliedSeeds returns the checksum of every seed that has been applied,
+	// keyed by the seed name.
+	AppliedSeeds() (map[string]string, error)
+	// SaveSeed records the checksum of an applied seed.
+	SaveSeed(name, checksum string) error
+	// UpsertQuery returns a statement that inserts the rows into the table,
+	// and updates the existing rows that have the same key columns instead,
+	// along with its bind parameters. The rows hold the values of the columns,
+	// in the same order, as decoded from JSON.
+	UpsertQuery(table string, keys, columns []string, rows [][]interface{}) (string, []interface{}, error)
+}
+
+// ValuesList renders the rows as the values list of an insert statement with
+// bind parameters, e.g. ($1, $2), ($3, $4), and returns the values of the rows
+// as the parameters.
+func ValuesList(rows [][]interface{}, placeholder PlaceholderStyle) (string, []interface{}, error) {
+	values := make([]string, 0, len(rows))
+	var args []interface{}
+	for _, row := range rows {
+		placeholders := make([]string, 0, len(row))
+		for _, value := range row {
+			arg, err := bindValue(value)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, arg)
+			placeholders = append(placeholders, placeholder.Placeholder(len(args)))
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+	}
+
+	return strings.Join(values, ", "), args, nil
+}
+
+// bindValue converts a value decoded from JSON to a bind parameter. Whole
+// numbers are passed as integers, the other numbers as floats.
+func bindValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, float64, string:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	default:
+		return nil, fmt.Errorf("unsupported seed value %v of type %T", value, value)
+	}
+}
+
+// UpdateColumns returns the columns that are not part of the key, which are the
+// columns updated when a seeded row already exists.
+func UpdateColumns(keys, columns []string) []string {
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[key] = true
+	}
+
+	var update []string
+	for _, column := range columns {
+		if !isKey[column] {
+			update = append(update, column)
+		}
+	}
+
+	return update
+}
+
+// OnConflictUpsertQuery returns an INSERT ... ON CONFLICT statement that updates
+// the rows whose key columns already exist, with the values of the rows as its
+// bind parameters. PostgreSQL and SQLite support the statement.
+func OnConflictUpsertQuery(table string, keys, columns []string, rows [][]interface{}, placeholder PlaceholderStyle) (string, []interface{}, error) {
+	values, args, err := ValuesList(rows, placeholder)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conflict := "DO NOTHING"
+	if update := UpdateColumns(keys, columns); len(update) > 0 {
+		set := make([]string, 0, len(update))
+		for _, column := range update {
+			set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		}
+		conflict = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON CONFLICT (%s) %s", table, strings.Join(columns, ", "), values, strings.Join(keys, ", "), conflict)
+	return query, args, nil
+}
@@ -0,0 +1,24 @@
+package drivers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesList(t *testing.T) {
+	t.Run("should bind the values of the rows", func(t *testing.T) {
+		rows := [][]interface{}{{json.Number("1"), `it's \`, true}, {json.Number("2.5"), nil, false}}
+
+		values, args, err := ValuesList(rows, DollarPlaceholder)
+		require.NoError(t, err)
+		require.Equal(t, "($1, $2, $3), ($4, $5, $6)", values)
+		require.Equal(t, []interface{}{int64(1), `it's \`, true, 2.5, nil, false}, args)
+	})
+
+	t.Run("should fail for values that are not scalars", func(t *testing.T) {
+		_, _, err := ValuesList([][]interface{}{{[]interface{}{1}}}, QuestionPlaceholder)
+		require.EqualError(t, err, "unsupported seed value [1] of type []interface {}")
+	})
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// checksums returns the table that records the checksum of the last applied
+// version of named scripts, such as repeatable migrations.
+func (driver *sqlite) checksums(table, noun, command string) (*drivers.TableChecksums, error) {
+	if driver.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	return drivers.NewTableChecksums(driver.conn, drivers.TableChecksumsConfig{
+		Driver:                 driverName,
+		Table:                  table,
+		CreateTableQuery:       fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name varchar not null primary key, checksum varchar(64) not null, applied_at bigint not null)", table),
+		UpsertClause:           "ON CONFLICT (name) DO UPDATE SET checksum = excluded.checksum, applied_at = excluded.applied_at",
+		Placeholder:            drivers.QuestionPlaceholder,
+		StatementTimeoutInSecs: driver.config.StatementTimeoutInSecs,
+		Noun:                   noun,
+		Command:                command,
+	}), nil
+}
+
+// appliedChecksums returns the checksums recorded in the table, keyed by name.
+func (driver *sqlite) appliedChecksums(table, noun, command string) (map[string]string, error) {
+	checksums, err := driver.checksums(table, noun, command)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := driver.lock(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = driver.unlock()
+	}()
+
+	return checksums.Applied()
+}
+
+// saveChecksum records the checksum of an applied script in the table.
+func (driver *sqlite) saveChecksum(table, noun, command, name, checksum string) error {
+	checksums, err := driver.checksums(table, noun, command)
+	if err != nil {
+		return err
+	}
+
+	if err := driver.lock(); err != nil {
+		return err
+	}
+	defer func() {
+		_ = driver.unlock()
+	}()
+
+	return checksums.Save(name, checksum)
+}
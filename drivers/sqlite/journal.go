@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+func (driver *sqlite) journalTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable + drivers.JournalTableSuffix)
+}
+
+// journal returns the journal of the plan runs, which is stored in the journal
+// table.
+func (driver *sqlite) journal() (*drivers.TableJournal, error) {
+	if driver.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	table := driver.journalTable()
+	return drivers.NewTableJournal(driver.conn, drivers.TableJournalConfig{
+		Driver:                 driverName,
+		Table:                  table,
+		CreateTableQuery:       fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (run_id varchar(64) not null, step integer not null, plan_id varchar(64) not null, name varchar not null, version bigint not null, direction varchar(8) not null, is_rollback boolean not null, started_at bigint not null, finished_at bigint not null, outcome varchar(16) not null, error_message text not null, primary key (run_id, step))", table),
+		UpsertClause:           "ON CONFLICT (run_id, step) DO UPDATE SET finished_at = EXCLUDED.finished_at, outcome = EXCLUDED.outcome, error_message = EXCLUDED.error_message",
+		Placeholder:            drivers.QuestionPlaceholder,
+		StatementTimeoutInSecs: driver.config.StatementTimeoutInSecs,
+	}), nil
+}
+
+// SavePlanStep inserts or updates the journal entry of a plan step.
+func (driver *sqlite) SavePlanStep(step *models.PlanStep) error {
+	journal, err := driver.journal()
+	if err != nil {
+		return err
+	}
+
+	if err := driver.lock(); err != nil {
+		return err
+	}
+	defer func() {
+		_ = driver.unlock()
+	}()
+
+	return journal.SavePlanStep(step)
+}
+
+// PlanRuns returns the last limit plan runs from the journal.
+func (driver *sqlite) PlanRuns(limit int) ([]*models.PlanRun, error) {
+	journal, err := driver.journal()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := driver.lock(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = driver.unlock()
+	}()
+
+	return journal.PlanRuns(limit)
+}
@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"github.com/mattermost/morph/drivers"
+)
+
+func (driver *sqlite) repeatableTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable + drivers.RepeatableTableSuffix)
+}
+
+// AppliedRepeatables returns the checksums of the applied repeatable migrations.
+func (driver *sqlite) AppliedRepeatables() (map[string]string, error) {
+	return driver.appliedChecksums(driver.repeatableTable(), "repeatable migration", "repeatable")
+}
+
+// SaveRepeatable records the checksum of an applied repeatable migration.
+func (driver *sqlite) SaveRepeatable(name, checksum string) error {
+	return driver.saveChecksum(driver.repeatableTable(), "repeatable migration", "repeatable", name, checksum)
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// DumpSchema describes the tables of the database by reading sqlite_master and
+// the table, index and foreign key pragmas. Check constraints are only available
+// in the original CREATE TABLE statement, therefore they are not included.
+func (driver *sqlite) DumpSchema() (*models.Schema, error) {
+	if driver.conn == nil {
+		return nil, &drivers.AppError{
+			OrigErr: errors.New("driver has no connection established"),
+			Message: "database connection is missing",
+			Driver:  driverName,
+		}
+	}
+
+	if err := driver.lock(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = driver.unlock()
+	}()
+
+	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	defer cancel()
+
+	tablesQuery := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	names, err := queryStrings(ctx, driver.conn, tablesQuery)
+	if err != nil {
+		return nil, dumpError(err, tablesQuery)
+	}
+
+	schema := &models.Schema{}
+	for _, name := range names {
+		if drivers.IsInternalTable(name, &driver.config.Config) {
+			continue
+		}
+
+		table, err := driver.dumpTable(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+	schema.Normalize()
+
+	return schema, nil
+}
+
+func (driver *sqlite) dumpTable(ctx context.Context, name string) (*models.Table, error) {
+	table := &models.Table{Name: name}
+
+	columnsQuery := "SELECT name, type, \"notnull\", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?) ORDER BY cid"
+	rows, err := driver.conn.QueryContext(ctx, columnsQuery, name)
+	if err != nil {
+		return nil, dumpError(err, columnsQuery)
+	}
+	defer rows.Close()
+
+	var primaryKey []string
+	for rows.Next() {
+		var column models.Column
+		var notNull, pk int
+		if err := rows.Scan(&column.Name, &column.Type, &notNull, &column.Default, &pk); err != nil {
+			return nil, dumpError(err, columnsQuery)
+		}
+		column.Nullable = notNull == 0
+		table.Columns = append(table.Columns, &column)
+
+		if pk > 0 {
+			if len(primaryKey) < pk {
+				primaryKey = append(primaryKey, make([]string, pk-len(primaryKey))...)
+			}
+			primaryKey[pk-1] = column.Name
+		}
+	}
+	rows.Close()
+
+	if len(primaryKey) > 0 {
+		table.Constraints = append(table.Constraints, &models.Constraint{
+			Name:       name + "_pkey",
+			Type:       "PRIMARY KEY",
+			Definition: fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")),
+		})
+	}
+
+	foreignKeysQuery := "SELECT id, \"table\", \"from\", COALESCE(\"to\", ''), on_update, on_delete FROM pragma_foreign_key_list(?) ORDER BY id, seq"
+	rows, err = driver.conn.QueryContext(ctx, foreignKeysQuery, name)
+	if err != nil {
+		return nil, dumpError(err, foreignKeysQuery)
+	}
+	defer rows.Close()
+
+	type foreignKey struct {
+		table, onUpdate, onDelete string
+		from, to                  []string
+	}
+	var ids []int
+	foreignKeys := make(map[int]*foreignKey)
+	for rows.Next() {
+		var id int
+		var refTable, from, to, onUpdate, onDelete string
+		if err := rows.Scan(&id, &refTable, &from, &to, &onUpdate, &onDelete); err != nil {
+			return nil, dumpError(err, foreignKeysQuery)
+		}
+		fk, ok := foreignKeys[id]
+		if !ok {
+			fk = &foreignKey{table: refTable, onUpdate: onUpdate, onDelete: onDelete}
+			foreignKeys[id] = fk
+			ids = append(ids, id)
+		}
+		fk.from = append(fk.from, from)
+		fk.to = append(fk.to, to)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		fk := foreignKeys[id]
+		definition := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", strings.Join(fk.from, ", "), fk.table, strings.Join(fk.to, ", "))
+		if fk.onUpdate != "NO ACTION" {
+			definition += " ON UPDATE " + fk.onUpdate
+		}
+		if fk.onDelete != "NO ACTION" {
+			definition += " ON DELETE " + fk.onDelete
+		}
+		table.Constraints = append(table.Constraints, &models.Constraint{
+			Name:       fmt.Sprintf("%s_%s_fkey", name, strings.Join(fk.from, "_")),
+			Type:       "FOREIGN KEY",
+			Definition: definition,
+		})
+	}
+
+	indexesQuery := "SELECT name, \"unique\" FROM pragma_index_list(?) WHERE origin <> 'pk' ORDER BY name"
+	rows, err = driver.conn.QueryContext(ctx, indexesQuery, name)
+	if err != nil {
+		return nil, dumpError(err, indexesQuery)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index models.Index
+		var unique int
+		if err := rows.Scan(&index.Name, &unique); err != nil {
+			return nil, dumpError(err, indexesQuery)
+		}
+		index.Unique = unique == 1
+		table.Indexes = append(table.Indexes, &index)
+	}
+	rows.Close()
+
+	indexColumnsQuery := "SELECT COALESCE(name, '') FROM pragma_index_info(?) ORDER BY seqno"
+	for _, index := range table.Indexes {
+		if index.Columns, err = queryStrings(ctx, driver.conn, indexColumnsQuery, index.Name); err != nil {
+			return nil, dumpError(err, indexColumnsQuery)
+		}
+	}
+
+	return table, nil
+}
+
+func queryStrings(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, rows.Err()
+}
+
+func dumpError(err error, query string) error {
+	return &drivers.DatabaseError{
+		OrigErr: err,
+		Driver:  driverName,
+		Message: "failed to dump schema",
+		Command: "dump_schema",
+		Query:   []byte(query),
+	}
+}
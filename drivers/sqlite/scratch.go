@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mattermost/morph/drivers"
+)
+
+// Scratch returns a driver connected to a new in-memory database.
+func (driver *sqlite) Scratch() (drivers.Driver, error) {
+	db, err := sql.Open(driverName, ":memory:")
+	if err != nil {
+		return nil, &drivers.DatabaseError{Driver: driverName, Command: "opening_connection", OrigErr: err, Message: "failed to open scratch database"}
+	}
+
+	// the in-memory database lives as long as the connection
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		_ = db.Close()
+		return nil, &drivers.DatabaseError{Driver: driverName, Command: "grabbing_connection", OrigErr: err, Message: "failed to grab connection to the scratch database"}
+	}
+
+	config := *driver.config
+	config.closeDBonClose = true
+	// the scratch database has no attached databases
+	config.MetadataSchema = ""
+
+	return &sqlite{
+		conn:   conn,
+		db:     db,
+		config: &config,
+	}, nil
+}
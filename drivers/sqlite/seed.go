@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"github.com/mattermost/morph/drivers"
+)
+
+func (driver *sqlite) seedTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable + drivers.SeedTableSuffix)
+}
+
+// AppliedSeeds returns the checksums of the applied seeds.
+func (driver *sqlite) AppliedSeeds() (map[string]string, error) {
+	return driver.appliedChecksums(driver.seedTable(), "seed", "seed")
+}
+
+// SaveSeed records the checksum of an applied seed.
+func (driver *sqlite) SaveSeed(name, checksum string) error {
+	return driver.saveChecksum(driver.seedTable(), "seed", "seed", name, checksum)
+}
+
+// UpsertQuery returns an INSERT ... ON CONFLICT statement that updates the rows
+// whose key columns already exist, with the values of the rows as its bind
+// parameters.
+func (driver *sqlite) UpsertQuery(table string, keys, columns []string, rows [][]interface{}) (string, []interface{}, error) {
+	return drivers.OnConflictUpsertQuery(table, keys, columns, rows, drivers.QuestionPlaceholder)
+}
@@ -21,8 +21,10 @@ const defaultMigrationMaxSize = 10 * 1 << 20 // 10 MB
 
 // add here any custom driver configuration
 var configParams = []string{
+	"x-metadata-schema",
 	"x-migration-max-size",
 	"x-migrations-table",
+	"x-mutex-table",
 	"x-statement-timeout",
 }
 
@@ -39,6 +41,12 @@ type sqlite struct {
 	lockedFlag int32 // indicates that the driver is locked or not
 }
 
+func init() {
+	drivers.Register(driverName, func(connURL string) (drivers.Driver, error) {
+		return Open(strings.TrimPrefix(connURL, driverName+"://"))
+	})
+}
+
 func WithInstance(dbInstance *sql.DB) (drivers.Driver, error) {
 	conn, err := dbInstance.Conn(context.Background())
 	if err != nil {
@@ -153,7 +161,7 @@ func (driver *sqlite) createSchemaTableIfNotExists() (err error) {
 	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
 	defer cancel()
 
-	createTableIfNotExistsQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (Version bigint not null primary key, Name varchar not null)", driver.config.MigrationsTable)
+	createTableIfNotExistsQuery := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (Version bigint not null primary key, Name varchar not null, SkipReason text, AppliedOrder bigint)", driver.migrationsTable())
 	if _, err = driver.conn.ExecContext(ctx, createTableIfNotExistsQuery); err != nil {
 		return &drivers.DatabaseError{
 			OrigErr: err,
@@ -164,6 +172,58 @@ func (driver *sqlite) createSchemaTableIfNotExists() (err error) {
 		}
 	}
 
+	// SQLite stores integers in up to 8 bytes whatever the declared type of the
+	// column, so unlike the other drivers the version column never has to be
+	// widened for 64-bit versions.
+	for _, column := range []struct{ name, definition string }{
+		{name: "SkipReason", definition: "text"},
+		{name: "AppliedOrder", definition: "bigint"},
+	} {
+		if err = driver.addColumnIfNotExists(ctx, column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addColumnIfNotExists adds a column to the migrations tables of earlier
+// versions, e.g. SkipReason to the tables created before skipped migrations
+// were recorded. SQLite has neither ADD COLUMN IF NOT EXISTS nor
+// information_schema, so the columns are listed with pragma_table_info, in the
+// attached database of the metadata schema if there is one.
+func (driver *sqlite) addColumnIfNotExists(ctx context.Context, column, definition string) error {
+	schema := driver.config.MetadataSchema
+	if schema == "" {
+		schema = "main"
+	}
+
+	columnExistsQuery := "SELECT COUNT(*) FROM pragma_table_info(?, ?) WHERE name = ?"
+	var count int
+	if err := driver.conn.QueryRowContext(ctx, columnExistsQuery, driver.config.MigrationsTable, schema, column).Scan(&count); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed while executing query",
+			Command: "check_column",
+			Query:   []byte(columnExistsQuery),
+		}
+	}
+	if count > 0 {
+		return nil
+	}
+
+	addColumnQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", driver.migrationsTable(), column, definition)
+	if _, err := driver.conn.ExecContext(ctx, addColumnQuery); err != nil {
+		return &drivers.DatabaseError{
+			OrigErr: err,
+			Driver:  driverName,
+			Message: "failed while executing query",
+			Command: "add_column",
+			Query:   []byte(addColumnQuery),
+		}
+	}
+
 	return nil
 }
 
@@ -177,9 +237,19 @@ func (driver *sqlite) Apply(migration *models.Migration, saveVersion bool) (err
 
 	query := migration.Query()
 
-	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
+	directives, err := migration.Directives()
+	if err != nil {
+		return &drivers.AppError{Driver: driverName, OrigErr: err, Message: "failed to parse migration directives"}
+	}
+
+	ctx, cancel := drivers.GetContext(drivers.MigrationTimeout(driver.config.StatementTimeoutInSecs, directives))
 	defer cancel()
 
+	// Some statements, like VACUUM, cannot run inside a transaction.
+	if directives.NonTransactional {
+		return driver.applyNonTransactional(ctx, migration, saveVersion)
+	}
+
 	transaction, err := driver.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return &drivers.DatabaseError{
@@ -190,13 +260,15 @@ func (driver *sqlite) Apply(migration *models.Migration, saveVersion bool) (err
 		}
 	}
 
-	if err = execTransaction(transaction, query); err != nil {
-		return err
+	if !migration.RecordOnly {
+		if err = execTransaction(transaction, query, migration.Args...); err != nil {
+			return err
+		}
 	}
 
 	if saveVersion {
-		updateVersionQuery := driver.addMigrationQuery(migration)
-		if err = execTransaction(transaction, updateVersionQuery); err != nil {
+		updateVersionQuery, args := driver.addMigrationQuery(migration)
+		if err = execTransaction(transaction, updateVersionQuery, args...); err != nil {
 			return err
 		}
 	}
@@ -214,6 +286,36 @@ func (driver *sqlite) Apply(migration *models.Migration, saveVersion bool) (err
 	return nil
 }
 
+func (driver *sqlite) applyNonTransactional(ctx context.Context, migration *models.Migration, saveVersion bool) error {
+	query := migration.Query()
+	if !migration.RecordOnly {
+		if _, err := driver.conn.ExecContext(ctx, query, migration.Args...); err != nil {
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driverName,
+				Message: "failed when applying migration",
+				Command: "apply_migration",
+				Query:   []byte(query),
+			}
+		}
+	}
+
+	if saveVersion {
+		updateVersionQuery, args := driver.addMigrationQuery(migration)
+		if _, err := driver.conn.ExecContext(ctx, updateVersionQuery, args...); err != nil {
+			return &drivers.DatabaseError{
+				OrigErr: err,
+				Driver:  driverName,
+				Message: "failed when updating migrations table with the new version",
+				Command: "update_version",
+				Query:   []byte(updateVersionQuery),
+			}
+		}
+	}
+
+	return nil
+}
+
 func (driver *sqlite) AppliedMigrations() (migrations []*models.Migration, err error) {
 	if driver.conn == nil {
 		return nil, &drivers.AppError{
@@ -234,12 +336,12 @@ func (driver *sqlite) AppliedMigrations() (migrations []*models.Migration, err e
 		return nil, err
 	}
 
-	query := fmt.Sprintf("SELECT version, name FROM %s", driver.config.MigrationsTable)
+	query := fmt.Sprintf("SELECT version, name, COALESCE(SkipReason, ''), COALESCE(AppliedOrder, 0) FROM %s", driver.migrationsTable())
 	ctx, cancel := drivers.GetContext(driver.config.StatementTimeoutInSecs)
 	defer cancel()
 	var appliedMigrations []*models.Migration
-	var version uint32
-	var name string
+	var version, appliedOrder uint64
+	var name, skipReason string
 
 	rows, err := driver.conn.QueryContext(ctx, query)
 	if err != nil {
@@ -254,7 +356,7 @@ func (driver *sqlite) AppliedMigrations() (migrations []*models.Migration, err e
 	defer rows.Close()
 
 	for rows.Next() {
-		if err := rows.Scan(&version, &name); err != nil {
+		if err := rows.Scan(&version, &name, &skipReason, &appliedOrder); err != nil {
 			return nil, &drivers.DatabaseError{
 				OrigErr: err,
 				Driver:  driverName,
@@ -264,9 +366,11 @@ func (driver *sqlite) AppliedMigrations() (migrations []*models.Migration, err e
 		}
 
 		appliedMigrations = append(appliedMigrations, &models.Migration{
-			Name:      name,
-			Version:   version,
-			Direction: models.Up,
+			Name:         name,
+			Version:      version,
+			Direction:    models.Up,
+			SkipReason:   skipReason,
+			AppliedOrder: appliedOrder,
 		})
 	}
 
@@ -284,7 +388,20 @@ func mergeConfigWithParams(params map[string]string, config *driverConfig) (*dri
 					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
 				}
 			case "x-migrations-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
 				config.MigrationsTable = v
+			case "x-metadata-schema":
+				if err = drivers.ValidateSchemaName(v); err != nil {
+					return nil, err
+				}
+				config.MetadataSchema = v
+			case "x-mutex-table":
+				if err = drivers.ValidateTableName(v); err != nil {
+					return nil, err
+				}
+				config.MutexTable = v
 			case "x-statement-timeout":
 				if config.StatementTimeoutInSecs, err = strconv.Atoi(v); err != nil {
 					return nil, errors.New(fmt.Sprintf("failed to cast config param %s of %s", configKey, v))
@@ -296,11 +413,21 @@ func mergeConfigWithParams(params map[string]string, config *driverConfig) (*dri
 	return config, nil
 }
 
-func (driver *sqlite) addMigrationQuery(migration *models.Migration) string {
+func (driver *sqlite) migrationsTable() string {
+	return driver.metadataTable(driver.config.MigrationsTable)
+}
+
+// addMigrationQuery returns the statement that records the migration in the
+// migrations table, or deletes it for a down migration, with its bind
+// parameters.
+func (driver *sqlite) addMigrationQuery(migration *models.Migration) (string, []interface{}) {
 	if migration.Direction == models.Down {
-		return fmt.Sprintf("DELETE FROM %s WHERE (Version=%d AND NAME='%s')", driver.config.MigrationsTable, migration.Version, migration.Name)
+		return fmt.Sprintf("DELETE FROM %s WHERE Version = ? AND Name = ?", driver.migrationsTable()), []interface{}{migration.Version, migration.Name}
 	}
-	return fmt.Sprintf("INSERT INTO %s (Version, Name) VALUES (%d, '%s')", driver.config.MigrationsTable, migration.Version, migration.Name)
+
+	// the order is the next one after the latest applied migration
+	skipReason := sql.NullString{String: migration.SkipReason, Valid: migration.SkipReason != ""}
+	return fmt.Sprintf("INSERT INTO %[1]s (Version, Name, SkipReason, AppliedOrder) VALUES (?, ?, ?, (SELECT COALESCE(MAX(AppliedOrder), 0) + 1 FROM %[1]s))", driver.migrationsTable()), []interface{}{migration.Version, migration.Name, skipReason}
 }
 
 func (driver *sqlite) SetConfig(key string, value interface{}) error {
@@ -316,18 +443,41 @@ func (driver *sqlite) SetConfig(key string, value interface{}) error {
 		case "MigrationsTable":
 			n, ok := value.(string)
 			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
 				driver.config.MigrationsTable = n
 				return nil
 			}
 			return fmt.Errorf("incorrect value type for %s", key)
+		case "MetadataSchema":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateSchemaName(n); err != nil {
+					return err
+				}
+				driver.config.MetadataSchema = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
+		case "MutexTable":
+			n, ok := value.(string)
+			if ok {
+				if err := drivers.ValidateTableName(n); err != nil {
+					return err
+				}
+				driver.config.MutexTable = n
+				return nil
+			}
+			return fmt.Errorf("incorrect value type for %s", key)
 		}
 	}
 
 	return fmt.Errorf("incorrect key name %q", key)
 }
 
-func execTransaction(transaction *sql.Tx, query string) error {
-	if _, err := transaction.Exec(query); err != nil {
+func execTransaction(transaction *sql.Tx, query string, args ...interface{}) error {
+	if _, err := transaction.Exec(query, args...); err != nil {
 		if txErr := transaction.Rollback(); txErr != nil {
 			err = errors.Wrap(errors.New(err.Error()+txErr.Error()), "failed to execute query in migration transaction")
 
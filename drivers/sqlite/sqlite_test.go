@@ -1,15 +1,19 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/drivers/drivertest"
 	"github.com/mattermost/morph/models"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -360,6 +364,334 @@ func (suite *SqliteTestSuite) TestWithInstance() {
 	}()
 }
 
+func (suite *SqliteTestSuite) TestApplyRecordOnly() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	_, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when creating migrations table")
+
+	err = connectedDriver.Apply(&models.Migration{
+		Version:    1,
+		Bytes:      []byte("select * from foobar;"),
+		Name:       "migration_1",
+		Direction:  models.Up,
+		RecordOnly: true,
+	}, true)
+	suite.Require().NoError(err, "should not execute the migration in record only mode")
+
+	appliedMigrations, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when fetching applied migrations")
+	suite.Require().Len(appliedMigrations, 1)
+	suite.Assert().Equal("migration_1", appliedMigrations[0].Name)
+
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", driver.config.MigrationsTable))
+	suite.Require().NoError(err, "should not error while dropping migrations table")
+}
+
+func (suite *SqliteTestSuite) TestApplySkipReason() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	// a migrations table created before skip reasons were recorded
+	_, err := driver.db.Exec(fmt.Sprintf("CREATE TABLE %s (Version bigint not null primary key, Name varchar not null, CreatedAt timestamp not null default current_timestamp)", driver.config.MigrationsTable))
+	suite.Require().NoError(err, "should not error while creating the migrations table")
+
+	_, err = connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should add the skip reason column to an existing migrations table")
+
+	err = connectedDriver.Apply(&models.Migration{
+		Version:   1,
+		Bytes:     []byte("CREATE TABLE skip_reason_test (id integer);"),
+		Name:      "migration_1",
+		Direction: models.Up,
+	}, true)
+	suite.Require().NoError(err, "should apply the migration")
+
+	err = connectedDriver.Apply(&models.Migration{
+		Version:    2,
+		Bytes:      []byte("select * from foobar;"),
+		Name:       "migration_2+dev",
+		Direction:  models.Up,
+		RecordOnly: true,
+		SkipReason: "none of the tags dev is active",
+	}, true)
+	suite.Require().NoError(err, "should record the skipped migration")
+
+	appliedMigrations, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when fetching applied migrations")
+	suite.Require().Len(appliedMigrations, 2)
+	suite.Assert().Empty(appliedMigrations[0].SkipReason)
+	suite.Assert().Equal("none of the tags dev is active", appliedMigrations[1].SkipReason)
+
+	_, err = driver.db.Exec("DROP TABLE IF EXISTS skip_reason_test")
+	suite.Require().NoError(err, "should not error while dropping the test table")
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", driver.config.MigrationsTable))
+	suite.Require().NoError(err, "should not error while dropping migrations table")
+}
+
+func (suite *SqliteTestSuite) TestPlanJournal() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	runs, err := driver.PlanRuns(10)
+	suite.Require().NoError(err, "should not error when the journal is empty")
+	suite.Require().Empty(runs)
+
+	started := time.Now().Add(-time.Minute)
+	for i, runID := range []string{"run-1", "run-2"} {
+		step := &models.PlanStep{
+			PlanID:    "plan",
+			RunID:     runID,
+			Sequence:  1,
+			Name:      "migration_1",
+			Version:   1,
+			Direction: models.Up,
+			StartedAt: started.Add(time.Duration(i) * time.Second),
+			Outcome:   models.StepRunning,
+		}
+		suite.Require().NoError(driver.SavePlanStep(step), "should not error when saving a plan step")
+
+		step.FinishedAt = step.StartedAt.Add(time.Second)
+		step.Outcome = models.StepFailed
+		step.Error = "failed"
+		suite.Require().NoError(driver.SavePlanStep(step), "should not error when updating a plan step")
+
+		rollback := *step
+		rollback.Sequence = 2
+		rollback.Direction = models.Down
+		rollback.Rollback = true
+		rollback.Outcome = models.StepSucceeded
+		rollback.Error = ""
+		suite.Require().NoError(driver.SavePlanStep(&rollback), "should not error when saving a rollback step")
+	}
+
+	runs, err = driver.PlanRuns(1)
+	suite.Require().NoError(err, "should not error when fetching plan runs")
+	suite.Require().Len(runs, 1)
+	suite.Assert().Equal("run-2", runs[0].RunID)
+	suite.Assert().Equal("plan", runs[0].PlanID)
+	suite.Require().Len(runs[0].Steps, 2)
+	suite.Assert().Equal(models.StepFailed, runs[0].Steps[0].Outcome)
+	suite.Assert().Equal("failed", runs[0].Steps[0].Error)
+	suite.Assert().True(runs[0].Steps[1].Rollback)
+	suite.Assert().Equal(models.RunReverted, runs[0].Status())
+
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", driver.journalTable()))
+	suite.Require().NoError(err, "should not error while dropping journal table")
+}
+
+func (suite *SqliteTestSuite) TestDumpSchema() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	_, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when creating migrations table")
+
+	err = connectedDriver.Apply(&models.Migration{
+		Version: 1,
+		Bytes: []byte(`CREATE TABLE users (id integer PRIMARY KEY, email text NOT NULL, name text DEFAULT 'anonymous');
+			CREATE UNIQUE INDEX idx_users_email ON users (email);
+			CREATE TABLE posts (id integer PRIMARY KEY, user_id integer NOT NULL REFERENCES users (id) ON DELETE CASCADE, body text);
+			CREATE INDEX idx_posts_user_id ON posts (user_id);`),
+		Name:      "migration_1",
+		Direction: models.Up,
+	}, true)
+	suite.Require().NoError(err, "should not error while creating the tables")
+
+	schema, err := driver.DumpSchema()
+	suite.Require().NoError(err, "should not error when dumping the schema")
+	suite.Assert().Nil(schema.Table(driver.config.MigrationsTable), "should not include the migrations table")
+
+	posts := schema.Table("posts")
+	suite.Require().NotNil(posts)
+	suite.Require().Len(posts.Columns, 3)
+	suite.Assert().Equal(&models.Column{Name: "user_id", Type: "INTEGER", Nullable: false}, posts.Columns[1])
+	suite.Require().Len(posts.Constraints, 2)
+	suite.Assert().Equal("FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE", posts.Constraints[0].Definition)
+	suite.Assert().Equal("PRIMARY KEY (id)", posts.Constraints[1].Definition)
+	suite.Require().Len(posts.Indexes, 1)
+	suite.Assert().Equal(&models.Index{Name: "idx_posts_user_id", Columns: []string{"user_id"}}, posts.Indexes[0])
+
+	users := schema.Table("users")
+	suite.Require().NotNil(users)
+	suite.Assert().Equal(&models.Column{Name: "name", Type: "TEXT", Nullable: true, Default: "'anonymous'"}, users.Columns[2])
+	suite.Require().Len(users.Indexes, 1)
+	suite.Assert().True(users.Indexes[0].Unique)
+
+	again, err := driver.DumpSchema()
+	suite.Require().NoError(err, "should not error when dumping the schema again")
+	suite.Assert().Equal(schema.String(), again.String(), "dumps should be deterministic")
+
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS posts; DROP TABLE IF EXISTS users; DROP TABLE IF EXISTS %s", driver.config.MigrationsTable))
+	suite.Require().NoError(err, "should not error while dropping the tables")
+}
+
+func (suite *SqliteTestSuite) TestApplyNonTransactional() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	_, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when creating migrations table")
+
+	err = connectedDriver.Apply(&models.Migration{
+		Version:   1,
+		Bytes:     []byte("VACUUM;"),
+		Name:      "migration_1",
+		Direction: models.Up,
+	}, false)
+	suite.Require().Error(err, "should not be able to vacuum within a transaction")
+
+	err = connectedDriver.Apply(&models.Migration{
+		Version:   1,
+		Bytes:     []byte("-- morph:nontransactional\nVACUUM;"),
+		Name:      "migration_1",
+		Direction: models.Up,
+	}, true)
+	suite.Require().NoError(err, "should vacuum outside of a transaction")
+
+	appliedMigrations, err := connectedDriver.AppliedMigrations()
+	suite.Require().NoError(err, "should not error when fetching applied migrations")
+	suite.Require().Len(appliedMigrations, 1)
+
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", driver.config.MigrationsTable))
+	suite.Require().NoError(err, "should not error while dropping migrations table")
+}
+
+func (suite *SqliteTestSuite) TestRepeatables() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	checksums, err := driver.AppliedRepeatables()
+	suite.Require().NoError(err, "should not error when no repeatable has been applied")
+	suite.Require().Empty(checksums)
+
+	suite.Require().NoError(driver.SaveRepeatable("views", "abc"), "should not error when saving a repeatable")
+	suite.Require().NoError(driver.SaveRepeatable("views", "def"), "should not error when updating a repeatable")
+	suite.Require().NoError(driver.SaveRepeatable("functions", "123"), "should not error when saving another repeatable")
+
+	checksums, err = driver.AppliedRepeatables()
+	suite.Require().NoError(err, "should not error when fetching applied repeatables")
+	suite.Assert().Equal(map[string]string{"views": "def", "functions": "123"}, checksums)
+
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", driver.repeatableTable()))
+	suite.Require().NoError(err, "should not error while dropping repeatable table")
+}
+
+func (suite *SqliteTestSuite) TestSeeds() {
+	connectedDriver := suite.InitializeDriver(testConnURL)
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+
+	checksums, err := driver.AppliedSeeds()
+	suite.Require().NoError(err, "should not error when no seed has been applied")
+	suite.Require().Empty(checksums)
+
+	suite.Require().NoError(driver.SaveSeed("roles", "abc"), "should not error when saving a seed")
+	suite.Require().NoError(driver.SaveSeed("roles", "def"), "should not error when updating a seed")
+
+	checksums, err = driver.AppliedSeeds()
+	suite.Require().NoError(err, "should not error when fetching applied seeds")
+	suite.Assert().Equal(map[string]string{"roles": "def"}, checksums)
+
+	_, err = driver.db.Exec("CREATE TABLE seed_roles (id integer primary key, name varchar not null, builtin boolean)")
+	suite.Require().NoError(err, "should not error while creating the seeded table")
+
+	upsert := func(rows [][]interface{}) {
+		query, args, err := driver.UpsertQuery("seed_roles", []string{"id"}, []string{"id", "name", "builtin"}, rows)
+		suite.Require().NoError(err, "should build the upsert query")
+		suite.Require().NotContains(query, "admin", "should bind the values")
+		_, err = driver.db.Exec(query, args...)
+		suite.Require().NoError(err, "should upsert the rows")
+	}
+	upsert([][]interface{}{{json.Number("1"), "admin", true}, {json.Number("2"), `user's \`, nil}})
+	upsert([][]interface{}{{json.Number("1"), "administrator", false}})
+
+	var name string
+	var builtin bool
+	suite.Require().NoError(driver.db.QueryRow("SELECT name, builtin FROM seed_roles WHERE id = 1").Scan(&name, &builtin))
+	suite.Assert().Equal("administrator", name)
+	suite.Assert().False(builtin)
+	suite.Require().NoError(driver.db.QueryRow("SELECT name FROM seed_roles WHERE id = 2").Scan(&name))
+	suite.Assert().Equal(`user's \`, name)
+
+	_, err = driver.db.Exec("DROP TABLE IF EXISTS seed_roles")
+	suite.Require().NoError(err, "should not error while dropping the seeded table")
+	_, err = driver.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", driver.seedTable()))
+	suite.Require().NoError(err, "should not error while dropping seeds table")
+}
+
+func (suite *SqliteTestSuite) TestMetadataSchema() {
+	connectedDriver := suite.InitializeDriver(testConnURL + "?x-metadata-schema=morph&x-migrations-table=metadata_migrations")
+	suite.T().Cleanup(func() {
+		require.NoError(suite.T(), connectedDriver.Close(), "should close the driver w/o errors")
+	})
+
+	driver, ok := connectedDriver.(*sqlite)
+	suite.Require().True(ok)
+	suite.Require().Equal("morph", driver.config.MetadataSchema)
+
+	_, err := driver.conn.ExecContext(context.Background(), "ATTACH DATABASE ? AS morph", filepath.Join(suite.T().TempDir(), "morph-metadata.db"))
+	suite.Require().NoError(err, "should attach the metadata database")
+
+	applied, err := driver.AppliedMigrations()
+	suite.Require().NoError(err, "should create the migrations table in the metadata schema")
+	suite.Require().Empty(applied)
+
+	migration := &models.Migration{Version: 1, Name: "noop", Direction: models.Up, Bytes: []byte("SELECT 1;")}
+	suite.Require().NoError(driver.Apply(migration, true), "should save the version in the metadata schema")
+
+	applied, err = driver.AppliedMigrations()
+	suite.Require().NoError(err)
+	suite.Require().Len(applied, 1)
+
+	var count int
+	suite.Require().NoError(driver.conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM morph.sqlite_master WHERE name = 'metadata_migrations'").Scan(&count))
+	suite.Assert().Equal(1, count, "should create the migrations table in the metadata schema")
+	suite.Require().NoError(driver.conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM main.sqlite_master WHERE name = 'metadata_migrations'").Scan(&count))
+	suite.Assert().Zero(count, "should not create the migrations table in the main schema")
+
+	suite.Require().NoError(driver.SetConfig("MetadataSchema", ""), "should reset the metadata schema")
+	suite.Require().Error(driver.SetConfig("MetadataSchema", "morph; DROP TABLE users"), "should reject a schema name that is not an identifier")
+
+	_, err = Open(testConnURL + "?x-metadata-schema=1morph")
+	suite.Require().Error(err, "should reject an invalid metadata schema")
+}
+
 func TestSqliteTestSuite(t *testing.T) {
 	defaultDBFile, err := os.CreateTemp("", "morph-default.db")
 	require.NoError(t, err)
@@ -379,3 +711,18 @@ func TestSqliteTestSuite(t *testing.T) {
 
 	suite.Run(t, new(SqliteTestSuite))
 }
+
+func TestConformance(t *testing.T) {
+	connURL := filepath.Join(t.TempDir(), "morph-conformance.db")
+	require.NoError(t, os.WriteFile(connURL, nil, 0600))
+
+	drivertest.RunConformance(t, func(t *testing.T) drivers.Driver {
+		driver, err := Open(connURL)
+		require.NoError(t, err, "should not error when connecting to database from url")
+		t.Cleanup(func() {
+			require.NoError(t, driver.Close(), "should close the driver w/o errors")
+		})
+
+		return driver
+	}, drivertest.WithConnURL(driverName, connURL))
+}
@@ -6,8 +6,16 @@ func getDefaultConfig() *driverConfig {
 	return &driverConfig{
 		Config: drivers.Config{
 			MigrationsTable:        "db_migrations",
+			MutexTable:             drivers.MutexTableName,
 			StatementTimeoutInSecs: 300,
 			MigrationMaxSize:       defaultMigrationMaxSize,
 		},
 	}
 }
+
+// metadataTable returns the quoted name of a table morph keeps its state in,
+// qualified with the metadata schema, the name of an attached database, if
+// there is one.
+func (driver *sqlite) metadataTable(name string) string {
+	return drivers.QuoteTableName(driver.config.MetadataSchema, name, `"`)
+}
@@ -0,0 +1,79 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+)
+
+// Row is a row returned by a query, such as a *sql.Row or a pgx.Row.
+type Row interface {
+	Scan(dest ...interface{}) error
+}
+
+// RowQueryer is the connection a migrations table is upgraded through. The
+// drivers adapt their connections to it.
+type RowQueryer interface {
+	Exec(ctx context.Context, query string) error
+	QueryRow(ctx context.Context, query string, args ...interface{}) Row
+}
+
+// postgresMigrationsColumns are the columns missing from the PostgreSQL
+// migrations tables created by earlier versions.
+var postgresMigrationsColumns = []struct{ name, definition string }{
+	{name: "skip_reason", definition: "text"},
+	{name: "applied_order", definition: "bigint"},
+}
+
+// UpgradePostgresMigrationsTable upgrades the migrations tables created by
+// earlier versions or by other tools, which the PostgreSQL drivers share. It
+// adds the columns that are missing, e.g. skip_reason to the tables created
+// before skipped migrations were recorded, and widens the version column if
+// it cannot hold 64-bit versions, such as timestamps. The columns are looked up
+// first instead of being added with ADD COLUMN IF NOT EXISTS, because ALTER
+// TABLE takes an exclusive lock on the table even when it has nothing to do.
+//
+// The table is looked up by its name in the schema, or in the current schema
+// if the schema is empty, and altered through its quoted name.
+func UpgradePostgresMigrationsTable(ctx context.Context, conn RowQueryer, driver, schema, name, quotedName string) error {
+	for _, column := range postgresMigrationsColumns {
+		columnExistsQuery := "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2 AND column_name = $3"
+		var count int
+		if err := conn.QueryRow(ctx, columnExistsQuery, schema, name, column.name).Scan(&count); err != nil {
+			return upgradeError(err, driver, "check_column", columnExistsQuery)
+		}
+		if count > 0 {
+			continue
+		}
+
+		addColumnQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quotedName, column.name, column.definition)
+		if err := conn.Exec(ctx, addColumnQuery); err != nil {
+			return upgradeError(err, driver, "add_column", addColumnQuery)
+		}
+	}
+
+	columnTypeQuery := "SELECT data_type FROM information_schema.columns WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2 AND column_name = 'version'"
+	var dataType string
+	if err := conn.QueryRow(ctx, columnTypeQuery, schema, name).Scan(&dataType); err != nil {
+		return upgradeError(err, driver, "check_version_column", columnTypeQuery)
+	}
+	if dataType != "smallint" && dataType != "integer" {
+		return nil
+	}
+
+	alterColumnQuery := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN version TYPE bigint", quotedName)
+	if err := conn.Exec(ctx, alterColumnQuery); err != nil {
+		return upgradeError(err, driver, "widen_version_column", alterColumnQuery)
+	}
+
+	return nil
+}
+
+func upgradeError(err error, driver, command, query string) error {
+	return &DatabaseError{
+		OrigErr: err,
+		Driver:  driver,
+		Message: "failed while executing query",
+		Command: command,
+		Query:   []byte(query),
+	}
+}
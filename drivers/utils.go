@@ -7,12 +7,21 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/mattermost/morph/models"
 )
 
+// customParamValue matches the value of a custom parameter up to the next
+// parameter, whatever it contains, so that the drivers validate all of it.
+const customParamValue = `[^&#]+`
+
+// ExtractCustomParams returns the values of the given parameters of the
+// connection URL. The values are returned as they are, for the drivers to
+// validate.
 func ExtractCustomParams(conn string, params []string) (map[string]string, error) {
 	result := make(map[string]string)
 	for _, param := range params {
-		reg := regexp.MustCompile(fmt.Sprintf("%s=(\\w+)", param))
+		reg := regexp.MustCompile(fmt.Sprintf("%s=(%s)", param, customParamValue))
 		match := reg.FindStringSubmatch(conn)
 		if len(match) > 1 {
 			result[param] = match[1]
@@ -27,7 +36,7 @@ func RemoveParamsFromURL(conn string, params []string) (string, error) {
 	repeatedAmber := regexp.MustCompile("&+")
 
 	for _, param := range params {
-		reg := regexp.MustCompile(fmt.Sprintf("%s=\\w+", param))
+		reg := regexp.MustCompile(fmt.Sprintf("%s=%s", param, customParamValue))
 		conn = string(reg.ReplaceAll([]byte(conn), []byte(``)))
 	}
 
@@ -56,3 +65,91 @@ func GetContext(timeoutInSeconds int) (context.Context, context.CancelFunc) {
 	}
 	return context.WithCancel(context.Background())
 }
+
+// tableNameRegex matches the names of the tables morph keeps its state in.
+var tableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// maxIdentifierLength is the length PostgreSQL truncates identifiers to, which
+// is below the limit of the other databases.
+const maxIdentifierLength = 63
+
+// ValidateTableName returns an error if the name cannot be used as the
+// migrations table, i.e. if it is not a plain identifier or if the tables named
+// after it would be too long.
+func ValidateTableName(name string) error {
+	if !tableNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid table name %q: only letters, digits and underscores are allowed", name)
+	}
+
+	if len(name)+len(RepeatableTableSuffix) > maxIdentifierLength {
+		return fmt.Errorf("invalid table name %q: must not be longer than %d characters", name, maxIdentifierLength-len(RepeatableTableSuffix))
+	}
+
+	return nil
+}
+
+// ValidateSchemaName returns an error if the name cannot be used as the
+// metadata schema. An empty name, which stands for the current schema, is
+// valid.
+func ValidateSchemaName(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	if !tableNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid schema name %q: only letters, digits and underscores are allowed", name)
+	}
+
+	if len(name) > maxIdentifierLength {
+		return fmt.Errorf("invalid schema name %q: must not be longer than %d characters", name, maxIdentifierLength)
+	}
+
+	return nil
+}
+
+// QuoteTableName quotes the name of a table like QuoteIdentifier, and
+// qualifies it with the quoted schema if the schema is not empty.
+func QuoteTableName(schema, table, quote string) string {
+	if schema == "" {
+		return QuoteIdentifier(table, quote)
+	}
+
+	return QuoteIdentifier(schema, quote) + "." + QuoteIdentifier(table, quote)
+}
+
+// QuoteIdentifier quotes an identifier of a query with the quote character of
+// the database, e.g. a double quote for PostgreSQL and a backtick for MySQL.
+// The quote characters of the identifier are doubled.
+func QuoteIdentifier(name, quote string) string {
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// MigrationTimeout returns the statement timeout for the migration, which is
+// the timeout directive of the migration if it has one, and the given default
+// otherwise.
+func MigrationTimeout(defaultTimeoutInSeconds int, directives *models.Directives) int {
+	if directives.TimeoutInSecs > 0 {
+		return directives.TimeoutInSecs
+	}
+
+	return defaultTimeoutInSeconds
+}
+
+// ToMillis converts the time to milliseconds since epoch, the zero time is
+// converted to zero.
+func ToMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMilli()
+}
+
+// FromMillis is the inverse of ToMillis.
+func FromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
@@ -1,6 +1,7 @@
 package drivers
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -12,3 +13,49 @@ func TestGenerateAdvisoryLockID(t *testing.T) {
 
 	t.Logf("advisory lock id: %s", n)
 }
+
+func TestValidateTableName(t *testing.T) {
+	for _, name := range []string{"db_migrations", "Migrations2", "_morph"} {
+		require.NoError(t, ValidateTableName(name), name)
+	}
+
+	for _, name := range []string{"", "db migrations", "db_migrations; DROP TABLE users", "morph.db_migrations", `"db_migrations"`, "1migrations", strings.Repeat("a", 53)} {
+		require.Error(t, ValidateTableName(name), name)
+	}
+
+	require.NoError(t, ValidateTableName(strings.Repeat("a", 52)))
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, `"db_migrations"`, QuoteIdentifier("db_migrations", `"`))
+	require.Equal(t, `"db""migrations"`, QuoteIdentifier(`db"migrations`, `"`))
+	require.Equal(t, "`db``migrations`", QuoteIdentifier("db`migrations", "`"))
+}
+
+func TestValidateSchemaName(t *testing.T) {
+	for _, name := range []string{"", "morph", "Morph_2"} {
+		require.NoError(t, ValidateSchemaName(name), name)
+	}
+
+	for _, name := range []string{"morph schema", "morph.meta", "morph; DROP TABLE users", strings.Repeat("a", 64)} {
+		require.Error(t, ValidateSchemaName(name), name)
+	}
+}
+
+func TestQuoteTableName(t *testing.T) {
+	require.Equal(t, `"db_migrations"`, QuoteTableName("", "db_migrations", `"`))
+	require.Equal(t, "`morph`.`db_lock`", QuoteTableName("morph", "db_lock", "`"))
+}
+
+func TestExtractCustomParams(t *testing.T) {
+	params, err := ExtractCustomParams("postgres://localhost/morph?sslmode=disable&x-migrations-table=a;drop&x-statement-timeout=10", []string{"x-migrations-table", "x-statement-timeout", "x-mutex-table"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"x-migrations-table": "a;drop", "x-statement-timeout": "10"}, params, "should capture the whole value of the parameters")
+	require.Error(t, ValidateTableName(params["x-migrations-table"]))
+}
+
+func TestRemoveParamsFromURL(t *testing.T) {
+	conn, err := RemoveParamsFromURL("postgres://localhost/morph?x-migrations-table=a;drop&x-statement-timeout=10&sslmode=disable", []string{"x-migrations-table", "x-statement-timeout"})
+	require.NoError(t, err)
+	require.Equal(t, "postgres://localhost/morph?sslmode=disable", conn, "should remove the whole value of the parameters")
+}
@@ -0,0 +1,85 @@
+package morph
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// planRecorder writes the steps of a plan run to the driver's journal. If the
+// driver does not support journaling, the recorder does nothing. Failing to write
+// to the journal does not abort the plan, it is only logged.
+type planRecorder struct {
+	journal  drivers.Journal
+	logger   Logger
+	planID   string
+	runID    string
+	sequence int
+}
+
+func (m *Morph) newPlanRecorder(plan *models.Plan) *planRecorder {
+	r := &planRecorder{
+		logger: m.config.Logger,
+		planID: plan.ID,
+		runID:  uuid.New().String(),
+	}
+
+	if journal, ok := m.driver.(drivers.Journal); ok && !m.config.DryRun {
+		r.journal = journal
+	}
+
+	if r.planID == "" {
+		// plans generated before the journal was introduced have no ID
+		r.planID = r.runID
+	}
+
+	return r
+}
+
+func (r *planRecorder) start(migration *models.Migration, rollback bool) *models.PlanStep {
+	r.sequence++
+	step := &models.PlanStep{
+		PlanID:    r.planID,
+		RunID:     r.runID,
+		Sequence:  r.sequence,
+		Name:      migration.Name,
+		Version:   migration.Version,
+		Direction: migration.Direction,
+		Rollback:  rollback,
+		StartedAt: time.Now(),
+		Outcome:   models.StepRunning,
+	}
+	r.save(step)
+
+	return step
+}
+
+func (r *planRecorder) finish(step *models.PlanStep, err error) {
+	step.FinishedAt = time.Now()
+	step.Outcome = models.StepSucceeded
+	if err != nil {
+		step.Outcome = models.StepFailed
+		step.Error = err.Error()
+	}
+	r.save(step)
+}
+
+func (r *planRecorder) skip(migration *models.Migration) {
+	step := r.start(migration, false)
+	step.FinishedAt = step.StartedAt
+	step.Outcome = models.StepSkipped
+	r.save(step)
+}
+
+func (r *planRecorder) save(step *models.PlanStep) {
+	if r.journal == nil {
+		return
+	}
+
+	if err := r.journal.SavePlanStep(step); err != nil {
+		r.logger.Printf("could not save step %d of plan run %s to the journal: %v", step.Sequence, step.RunID, err)
+	}
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DirectivePrefix starts a directive comment. Directives are read from the
+// comments at the top of a migration, before the first statement, e.g.
+//
+//	-- morph:nontransactional,timeout=600
+//	-- morph:env=production|staging
+//
+// A line holds a comma separated list of directives, and a directive may have a
+// value after an equals sign. Multiple values are separated by a pipe.
+const DirectivePrefix = "-- morph:"
+
+const (
+	// DirectiveNonTransactional runs the migration outside of a transaction.
+	DirectiveNonTransactional = "nontransactional"
+	// DirectiveTimeout overrides the statement timeout, in seconds.
+	DirectiveTimeout = "timeout"
+	// DirectiveConfirm requires a manual confirmation before the migration runs.
+	DirectiveConfirm = "confirm"
+	// DirectiveEnv restricts the migration to the listed environments.
+	DirectiveEnv = "env"
+	// DirectiveTags restricts the migration to deployments with one of the
+	// listed tags active.
+	DirectiveTags = "tags"
+	// DirectiveDependsOn lists the migrations that have to be applied before
+	// this one, by name, e.g. create_teams for 000003_create_teams.up.sql.
+	DirectiveDependsOn = "depends-on"
+	// DirectiveSquash marks a migration that replaces every migration up to and
+	// including its version, as generated by morph squash.
+	DirectiveSquash = "squash"
+)
+
+// Directives are the per migration settings declared in its header comments.
+type Directives struct {
+	NonTransactional bool
+	// TimeoutInSecs is zero if the migration does not override the timeout.
+	TimeoutInSecs int
+	Confirm       bool
+	// Environments is empty if the migration runs in every environment.
+	Environments []string
+	// Tags are the tags declared with the tags directive. The tags in the file
+	// name are returned by Migration.Tags along with these.
+	Tags []string
+	// DependsOn are the names of the migrations this one depends on.
+	DependsOn []string
+	Squash    bool
+	// Unknown are the entries that are not directives known to this version,
+	// e.g. free-form comments. They are ignored.
+	Unknown []string
+}
+
+// ParseDirectives reads the directives from the header comments of a query.
+// Parsing stops at the first line that is neither empty nor a comment. Unknown
+// directives are collected in Unknown rather than rejected, while the known ones
+// with an invalid value return an error.
+func ParseDirectives(query string) (*Directives, error) {
+	directives := &Directives{}
+
+	scanner := bufio.NewScanner(strings.NewReader(query))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if !strings.HasPrefix(line, DirectivePrefix) {
+			continue
+		}
+
+		for _, entry := range strings.Split(strings.TrimPrefix(line, DirectivePrefix), ",") {
+			if err := directives.set(strings.TrimSpace(entry)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return directives, nil
+}
+
+func (d *Directives) set(entry string) error {
+	key, value, hasValue := strings.Cut(entry, "=")
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case DirectiveNonTransactional:
+		d.NonTransactional = true
+	case DirectiveConfirm:
+		d.Confirm = true
+	case DirectiveSquash:
+		d.Squash = true
+	case DirectiveTimeout:
+		timeout, err := strconv.Atoi(value)
+		if !hasValue || err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid directive %q: timeout must be a positive number of seconds", entry)
+		}
+		d.TimeoutInSecs = timeout
+	case DirectiveEnv:
+		if !hasValue || value == "" {
+			return fmt.Errorf("invalid directive %q: at least one environment is required", entry)
+		}
+		d.Environments = append(d.Environments, splitValues(value)...)
+	case DirectiveTags:
+		if !hasValue || value == "" {
+			return fmt.Errorf("invalid directive %q: at least one tag is required", entry)
+		}
+		d.Tags = append(d.Tags, splitValues(value)...)
+	case DirectiveDependsOn:
+		if !hasValue || value == "" {
+			return fmt.Errorf("invalid directive %q: at least one migration is required", entry)
+		}
+		d.DependsOn = append(d.DependsOn, splitValues(value)...)
+	default:
+		if entry != "" {
+			d.Unknown = append(d.Unknown, entry)
+		}
+	}
+
+	return nil
+}
+
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// RunsIn reports whether the migration runs in the given environment.
+func (d *Directives) RunsIn(env string) bool {
+	if len(d.Environments) == 0 {
+		return true
+	}
+
+	for _, e := range d.Environments {
+		if e == env {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirectives(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected *Directives
+		err      string
+	}{
+		{
+			name:     "no directives",
+			query:    "CREATE TABLE users (id integer);",
+			expected: &Directives{},
+		},
+		{
+			name:  "valid directives",
+			query: "-- creates the index\n-- morph:nontransactional, timeout=600\n-- morph:env=staging|production,confirm\n-- morph:tags=dev|test,depends-on=create_users|create_teams\n-- morph:squash\nCREATE INDEX CONCURRENTLY idx ON users (id);",
+			expected: &Directives{
+				NonTransactional: true,
+				TimeoutInSecs:    600,
+				Confirm:          true,
+				Environments:     []string{"staging", "production"},
+				Tags:             []string{"dev", "test"},
+				DependsOn:        []string{"create_users", "create_teams"},
+				Squash:           true,
+			},
+		},
+		{
+			name:     "directives after the first statement",
+			query:    "SELECT 1;\n-- morph:nontransactional",
+			expected: &Directives{},
+		},
+		{
+			name:  "duplicate directives",
+			query: "-- morph:env=staging,env=production\n-- morph:confirm,confirm\n-- morph:timeout=10,timeout=20",
+			expected: &Directives{
+				TimeoutInSecs: 20,
+				Confirm:       true,
+				Environments:  []string{"staging", "production"},
+			},
+		},
+		{
+			name:  "unknown directives",
+			query: "-- morph:nontransactinal, keep this migration small\n-- morph:timeout=60\nSELECT 1;",
+			expected: &Directives{
+				TimeoutInSecs: 60,
+				Unknown:       []string{"nontransactinal", "keep this migration small"},
+			},
+		},
+		{
+			name:  "empty entries",
+			query: "-- morph:confirm,,\nSELECT 1;",
+			expected: &Directives{
+				Confirm: true,
+			},
+		},
+		{
+			name:  "timeout without a value",
+			query: "-- morph:timeout\nSELECT 1;",
+			err:   `invalid directive "timeout": timeout must be a positive number of seconds`,
+		},
+		{
+			name:  "timeout that is not a number",
+			query: "-- morph:timeout=ten\nSELECT 1;",
+			err:   `invalid directive "timeout=ten": timeout must be a positive number of seconds`,
+		},
+		{
+			name:  "negative timeout",
+			query: "-- morph:timeout=-1\nSELECT 1;",
+			err:   `invalid directive "timeout=-1": timeout must be a positive number of seconds`,
+		},
+		{
+			name:  "env without a value",
+			query: "-- morph:env=\nSELECT 1;",
+			err:   `invalid directive "env=": at least one environment is required`,
+		},
+		{
+			name:  "tags without a value",
+			query: "-- morph:tags\nSELECT 1;",
+			err:   `invalid directive "tags": at least one tag is required`,
+		},
+		{
+			name:  "depends-on without a value",
+			query: "-- morph:depends-on=\nSELECT 1;",
+			err:   `invalid directive "depends-on=": at least one migration is required`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			directives, err := ParseDirectives(tc.query)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, directives)
+		})
+	}
+}
+
+func TestRunsIn(t *testing.T) {
+	directives := &Directives{Environments: []string{"staging", "production"}}
+	require.True(t, directives.RunsIn("staging"))
+	require.False(t, directives.RunsIn("development"))
+	require.False(t, directives.RunsIn(""))
+
+	require.True(t, (&Directives{}).RunsIn(""))
+}
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// StepOutcome is the result of a single step taken while executing a plan.
+type StepOutcome string
+
+const (
+	StepRunning   StepOutcome = "running"
+	StepSucceeded StepOutcome = "succeeded"
+	StepFailed    StepOutcome = "failed"
+	StepSkipped   StepOutcome = "skipped"
+)
+
+// RunStatus is the overall result of a plan run.
+type RunStatus string
+
+const (
+	// RunInProgress means that at least one step did not finish, either because
+	// the plan is still being executed or because the process has been interrupted.
+	RunInProgress RunStatus = "in_progress"
+	// RunSucceeded means that every step of the plan has been applied.
+	RunSucceeded RunStatus = "succeeded"
+	// RunFailed means that a step failed and no rollback has been attempted.
+	RunFailed RunStatus = "failed"
+	// RunReverted means that a step failed and every rollback step succeeded.
+	RunReverted RunStatus = "reverted"
+	// RunRevertFailed means that a step failed and the rollback failed as well.
+	RunRevertFailed RunStatus = "revert_failed"
+)
+
+// PlanStep is the journal entry of a single migration applied during a plan run.
+type PlanStep struct {
+	// PlanID is the identifier of the plan being executed.
+	PlanID string
+	// RunID is the identifier of the plan execution.
+	RunID string
+	// Sequence is the position of the step within the run, starting from 1.
+	Sequence int
+	// Name is the name of the migration.
+	Name string
+	// Version is the version of the migration.
+	Version uint64
+	// Direction is the direction of the migration.
+	Direction Direction
+	// Rollback is true if the step has been taken to revert a failed plan.
+	Rollback bool
+	// StartedAt is the time the step has started.
+	StartedAt time.Time
+	// FinishedAt is the time the step has finished, zero if it is still running.
+	FinishedAt time.Time
+	// Outcome is the result of the step.
+	Outcome StepOutcome
+	// Error is the error message if the step has failed.
+	Error string
+}
+
+// PlanRun is a single execution of a plan, along with the steps taken.
+type PlanRun struct {
+	PlanID string
+	RunID  string
+	Steps  []*PlanStep
+}
+
+// StartedAt returns the time the first step of the run has started.
+func (r *PlanRun) StartedAt() time.Time {
+	if len(r.Steps) == 0 {
+		return time.Time{}
+	}
+
+	return r.Steps[0].StartedAt
+}
+
+// Status computes the overall result of the run from its steps.
+func (r *PlanRun) Status() RunStatus {
+	var failed, rolledBack, rollbackFailed bool
+	for _, step := range r.Steps {
+		switch {
+		case step.Outcome == StepRunning:
+			return RunInProgress
+		case step.Rollback && step.Outcome == StepFailed:
+			rollbackFailed = true
+		case step.Rollback:
+			rolledBack = true
+		case step.Outcome == StepFailed:
+			failed = true
+		}
+	}
+
+	switch {
+	case rollbackFailed:
+		return RunRevertFailed
+	case failed && rolledBack:
+		return RunReverted
+	case failed:
+		return RunFailed
+	}
+
+	return RunSucceeded
+}
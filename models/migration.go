@@ -2,20 +2,50 @@ package models
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
+	"strings"
 )
 
 type Migration struct {
 	Bytes     []byte
 	Name      string
 	RawName   string
-	Version   uint32
+	Version   uint64
 	Direction Direction
+	// RecordOnly instructs the driver to only save the version of the migration
+	// without executing it.
+	RecordOnly bool `json:",omitempty"`
+	// Repeatable migrations have no version. They are applied after the
+	// versioned migrations every time their checksum changes.
+	Repeatable bool `json:",omitempty"`
+	// Seed is set on seeds, which hold reference data rather than schema
+	// changes. They have no version and are applied by Morph.Seed.
+	Seed bool `json:",omitempty"`
+	// SkipReason is set on migrations that are recorded as applied without
+	// running because they do not apply to the deployment, e.g. their tags are
+	// not active. It is stored in the migrations table.
+	SkipReason string `json:",omitempty"`
+	// AppliedOrder is the position of an applied migration in the order the
+	// migrations have been applied in, which differs from the order of the
+	// versions for the migrations applied out of order. It is zero for the
+	// migrations recorded before the order was stored, and with the drivers
+	// that do not store it.
+	AppliedOrder uint64 `json:",omitempty"`
+	// Args are the bind parameters of the query, set on the migrations built
+	// from seed data files. The drivers execute the query with them.
+	Args []interface{} `json:"-"`
 }
 
 func NewMigration(migrationBytes io.ReadCloser, fileName string) (*Migration, error) {
+	if r := RepeatableRegex.FindStringSubmatch(fileName); len(r) == 3 {
+		return newRepeatableMigration(migrationBytes, fileName, r[1])
+	}
+
 	m := Regex.FindStringSubmatch(fileName)
 
 	var (
@@ -30,6 +60,10 @@ func NewMigration(migrationBytes io.ReadCloser, fileName string) (*Migration, er
 		if err != nil {
 			return nil, err
 		}
+		// the drivers store the versions in signed 64-bit columns
+		if versionUint64 > math.MaxInt64 {
+			return nil, fmt.Errorf("could not parse file: %s: the version must not be greater than %d", fileName, int64(math.MaxInt64))
+		}
 		identifier = m[2]
 		direction = Direction(m[3])
 	} else {
@@ -43,7 +77,7 @@ func NewMigration(migrationBytes io.ReadCloser, fileName string) (*Migration, er
 	defer migrationBytes.Close()
 
 	return &Migration{
-		Version:   uint32(versionUint64),
+		Version:   versionUint64,
 		Name:      identifier,
 		RawName:   fileName,
 		Bytes:     buf.Bytes(),
@@ -51,6 +85,58 @@ func NewMigration(migrationBytes io.ReadCloser, fileName string) (*Migration, er
 	}, nil
 }
 
+func newRepeatableMigration(migrationBytes io.ReadCloser, fileName, identifier string) (*Migration, error) {
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(migrationBytes); err != nil {
+		return nil, err
+	}
+	defer migrationBytes.Close()
+
+	return &Migration{
+		Name:       identifier,
+		RawName:    fileName,
+		Bytes:      buf.Bytes(),
+		Direction:  Up,
+		Repeatable: true,
+	}, nil
+}
+
 func (m *Migration) Query() string {
 	return string(m.Bytes)
 }
+
+// Directives parses the directives declared in the header comments of the
+// migration.
+func (m *Migration) Directives() (*Directives, error) {
+	directives, err := ParseDirectives(m.Query())
+	if err != nil {
+		return nil, fmt.Errorf("could not parse directives of migration %s: %w", m.Name, err)
+	}
+
+	return directives, nil
+}
+
+// Tags returns the tags of the migration, from both its file name and its
+// tags directive. A migration without tags applies to every deployment.
+func (m *Migration) Tags() ([]string, error) {
+	directives, err := m.Directives()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(m.Name, TagSeparator)
+	tags := make([]string, 0, len(parts)-1+len(directives.Tags))
+	for _, tag := range append(parts[1:], directives.Tags...) {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+// Checksum returns the hex encoded SHA-256 hash of the migration contents.
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256(m.Bytes)
+	return hex.EncodeToString(sum[:])
+}
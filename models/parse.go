@@ -22,3 +22,15 @@ var (
 //	123_name.up.ext
 //	123_name.down.ext
 var Regex = regexp.MustCompile(`^([0-9]+)_(.*)\.(` + string(Down) + `|` + string(Up) + `)\.(.*)$`)
+
+// TagSeparator separates the tags from the name of a migration in its file
+// name, e.g. 000005_seed_users+dev+test.up.sql is tagged with dev and test.
+const TagSeparator = "+"
+
+// RepeatablePrefix is the prefix of the file name of repeatable migrations.
+const RepeatablePrefix = "R__"
+
+// RepeatableRegex matches the following pattern:
+//
+//	R__name.ext
+var RepeatableRegex = regexp.MustCompile(`^` + RepeatablePrefix + `(.+)\.([^.]+)$`)
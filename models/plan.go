@@ -1,12 +1,19 @@
 package models
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
 
 const CurrentPlanVersion = 1
 
 var ErrInvalidPlanVersion = errors.New("invalid plan version")
 
 type Plan struct {
+	// ID is the unique identifier of the plan, it is used to group the plan runs
+	// in the journal.
+	ID string
 	// Version is the version of the plan.
 	Version int
 	// Auto is the mode of the plan. If true, the plan will rollback automatically in case of an error.
@@ -15,10 +22,14 @@ type Plan struct {
 	Migrations []*Migration
 	// RevertMigrations is the list of migrations to be applied in case of an error.
 	RevertMigrations []*Migration
+	// Rendered is true if the template variables have been rendered into the
+	// migrations when the plan was generated.
+	Rendered bool
 }
 
 func NewPlan(migrations, rollback []*Migration, auto bool) *Plan {
 	return &Plan{
+		ID:               uuid.New().String(),
 		Version:          CurrentPlanVersion,
 		Migrations:       migrations,
 		RevertMigrations: rollback,
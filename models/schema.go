@@ -0,0 +1,248 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// referencesRegex matches the referenced table of a foreign key definition.
+var referencesRegex = regexp.MustCompile(`(?i)REFERENCES\s+([^\s(]+)`)
+
+// Schema is a normalized description of the tables of a database. Two dumps of
+// the same schema are identical, regardless of the order the objects have been
+// created in.
+type Schema struct {
+	Tables []*Table
+}
+
+// Table describes a table along with its columns, indexes and constraints.
+type Table struct {
+	Name string
+	// Columns are kept in their ordinal position.
+	Columns     []*Column
+	Indexes     []*Index
+	Constraints []*Constraint
+}
+
+// Column describes a column of a table. The type is the one reported by the
+// database, therefore it is specific to the driver.
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// Default is the default value expression, empty if there is none.
+	Default string
+}
+
+// Index describes an index that is not backing a constraint.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// Constraint describes a table constraint. The type is one of PRIMARY KEY, UNIQUE,
+// FOREIGN KEY or CHECK and the definition is the constraint as it would appear
+// in a CREATE TABLE statement, e.g. FOREIGN KEY (user_id) REFERENCES users (id).
+type Constraint struct {
+	Name       string
+	Type       string
+	Definition string
+}
+
+// Normalize sorts the tables, indexes and constraints by name.
+func (s *Schema) Normalize() {
+	sort.Slice(s.Tables, func(i, j int) bool {
+		return s.Tables[i].Name < s.Tables[j].Name
+	})
+
+	for _, table := range s.Tables {
+		sort.Slice(table.Indexes, func(i, j int) bool {
+			return table.Indexes[i].Name < table.Indexes[j].Name
+		})
+		sort.Slice(table.Constraints, func(i, j int) bool {
+			if table.Constraints[i].Type != table.Constraints[j].Type {
+				return table.Constraints[i].Type < table.Constraints[j].Type
+			}
+			return table.Constraints[i].Name < table.Constraints[j].Name
+		})
+	}
+}
+
+// Table returns the table with the given name, or nil if it does not exist.
+func (s *Schema) Table(name string) *Table {
+	for _, table := range s.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+
+	return nil
+}
+
+// String renders the schema as SQL statements, one CREATE TABLE statement for
+// each table followed by its indexes.
+func (s *Schema) String() string {
+	var b strings.Builder
+	for i, table := range s.Tables {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(table.String())
+	}
+
+	return b.String()
+}
+
+// Script renders the schema as SQL statements that create it from scratch. Unlike
+// String, the tables are created after the tables they reference, and the columns
+// filled from a sequence are declared as serial columns.
+func (s *Schema) Script() string {
+	var b strings.Builder
+	for i, table := range s.createOrder() {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(table.render(true))
+	}
+
+	return b.String()
+}
+
+// DropScript renders the SQL statements that drop the tables of the schema, the
+// tables referencing others first.
+func (s *Schema) DropScript() string {
+	tables := s.createOrder()
+
+	var b strings.Builder
+	for i := len(tables) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "DROP TABLE IF EXISTS %s;\n", tables[i].Name)
+	}
+
+	return b.String()
+}
+
+// createOrder returns the tables ordered so that referenced tables come before
+// the tables referencing them. Tables referencing each other keep the order of
+// their names.
+func (s *Schema) createOrder() []*Table {
+	ordered := make([]*Table, 0, len(s.Tables))
+	created := make(map[string]bool, len(s.Tables))
+	remaining := append([]*Table{}, s.Tables...)
+
+	for len(remaining) > 0 {
+		next := 0
+		for i, table := range remaining {
+			ready := true
+			for _, referenced := range table.references() {
+				if !created[referenced] && referenced != table.Name && s.Table(referenced) != nil {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		ordered = append(ordered, remaining[next])
+		created[remaining[next].Name] = true
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+
+	return ordered
+}
+
+// references returns the names of the tables referenced by the foreign keys of
+// the table.
+func (t *Table) references() []string {
+	var tables []string
+	for _, constraint := range t.Constraints {
+		match := referencesRegex.FindStringSubmatch(constraint.Definition)
+		if match == nil {
+			continue
+		}
+
+		name := strings.Trim(match[1], "\"`")
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		tables = append(tables, name)
+	}
+
+	return tables
+}
+
+// String renders the table as a CREATE TABLE statement followed by its indexes.
+func (t *Table) String() string {
+	return t.render(false)
+}
+
+func (t *Table) render(serial bool) string {
+	lines := make([]string, 0, len(t.Columns)+len(t.Constraints))
+	for _, column := range t.Columns {
+		if serial {
+			lines = append(lines, column.serial().String())
+			continue
+		}
+		lines = append(lines, column.String())
+	}
+	for _, constraint := range t.Constraints {
+		lines = append(lines, constraint.String())
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n\t%s\n);\n", t.Name, strings.Join(lines, ",\n\t"))
+	for _, index := range t.Indexes {
+		unique := ""
+		if index.Unique {
+			unique = "UNIQUE "
+		}
+		fmt.Fprintf(&b, "CREATE %sINDEX %s ON %s (%s);\n", unique, index.Name, t.Name, strings.Join(index.Columns, ", "))
+	}
+
+	return b.String()
+}
+
+func (c *Column) String() string {
+	def := c.Name + " " + c.Type
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + c.Default
+	}
+
+	return def
+}
+
+// serial returns the column declared as a serial column if its default value is
+// taken from a sequence, as the sequence is not part of the schema.
+func (c *Column) serial() *Column {
+	if !strings.HasPrefix(strings.ToLower(c.Default), "nextval(") {
+		return c
+	}
+
+	serialTypes := map[string]string{
+		"smallint": "smallserial",
+		"integer":  "serial",
+		"bigint":   "bigserial",
+	}
+	serialType, ok := serialTypes[strings.ToLower(c.Type)]
+	if !ok {
+		return c
+	}
+
+	return &Column{Name: c.Name, Type: serialType, Nullable: c.Nullable}
+}
+
+func (c *Constraint) String() string {
+	if c.Name == "" {
+		return c.Definition
+	}
+
+	return "CONSTRAINT " + c.Name + " " + c.Definition
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaChangeKind tells how an object differs between two schemas.
+type SchemaChangeKind string
+
+const (
+	// ObjectAdded means the object only exists in the actual schema.
+	ObjectAdded SchemaChangeKind = "added"
+	// ObjectRemoved means the object only exists in the expected schema.
+	ObjectRemoved SchemaChangeKind = "removed"
+	// ObjectChanged means the object exists in both schemas with a different definition.
+	ObjectChanged SchemaChangeKind = "changed"
+)
+
+// SchemaChange describes an object that differs between the expected and the
+// actual schema. Object identifies it, e.g. "column users.email", and the
+// definitions are empty when the object does not exist on that side.
+type SchemaChange struct {
+	Kind     SchemaChangeKind
+	Object   string
+	Expected string
+	Actual   string
+}
+
+// SchemaDiff is the list of differences between two schemas.
+type SchemaDiff struct {
+	Changes []*SchemaChange
+}
+
+// DiffSchemas compares the actual schema against the expected one. Both schemas
+// are expected to be normalized.
+func DiffSchemas(expected, actual *Schema) *SchemaDiff {
+	diff := &SchemaDiff{}
+
+	for _, table := range expected.Tables {
+		other := actual.Table(table.Name)
+		if other == nil {
+			diff.add(ObjectRemoved, "table "+table.Name, table.String(), "")
+			continue
+		}
+
+		diff.compare("column "+table.Name+".", columnDefinitions(table), columnDefinitions(other))
+		diff.compare("constraint "+table.Name+".", constraintDefinitions(table), constraintDefinitions(other))
+		diff.compare("index "+table.Name+".", indexDefinitions(table), indexDefinitions(other))
+	}
+
+	for _, table := range actual.Tables {
+		if expected.Table(table.Name) == nil {
+			diff.add(ObjectAdded, "table "+table.Name, "", table.String())
+		}
+	}
+
+	return diff
+}
+
+// HasChanges reports whether the schemas differ.
+func (d *SchemaDiff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+// String renders one line per change, with the definitions on both sides for
+// changed objects.
+func (d *SchemaDiff) String() string {
+	var b strings.Builder
+	for _, change := range d.Changes {
+		switch change.Kind {
+		case ObjectChanged:
+			fmt.Fprintf(&b, "%s %s:\n\texpected: %s\n\tactual:   %s\n", change.Kind, change.Object, change.Expected, change.Actual)
+		default:
+			fmt.Fprintf(&b, "%s %s\n", change.Kind, change.Object)
+		}
+	}
+
+	return b.String()
+}
+
+func (d *SchemaDiff) add(kind SchemaChangeKind, object, expected, actual string) {
+	d.Changes = append(d.Changes, &SchemaChange{
+		Kind:     kind,
+		Object:   object,
+		Expected: expected,
+		Actual:   actual,
+	})
+}
+
+// compare records the differences between two sets of named definitions,
+// keeping the order of the expected set followed by the added objects.
+func (d *SchemaDiff) compare(prefix string, expected, actual []namedDefinition) {
+	actualByName := make(map[string]string, len(actual))
+	for _, def := range actual {
+		actualByName[def.name] = def.definition
+	}
+
+	expectedNames := make(map[string]bool, len(expected))
+	for _, def := range expected {
+		expectedNames[def.name] = true
+
+		other, ok := actualByName[def.name]
+		switch {
+		case !ok:
+			d.add(ObjectRemoved, prefix+def.name, def.definition, "")
+		case other != def.definition:
+			d.add(ObjectChanged, prefix+def.name, def.definition, other)
+		}
+	}
+
+	for _, def := range actual {
+		if !expectedNames[def.name] {
+			d.add(ObjectAdded, prefix+def.name, "", def.definition)
+		}
+	}
+}
+
+type namedDefinition struct {
+	name       string
+	definition string
+}
+
+func columnDefinitions(table *Table) []namedDefinition {
+	defs := make([]namedDefinition, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		defs = append(defs, namedDefinition{name: column.Name, definition: column.String()})
+	}
+
+	return defs
+}
+
+func constraintDefinitions(table *Table) []namedDefinition {
+	defs := make([]namedDefinition, 0, len(table.Constraints))
+	for _, constraint := range table.Constraints {
+		defs = append(defs, namedDefinition{name: constraint.Name, definition: constraint.String()})
+	}
+
+	return defs
+}
+
+func indexDefinitions(table *Table) []namedDefinition {
+	defs := make([]namedDefinition, 0, len(table.Indexes))
+	for _, index := range table.Indexes {
+		definition := "(" + strings.Join(index.Columns, ", ") + ")"
+		if index.Unique {
+			definition = "UNIQUE " + definition
+		}
+		defs = append(defs, namedDefinition{name: index.Name, definition: definition})
+	}
+
+	return defs
+}
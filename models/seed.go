@@ -0,0 +1,166 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SeedDataExt is the extension of the seed files that hold rows to upsert,
+// described by SeedData. Other seed files are SQL scripts.
+const SeedDataExt = ".json"
+
+// identifierRegex matches the table and column names of seed data, which may
+// be qualified with a schema.
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// NewSeed creates a seed from a file. The seed is named after the file name
+// without its extension, e.g. 01_roles for 01_roles.json.
+func NewSeed(seedBytes io.ReadCloser, fileName string) (*Migration, error) {
+	ext := filepath.Ext(fileName)
+	if ext != ".sql" && ext != SeedDataExt {
+		return nil, fmt.Errorf("could not parse seed file %s: the extension must be .sql or %s", fileName, SeedDataExt)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(seedBytes); err != nil {
+		return nil, err
+	}
+	defer seedBytes.Close()
+
+	return &Migration{
+		Name:      strings.TrimSuffix(fileName, ext),
+		RawName:   fileName,
+		Bytes:     buf.Bytes(),
+		Direction: Up,
+		Seed:      true,
+	}, nil
+}
+
+// IsSeedData reports whether the migration is a seed with rows to upsert
+// rather than an SQL script.
+func (m *Migration) IsSeedData() bool {
+	return m.Seed && filepath.Ext(m.RawName) == SeedDataExt
+}
+
+// SeedData holds the rows a seed upserts into a table, e.g.
+//
+//	{
+//	  "table": "roles",
+//	  "keys": ["id"],
+//	  "rows": [{"id": 1, "name": "admin"}, {"id": 2, "name": "user"}],
+//	  "environments": ["dev", "staging"]
+//	}
+type SeedData struct {
+	Table string `json:"table"`
+	// Keys are the columns that identify a row, an existing row with the same
+	// keys is updated instead of inserted.
+	Keys []string                 `json:"keys"`
+	Rows []map[string]interface{} `json:"rows"`
+	// Environments restricts the seed to the listed environments, as the env
+	// directive does for SQL seeds.
+	Environments []string `json:"environments,omitempty"`
+	// Tags restricts the seed to deployments with one of the tags active, as
+	// the tags directive does for SQL seeds.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// ParseSeedData decodes and validates the seed data of a seed. Every row must
+// have the same columns, including the keys.
+func ParseSeedData(seed *Migration) (*SeedData, error) {
+	decoder := json.NewDecoder(bytes.NewReader(seed.Bytes))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+
+	var data SeedData
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("could not decode seed %s: %w", seed.Name, err)
+	}
+
+	if !identifierRegex.MatchString(data.Table) {
+		return nil, fmt.Errorf("invalid table name %q in seed %s", data.Table, seed.Name)
+	}
+	if len(data.Keys) == 0 {
+		return nil, fmt.Errorf("seed %s has no keys", seed.Name)
+	}
+	if len(data.Rows) == 0 {
+		return nil, fmt.Errorf("seed %s has no rows", seed.Name)
+	}
+
+	columns := data.Columns()
+	for _, column := range columns {
+		if !identifierRegex.MatchString(column) {
+			return nil, fmt.Errorf("invalid column name %q in seed %s", column, seed.Name)
+		}
+	}
+
+	for i, row := range data.Rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("row %d of seed %s does not have the same columns as the first row", i+1, seed.Name)
+		}
+		for _, column := range columns {
+			if _, ok := row[column]; !ok {
+				return nil, fmt.Errorf("row %d of seed %s has no value for column %s", i+1, seed.Name, column)
+			}
+		}
+	}
+
+	return &data, nil
+}
+
+// Columns returns the keys followed by the other columns of the first row, in
+// alphabetical order.
+func (s *SeedData) Columns() []string {
+	columns := append([]string{}, s.Keys...)
+	if len(s.Rows) == 0 {
+		return columns
+	}
+
+	isKey := make(map[string]bool, len(s.Keys))
+	for _, key := range s.Keys {
+		isKey[key] = true
+	}
+
+	var others []string
+	for column := range s.Rows[0] {
+		if !isKey[column] {
+			others = append(others, column)
+		}
+	}
+	sort.Strings(others)
+
+	return append(columns, others...)
+}
+
+// Values returns the values of the rows in the order of the columns.
+func (s *SeedData) Values(columns []string) [][]interface{} {
+	values := make([][]interface{}, 0, len(s.Rows))
+	for _, row := range s.Rows {
+		v := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			v = append(v, row[column])
+		}
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// Header returns the directives equivalent to the environments and the tags of
+// the seed data.
+func (s *SeedData) Header() string {
+	var header string
+	if len(s.Environments) > 0 {
+		header += fmt.Sprintf("%s%s=%s\n", DirectivePrefix, DirectiveEnv, strings.Join(s.Environments, "|"))
+	}
+	if len(s.Tags) > 0 {
+		header += fmt.Sprintf("%s%s=%s\n", DirectivePrefix, DirectiveTags, strings.Join(s.Tags, "|"))
+	}
+
+	return header
+}
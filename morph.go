@@ -24,6 +24,10 @@ var (
 	migrationProgressStart    = "==  %s: migrating (%s)  ============================================="
 	migrationProgressFinished = "==  %s: migrated (%s)  ========================================"
 	migrationInterceptor      = "== %s: running pre-migration function =================================="
+	migrationBaseline         = "==  %s: marking as applied (baseline)  ========================================"
+	migrationSkipped          = "==  %s: skipped (%s)  ========================================"
+	migrationOutOfOrder       = "==  %s: out of order (sorts before %s)  ========================================"
+	migrationUnknownDirective = "==  %s: unknown directive %q ignored  ========================================"
 )
 
 const maxProgressLogLength = 100
@@ -32,21 +36,61 @@ type Morph struct {
 	config *Config
 	driver drivers.Driver
 	source sources.Source
+	seeds  sources.SeedSource
 	mutex  drivers.Locker
 
 	interceptorLock   sync.Mutex
-	intercecptorsUp   map[int]Interceptor
-	intercecptorsDown map[int]Interceptor
+	intercecptorsUp   map[uint64]Interceptor
+	intercecptorsDown map[uint64]Interceptor
 }
 
 type Config struct {
 	Logger  Logger
 	LockKey string
 	DryRun  bool
+	// TemplateVariables are used to render the migrations as text/template
+	// templates. The migrations are not rendered if it is nil and StrictTemplates
+	// is not set.
+	TemplateVariables map[string]string
+	// StrictTemplates makes rendering fail when a migration refers to a
+	// variable that is not defined.
+	StrictTemplates bool
+	// Environment is matched against the env directive of the migrations.
+	Environment string
+	// Tags are the active tags. Migrations with tags apply only if one of
+	// their tags is active.
+	Tags []string
+	// Confirm is asked before applying a migration with the confirm directive.
+	Confirm ConfirmFunc
+	// OutOfOrder decides what happens to the pending migrations with a lower
+	// version than the latest applied one. Defaults to OutOfOrderAllow.
+	OutOfOrder OutOfOrderPolicy
+	// ResumePlan allows ApplyPlan to resume a plan that has been partially
+	// applied by a previous run.
+	ResumePlan bool
 }
 
 type EngineOption func(*Morph) error
 
+// OutOfOrderPolicy decides what happens to a pending migration that sorts before
+// an already applied one, e.g. when two branches add migrations and the one with
+// the lower version is deployed last.
+type OutOfOrderPolicy string
+
+const (
+	// OutOfOrderStrict fails before applying anything.
+	OutOfOrderStrict OutOfOrderPolicy = "strict"
+	// OutOfOrderAllow applies the migration with a warning. Rolling back follows
+	// the order the migrations were applied in, if the driver records it.
+	OutOfOrderAllow OutOfOrderPolicy = "allow"
+	// OutOfOrderIgnore leaves the migration pending without applying it.
+	OutOfOrderIgnore OutOfOrderPolicy = "ignore"
+)
+
+// ConfirmFunc is called before applying a migration that requires a manual
+// confirmation. The migration is applied only if it returns true.
+type ConfirmFunc func(migration *models.Migration) (bool, error)
+
 // Interceptor is a handler function that being called just before the migration
 // applied. If the interceptor returns an error, migration will be aborted.
 type Interceptor func() error
@@ -64,6 +108,23 @@ func SetMigrationTableName(name string) EngineOption {
 	}
 }
 
+// SetMetadataSchema sets the schema, or the database on MySQL, of the tables
+// morph keeps its state in: the migrations table, the tables named after it
+// and the mutex table. The schema must exist.
+func SetMetadataSchema(schema string) EngineOption {
+	return func(m *Morph) error {
+		return m.driver.SetConfig("MetadataSchema", schema)
+	}
+}
+
+// SetMutexTableName sets the name of the table that stores the mutex created
+// with WithLock.
+func SetMutexTableName(name string) EngineOption {
+	return func(m *Morph) error {
+		return m.driver.SetConfig("MutexTable", name)
+	}
+}
+
 func SetStatementTimeoutInSeconds(n int) EngineOption {
 	return func(m *Morph) error {
 		return m.driver.SetConfig("StatementTimeoutInSecs", n)
@@ -89,6 +150,93 @@ func SetDryRun(enable bool) EngineOption {
 	}
 }
 
+// WithTemplateVariables enables rendering the migrations as text/template
+// templates before they are applied, e.g. {{.tablespace}} is replaced with the
+// value of the tablespace variable. It can be used multiple times, later values
+// override the earlier ones.
+func WithTemplateVariables(vars map[string]string) EngineOption {
+	return func(m *Morph) error {
+		if m.config.TemplateVariables == nil {
+			m.config.TemplateVariables = make(map[string]string, len(vars))
+		}
+		for k, v := range vars {
+			m.config.TemplateVariables[k] = v
+		}
+		return nil
+	}
+}
+
+// SetStrictTemplates makes rendering the migrations fail if a migration refers
+// to an undefined template variable. Otherwise undefined variables are rendered
+// as empty strings. Enabling it also enables rendering.
+func SetStrictTemplates(enable bool) EngineOption {
+	return func(m *Morph) error {
+		m.config.StrictTemplates = enable
+		return nil
+	}
+}
+
+// SetEnvironment sets the environment the engine runs in. Migrations that are
+// restricted to other environments with the env directive are recorded as
+// applied without being executed, as are all of them if no environment is set.
+func SetEnvironment(env string) EngineOption {
+	return func(m *Morph) error {
+		m.config.Environment = env
+		return nil
+	}
+}
+
+// WithTags sets the active tags. Migrations tagged in their file name, e.g.
+// 000005_seed_users+dev.up.sql, or with the tags directive apply only if one
+// of their tags is active, otherwise they are recorded as applied without
+// running, along with the reason.
+func WithTags(tags ...string) EngineOption {
+	return func(m *Morph) error {
+		m.config.Tags = append(m.config.Tags, tags...)
+		return nil
+	}
+}
+
+// WithConfirmation sets the function that confirms the migrations with the
+// confirm directive. Without it, these migrations cannot be applied.
+func WithConfirmation(fn ConfirmFunc) EngineOption {
+	return func(m *Morph) error {
+		m.config.Confirm = fn
+		return nil
+	}
+}
+
+// SetOutOfOrderPolicy sets what happens to the pending migrations that have a
+// lower version than the latest applied migration.
+func SetOutOfOrderPolicy(policy OutOfOrderPolicy) EngineOption {
+	return func(m *Morph) error {
+		switch policy {
+		case OutOfOrderStrict, OutOfOrderAllow, OutOfOrderIgnore:
+			m.config.OutOfOrder = policy
+			return nil
+		default:
+			return fmt.Errorf("unknown out of order policy %q", policy)
+		}
+	}
+}
+
+// SetPlanResume allows ApplyPlan to resume a plan that stopped halfway from its
+// first incomplete step. Otherwise applying a partially applied plan fails.
+func SetPlanResume(enable bool) EngineOption {
+	return func(m *Morph) error {
+		m.config.ResumePlan = enable
+		return nil
+	}
+}
+
+// WithSeeds sets the source of the seeds applied by Seed.
+func WithSeeds(source sources.SeedSource) EngineOption {
+	return func(m *Morph) error {
+		m.seeds = source
+		return nil
+	}
+}
+
 // New creates a new instance of the migrations engine from an existing db instance and a migrations source.
 // If the driver implements the Lockable interface, it will also wait until it has acquired a lock.
 // The context is propagated to the drivers lock method (if the driver implements divers.Locker interface) and
@@ -96,12 +244,13 @@ func SetDryRun(enable bool) EngineOption {
 func New(ctx context.Context, driver drivers.Driver, source sources.Source, options ...EngineOption) (*Morph, error) {
 	engine := &Morph{
 		config: &Config{
-			Logger: newColorLogger(log.New(os.Stderr, "", log.LstdFlags)), // add default logger
+			Logger:     newColorLogger(log.New(os.Stderr, "", log.LstdFlags)), // add default logger
+			OutOfOrder: OutOfOrderAllow,
 		},
 		source:            source,
 		driver:            driver,
-		intercecptorsUp:   make(map[int]Interceptor),
-		intercecptorsDown: make(map[int]Interceptor),
+		intercecptorsUp:   make(map[uint64]Interceptor),
+		intercecptorsDown: make(map[uint64]Interceptor),
 	}
 
 	for _, option := range options {
@@ -110,6 +259,22 @@ func New(ctx context.Context, driver drivers.Driver, source sources.Source, opti
 		}
 	}
 
+	if engine.templatingEnabled() {
+		src, err := engine.renderSource(source)
+		if err != nil {
+			return nil, err
+		}
+		engine.source = src
+
+		if engine.seeds != nil {
+			seeds, err := engine.renderMigrations(engine.seeds.Seeds())
+			if err != nil {
+				return nil, err
+			}
+			engine.seeds = &renderedSource{seeds: seeds}
+		}
+	}
+
 	if err := driver.Ping(); err != nil {
 		return nil, err
 	}
@@ -146,6 +311,41 @@ func (m *Morph) apply(migration *models.Migration, saveVersion, dryRun bool) err
 	start := time.Now()
 	migrationName := migration.Name
 	direction := migration.Direction
+
+	directives, err := migration.Directives()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range directives.Unknown {
+		m.config.Logger.Println(formatProgress(fmt.Sprintf(migrationUnknownDirective, migrationName, entry)))
+	}
+
+	reason := migration.SkipReason
+	if reason == "" {
+		if reason, err = m.skipReason(migration, directives); err != nil {
+			return err
+		}
+	}
+
+	if reason != "" {
+		m.config.Logger.Println(formatProgress(fmt.Sprintf(migrationSkipped, migrationName, reason)))
+		if dryRun {
+			return nil
+		}
+
+		record := *migration
+		record.RecordOnly = true
+		record.SkipReason = reason
+		return m.driver.Apply(&record, saveVersion)
+	}
+
+	if directives.Confirm && !dryRun {
+		if err := m.confirm(migration); err != nil {
+			return err
+		}
+	}
+
 	f := m.getInterceptor(migration)
 	if f != nil {
 		m.config.Logger.Println(formatProgress(fmt.Sprintf(migrationInterceptor, migrationName)))
@@ -167,64 +367,289 @@ func (m *Morph) apply(migration *models.Migration, saveVersion, dryRun bool) err
 	return nil
 }
 
+// skipReason tells why the migration does not apply to the deployment, or
+// returns an empty string if it does.
+func (m *Morph) skipReason(migration *models.Migration, directives *models.Directives) (string, error) {
+	if !directives.RunsIn(m.config.Environment) {
+		if m.config.Environment == "" {
+			return fmt.Sprintf("no environment is set and it only runs in %s", strings.Join(directives.Environments, ", ")), nil
+		}
+
+		return fmt.Sprintf("environment %s is not one of %s", m.config.Environment, strings.Join(directives.Environments, ", ")), nil
+	}
+
+	tags, err := migration.Tags()
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	for _, tag := range tags {
+		for _, active := range m.config.Tags {
+			if tag == active {
+				return "", nil
+			}
+		}
+	}
+
+	return fmt.Sprintf("none of the tags %s is active", strings.Join(tags, ", ")), nil
+}
+
+// isSkipped reports whether the migration would be recorded without running.
+func (m *Morph) isSkipped(migration *models.Migration) (bool, error) {
+	if migration.SkipReason != "" {
+		return true, nil
+	}
+
+	directives, err := migration.Directives()
+	if err != nil {
+		return false, err
+	}
+
+	reason, err := m.skipReason(migration, directives)
+	return reason != "", err
+}
+
+func (m *Morph) confirm(migration *models.Migration) error {
+	if m.config.Confirm == nil {
+		return fmt.Errorf("migration %s requires a confirmation", migration.Name)
+	}
+
+	ok, err := m.config.Confirm(migration)
+	if err != nil {
+		return fmt.Errorf("could not confirm migration %s: %w", migration.Name, err)
+	}
+	if !ok {
+		return fmt.Errorf("migration %s has not been confirmed", migration.Name)
+	}
+
+	return nil
+}
+
 // ApplyAll applies all pending migrations.
 func (m *Morph) ApplyAll() error {
 	_, err := m.Apply(-1)
 	return err
 }
 
-// Applies limited number of migrations upwards.
+// Applies limited number of migrations upwards. Once there are no pending
+// versioned migrations left, the changed repeatable migrations are applied as
+// well and counted in the result.
 func (m *Morph) Apply(limit int) (int, error) {
-	appliedMigrations, err := m.driver.AppliedMigrations()
+	set, err := m.loadMigrations()
+	if err != nil {
+		return -1, err
+	}
+	appliedMigrations := set.applied
+
+	pendingMigrations, err := computePendingMigrations(appliedMigrations, set.source)
 	if err != nil {
 		return -1, err
 	}
 
-	pendingMigrations, err := computePendingMigrations(appliedMigrations, m.source.Migrations())
+	graph, err := m.dependencyGraph(set)
+	if err != nil {
+		return -1, err
+	}
+
+	pendingMigrations, err = m.checkOutOfOrder(graph, appliedMigrations, pendingMigrations)
 	if err != nil {
 		return -1, err
 	}
 
 	migrations := make([]*models.Migration, 0)
-	sortedMigrations := sortMigrations(pendingMigrations)
+	sortedMigrations := graph.sort(pendingMigrations)
 
+	// skipped migrations are recorded on the way, but they are not counted
+	var available int
+	skipped := make(map[string]bool)
 	for _, migration := range sortedMigrations {
 		if migration.Direction != models.Up {
 			continue
 		}
 		migrations = append(migrations, migration)
+
+		isSkipped, err := m.isSkipped(migration)
+		if err != nil {
+			return -1, err
+		}
+		if isSkipped {
+			skipped[migration.Name] = true
+		} else {
+			available++
+		}
 	}
 
 	steps := limit
-	if len(migrations) < steps {
-		return -1, fmt.Errorf("there are only %d migrations available, but you requested %d", len(migrations), steps)
+	if available < steps {
+		return -1, fmt.Errorf("there are only %d migrations available, but you requested %d", available, steps)
 	}
 
 	if limit < 0 {
-		steps = len(migrations)
+		steps = available
+	}
+
+	isApplied := make(map[string]bool, len(appliedMigrations)+len(migrations))
+	for _, migration := range appliedMigrations {
+		isApplied[migration.Name] = true
 	}
 
 	var applied int
-	for i := 0; i < steps; i++ {
-		if err := m.apply(migrations[i], true, m.config.DryRun); err != nil {
+	for _, migration := range migrations {
+		// a limited run stops at the last requested migration, the skipped
+		// ones following it are recorded by the next run
+		if applied == steps && limit >= 0 {
+			break
+		}
+
+		if err := checkDependencies(graph, migration, isApplied); err != nil {
+			return applied, err
+		}
+
+		if err := m.apply(migration, true, m.config.DryRun); err != nil {
 			return applied, err
 		}
+		isApplied[migration.Name] = true
+		if !skipped[migration.Name] {
+			applied++
+		}
+	}
+
+	if steps < available {
+		return applied, nil
+	}
+
+	n, err := m.ApplyRepeatables()
+	if n > 0 {
+		applied += n
+	}
+
+	return applied, err
+}
+
+// ApplyRepeatables applies the repeatable migrations of the source that have
+// not been applied yet or whose checksum has changed since they were last
+// applied, in the order of their file names. The source has to implement the
+// sources.RepeatableSource interface and the driver the drivers.RepeatableTracker
+// interface.
+func (m *Morph) ApplyRepeatables() (int, error) {
+	pending, err := m.PendingRepeatables()
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	tracker, ok := m.driver.(drivers.RepeatableTracker)
+	if !ok {
+		return -1, errors.New("driver does not support repeatable migrations")
+	}
+
+	var applied int
+	for _, migration := range pending {
+		if err := m.apply(migration, false, m.config.DryRun); err != nil {
+			return applied, err
+		}
+
+		if !m.config.DryRun {
+			if err := tracker.SaveRepeatable(migration.Name, migration.Checksum()); err != nil {
+				return applied, err
+			}
+		}
 		applied++
 	}
 
 	return applied, nil
 }
 
+// PendingRepeatables returns the repeatable migrations that would be applied by
+// ApplyRepeatables.
+func (m *Morph) PendingRepeatables() ([]*models.Migration, error) {
+	source, ok := m.source.(sources.RepeatableSource)
+	if !ok || len(source.Repeatables()) == 0 {
+		return nil, nil
+	}
+
+	tracker, ok := m.driver.(drivers.RepeatableTracker)
+	if !ok {
+		return nil, errors.New("driver does not support repeatable migrations")
+	}
+
+	checksums, err := tracker.AppliedRepeatables()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*models.Migration
+	for _, migration := range source.Repeatables() {
+		if checksums[migration.Name] != migration.Checksum() {
+			pending = append(pending, migration)
+		}
+	}
+
+	return sortMigrations(pending), nil
+}
+
+// Baseline records every migration of the source up to and including the given
+// version as applied, without executing them. It is meant to be used when adopting
+// morph on a database that already has the schema these migrations would create.
+func (m *Morph) Baseline(version uint64) (int, error) {
+	set, err := m.loadMigrations()
+	if err != nil {
+		return -1, err
+	}
+
+	var found bool
+	for _, migration := range set.source {
+		if migration.Version == version {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return -1, fmt.Errorf("there is no migration with version %d in the source", version)
+	}
+
+	pendingMigrations, err := computePendingMigrations(set.applied, set.source)
+	if err != nil {
+		return -1, err
+	}
+
+	var recorded int
+	for _, migration := range sortMigrations(pendingMigrations) {
+		if migration.Direction != models.Up || migration.Version > version {
+			continue
+		}
+
+		m.config.Logger.Println(formatProgress(fmt.Sprintf(migrationBaseline, migration.Name)))
+		if !m.config.DryRun {
+			record := *migration
+			record.RecordOnly = true
+			if err := m.driver.Apply(&record, true); err != nil {
+				return recorded, err
+			}
+		}
+		recorded++
+	}
+
+	return recorded, nil
+}
+
 // ApplyDown rollbacks a limited number of migrations
 // if limit is given below zero, all down scripts are going to be applied.
 func (m *Morph) ApplyDown(limit int) (int, error) {
-	appliedMigrations, err := m.driver.AppliedMigrations()
+	set, err := m.loadMigrations()
 	if err != nil {
 		return -1, err
 	}
 
-	sortedMigrations := reverseSortMigrations(appliedMigrations)
-	downMigrations, err := findDownScripts(sortedMigrations, m.source.Migrations())
+	graph, err := m.dependencyGraph(set)
+	if err != nil {
+		return -1, err
+	}
+
+	sortedMigrations := graph.reverseSort(set.applied)
+	downMigrations, err := findDownScripts(sortedMigrations, set.source)
 	if err != nil {
 		return -1, err
 	}
@@ -238,10 +663,12 @@ func (m *Morph) ApplyDown(limit int) (int, error) {
 		steps = len(sortedMigrations)
 	}
 
+	// the dependents of a migration always sort before it, so they are rolled
+	// back first
 	var applied int
 	for i := 0; i < steps; i++ {
 		migrationName := sortedMigrations[i].Name
-		if err := m.apply(downMigrations[migrationName], true, m.config.DryRun); err != nil {
+		if err := m.apply(skippedDown(sortedMigrations[i], downMigrations[migrationName]), true, m.config.DryRun); err != nil {
 			return applied, err
 		}
 		applied++
@@ -250,38 +677,112 @@ func (m *Morph) ApplyDown(limit int) (int, error) {
 	return applied, nil
 }
 
+// Redo rolls back the last n applied migrations and applies them again. As the
+// engine holds the lock from its creation until it is closed, no other instance
+// can run migrations in between.
+func (m *Morph) Redo(n int) (int, error) {
+	if n <= 0 {
+		return -1, fmt.Errorf("the number of migrations to redo must be greater than zero, got %d", n)
+	}
+
+	set, err := m.loadMigrations()
+	if err != nil {
+		return -1, err
+	}
+
+	graph, err := m.dependencyGraph(set)
+	if err != nil {
+		return -1, err
+	}
+
+	sortedMigrations := graph.reverseSort(set.applied)
+	if len(sortedMigrations) < n {
+		return -1, fmt.Errorf("there are only %d migrations applied, but you requested %d", len(sortedMigrations), n)
+	}
+
+	upMigrations := make(map[string]*models.Migration)
+	for _, migration := range set.source {
+		if migration.Direction == models.Up {
+			upMigrations[migration.Name] = migration
+		}
+	}
+
+	redoMigrations := make([]*models.Migration, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		migration, ok := upMigrations[sortedMigrations[i].Name]
+		if !ok {
+			return -1, fmt.Errorf("could not find up script for %s", sortedMigrations[i].Name)
+		}
+		redoMigrations = append(redoMigrations, migration)
+	}
+
+	rolledBack, err := m.ApplyDown(n)
+	if err != nil {
+		return 0, fmt.Errorf("redo stopped while rolling back: %d of %d migrations have been rolled back and are not re-applied: %w", rolledBack, n, err)
+	}
+
+	var reapplied int
+	for _, migration := range redoMigrations {
+		if err := m.apply(migration, true, m.config.DryRun); err != nil {
+			return reapplied, fmt.Errorf("redo stopped while re-applying %s: %d of %d migrations have been re-applied, %d are still rolled back: %w", migration.Name, reapplied, n, n-reapplied, err)
+		}
+		reapplied++
+	}
+
+	return reapplied, nil
+}
+
 // Diff returns the difference between the applied migrations and the available migrations.
 func (m *Morph) Diff(mode models.Direction) ([]*models.Migration, error) {
-	appliedMigrations, err := m.driver.AppliedMigrations()
+	set, err := m.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	appliedMigrations := set.applied
+
+	graph, err := m.dependencyGraph(set)
 	if err != nil {
 		return nil, err
 	}
 
 	if mode == models.Down {
-		sortedMigrations := reverseSortMigrations(appliedMigrations)
-		downMigrations, err := findDownScripts(sortedMigrations, m.source.Migrations())
+		sortedMigrations := graph.reverseSort(appliedMigrations)
+		downMigrations, err := findDownScripts(sortedMigrations, set.source)
 		if err != nil {
 			return nil, err
 		}
 
 		diff := make([]*models.Migration, 0, len(downMigrations))
 		for i := 0; i < len(sortedMigrations); i++ {
-			diff = append(diff, downMigrations[sortedMigrations[i].Name])
+			diff = append(diff, skippedDown(sortedMigrations[i], downMigrations[sortedMigrations[i].Name]))
 		}
 
 		return diff, nil
 	}
 
-	pendingMigrations, err := computePendingMigrations(appliedMigrations, m.source.Migrations())
+	pendingMigrations, err := computePendingMigrations(appliedMigrations, set.source)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingMigrations, err = m.checkOutOfOrder(graph, appliedMigrations, pendingMigrations)
 	if err != nil {
 		return nil, err
 	}
 
 	var diff []*models.Migration
-	for _, migration := range sortMigrations(pendingMigrations) {
+	for _, migration := range graph.sort(pendingMigrations) {
 		if migration.Direction != models.Up {
 			continue
 		}
+
+		skipped, err := m.isSkipped(migration)
+		if err != nil {
+			return nil, err
+		}
+		if skipped {
+			continue
+		}
 		diff = append(diff, migration)
 	}
 
@@ -338,21 +839,69 @@ func (m *Morph) GeneratePlan(migrations []*models.Migration, auto bool) (*models
 	}
 
 	plan := models.NewPlan(migrations, rollbackMigrations, auto)
+	plan.Rendered = m.templatingEnabled()
 
 	return plan, nil
 }
 
+// ApplyPlan applies the migrations of the plan in order. The plan execution is
+// idempotent: the steps whose effect is already reflected in the migrations
+// table are skipped. A plan that previously stopped halfway is resumed from the
+// first incomplete step if SetPlanResume is enabled, otherwise an error is
+// returned without applying anything.
 func (m *Morph) ApplyPlan(plan *models.Plan) error {
 	if err := plan.Validate(); err != nil {
 		return fmt.Errorf("invalid plan: %w", err)
 	}
 
+	plan, err := m.renderPlan(plan)
+	if err != nil {
+		return err
+	}
+
+	set, err := m.loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied := newAppliedSteps(set.applied)
+	start := applied.progress(plan)
+
+	if start == len(plan.Migrations) && start > 0 {
+		m.config.Logger.Println("all steps of the plan have already been applied")
+		return nil
+	} else if start > 0 {
+		if !m.config.ResumePlan {
+			return fmt.Errorf("the plan has been partially applied, previous run stopped at step %d (%s)", start+1, plan.Migrations[start].Name)
+		}
+		m.config.Logger.Printf("previous run of the plan stopped at step %d (%s), resuming from there", start+1, plan.Migrations[start].Name)
+	}
+
+	graph, err := m.dependencyGraph(set)
+	if err != nil {
+		return err
+	}
+
+	isApplied := make(map[string]bool, len(set.applied))
+	for _, migration := range set.applied {
+		isApplied[migration.Name] = true
+	}
+
+	recorder := m.newPlanRecorder(plan)
 	revertMigrations := make([]*models.Migration, 0, len(plan.RevertMigrations))
-	var err error
 	var failIndex int
 
 	for i := range plan.Migrations {
-		// add to the revert queue
+		done := applied.contains(plan.Migrations[i])
+		if !done {
+			failIndex = i
+			if err = checkDependencies(graph, plan.Migrations[i], isApplied); err != nil {
+				break
+			}
+		}
+
+		// add to the revert queue, including the steps applied by a previous
+		// run, so that an auto plan is rolled back as a whole
 		for _, migration := range plan.RevertMigrations {
 			if migration.Name == plan.Migrations[i].Name && migration.Version == plan.Migrations[i].Version {
 				revertMigrations = append(revertMigrations, migration)
@@ -360,12 +909,20 @@ func (m *Morph) ApplyPlan(plan *models.Plan) error {
 			}
 		}
 
+		if done {
+			m.config.Logger.Printf("step %d (%s) has already been applied, skipping", i+1, plan.Migrations[i].Name)
+			recorder.skip(plan.Migrations[i])
+			continue
+		}
+
+		step := recorder.start(plan.Migrations[i], false)
 		err = m.apply(plan.Migrations[i], true, m.config.DryRun)
+		recorder.finish(step, err)
 		if err != nil {
 			break
 		}
-
-		failIndex = i
+		isApplied[plan.Migrations[i].Name] = plan.Migrations[i].Direction == models.Up
+		applied.record(plan.Migrations[i])
 	}
 
 	if err == nil {
@@ -373,6 +930,7 @@ func (m *Morph) ApplyPlan(plan *models.Plan) error {
 	}
 
 	if !plan.Auto {
+		m.config.Logger.Printf("plan stopped at step %d (%s), run the plan again to resume from this step", failIndex+1, plan.Migrations[failIndex].Name)
 		return err
 	}
 
@@ -390,7 +948,9 @@ func (m *Morph) ApplyPlan(plan *models.Plan) error {
 		// So in this case, we need to apply the migration_2 (up) but it will be in the migrations table.
 		// Therefore we are not saving the version in the database because it will fail on the save version step.
 		skipSave := revertMigrations[j].Direction == models.Up && j == len(revertMigrations)-1
+		step := recorder.start(revertMigrations[j], true)
 		rErr := m.apply(revertMigrations[j], !skipSave, m.config.DryRun)
+		recorder.finish(step, rErr)
 		if rErr != nil {
 			return fmt.Errorf("could not rollback migrations after trying to migrate: %w", rErr)
 		}
@@ -402,8 +962,184 @@ func (m *Morph) ApplyPlan(plan *models.Plan) error {
 	return fmt.Errorf("could not apply migration: %w", err)
 }
 
+// PlanProgress returns the index of the first step of the plan that has not been
+// applied to the database yet. If all steps are applied, the length of the plan
+// migrations is returned.
+func (m *Morph) PlanProgress(plan *models.Plan) (int, error) {
+	appliedMigrations, err := m.driver.AppliedMigrations()
+	if err != nil {
+		return -1, err
+	}
+
+	return newAppliedSteps(appliedMigrations).progress(plan), nil
+}
+
+// appliedSteps is the set of the applied migrations, by version and name, as
+// two migrations may share a name across directories or after a squash.
+type appliedSteps map[appliedStep]bool
+
+type appliedStep struct {
+	version uint64
+	name    string
+}
+
+func newAppliedSteps(appliedMigrations []*models.Migration) appliedSteps {
+	applied := make(appliedSteps, len(appliedMigrations))
+	for _, migration := range appliedMigrations {
+		applied[appliedStep{version: migration.Version, name: migration.Name}] = true
+	}
+
+	return applied
+}
+
+// contains checks whether the effect of the given plan step is already
+// reflected in the migrations table. An up migration is done once it has been
+// saved, a down migration once it has been removed.
+func (a appliedSteps) contains(migration *models.Migration) bool {
+	found := a[appliedStep{version: migration.Version, name: migration.Name}]
+	if migration.Direction == models.Down {
+		return !found
+	}
+
+	return found
+}
+
+// record updates the set after the given plan step has been applied.
+func (a appliedSteps) record(migration *models.Migration) {
+	key := appliedStep{version: migration.Version, name: migration.Name}
+	if migration.Direction == models.Down {
+		delete(a, key)
+		return
+	}
+
+	a[key] = true
+}
+
+// progress returns the index of the first step of the plan that has not been
+// applied, or the length of the plan migrations if all steps are applied.
+func (a appliedSteps) progress(plan *models.Plan) int {
+	for i := range plan.Migrations {
+		if !a.contains(plan.Migrations[i]) {
+			return i
+		}
+	}
+
+	return len(plan.Migrations)
+}
+
+// PlanRuns returns the last limit plan runs from the journal, the most recent
+// run first. The driver has to implement the drivers.Journal interface.
+func (m *Morph) PlanRuns(limit int) ([]*models.PlanRun, error) {
+	journal, ok := m.driver.(drivers.Journal)
+	if !ok {
+		return nil, errors.New("driver does not support plan journal")
+	}
+
+	return journal.PlanRuns(limit)
+}
+
+// DumpSchema returns a normalized description of the database schema. The
+// driver has to implement the drivers.SchemaDumper interface.
+func (m *Morph) DumpSchema() (*models.Schema, error) {
+	dumper, ok := m.driver.(drivers.SchemaDumper)
+	if !ok {
+		return nil, errors.New("driver does not support schema dumps")
+	}
+
+	return dumper.DumpSchema()
+}
+
+// CheckDrift compares the schema of the database with the schema obtained by
+// applying the same migrations to a scratch database. Only the migrations that
+// have been applied to the database are replayed, so pending migrations are not
+// reported as drift. The driver has to implement both the drivers.SchemaDumper
+// and the drivers.Scratcher interfaces.
+func (m *Morph) CheckDrift() (*models.SchemaDiff, error) {
+	dumper, ok := m.driver.(drivers.SchemaDumper)
+	if !ok {
+		return nil, errors.New("driver does not support schema dumps")
+	}
+
+	scratcher, ok := m.driver.(drivers.Scratcher)
+	if !ok {
+		return nil, errors.New("driver does not support scratch databases")
+	}
+
+	set, err := m.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]*models.Migration, len(set.applied))
+	for _, migration := range set.applied {
+		applied[migration.Name] = migration
+	}
+
+	var migrations []*models.Migration
+	for _, migration := range set.source {
+		record, ok := applied[migration.Name]
+		if migration.Direction != models.Up || !ok {
+			continue
+		}
+
+		// skipped migrations have been recorded without running
+		if record.SkipReason != "" {
+			continue
+		}
+
+		directives, err := migration.Directives()
+		if err != nil {
+			return nil, err
+		}
+		if directives.RunsIn(m.config.Environment) {
+			migrations = append(migrations, migration)
+		}
+	}
+
+	graph, err := m.dependencyGraph(set)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch, err := scratcher.Scratch()
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.Close()
+
+	migrations = graph.sort(migrations)
+	// a database past the squash point is expected to match the squashed migration
+	if set.squashImplied {
+		migrations = append([]*models.Migration{set.squash}, migrations...)
+	}
+
+	m.config.Logger.Printf("replaying %d migrations on a scratch database", len(migrations))
+	for _, migration := range migrations {
+		if err := scratch.Apply(migration, false); err != nil {
+			return nil, fmt.Errorf("could not apply migration %s to the scratch database: %w", migration.Name, err)
+		}
+	}
+
+	scratchDumper, ok := scratch.(drivers.SchemaDumper)
+	if !ok {
+		return nil, errors.New("scratch driver does not support schema dumps")
+	}
+
+	expected, err := scratchDumper.DumpSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	actual, err := dumper.DumpSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DiffSchemas(expected, actual), nil
+}
+
 // AddInterceptor registers a handler function to be executed before the actual migration
-func (m *Morph) AddInterceptor(version int, direction models.Direction, handler Interceptor) {
+func (m *Morph) AddInterceptor(version uint64, direction models.Direction, handler Interceptor) {
 	m.interceptorLock.Lock()
 	switch direction {
 	case models.Up:
@@ -415,7 +1151,7 @@ func (m *Morph) AddInterceptor(version int, direction models.Direction, handler
 }
 
 // RemoveInterceptor removes the handler function from the engine
-func (m *Morph) RemoveInterceptor(version int, direction models.Direction) {
+func (m *Morph) RemoveInterceptor(version uint64, direction models.Direction) {
 	m.interceptorLock.Lock()
 	switch direction {
 	case models.Up:
@@ -427,16 +1163,22 @@ func (m *Morph) RemoveInterceptor(version int, direction models.Direction) {
 }
 
 func (m *Morph) getInterceptor(migration *models.Migration) Interceptor {
+	// repeatable migrations and seeds have no version to register an
+	// interceptor for
+	if migration.Repeatable || migration.Seed {
+		return nil
+	}
+
 	m.interceptorLock.Lock()
 	var f Interceptor
 	switch migration.Direction {
 	case models.Up:
-		fn, ok := m.intercecptorsUp[int(migration.Version)]
+		fn, ok := m.intercecptorsUp[migration.Version]
 		if ok {
 			f = fn
 		}
 	case models.Down:
-		fn, ok := m.intercecptorsDown[int(migration.Version)]
+		fn, ok := m.intercecptorsDown[migration.Version]
 		if ok {
 			f = fn
 		}
@@ -445,6 +1187,21 @@ func (m *Morph) getInterceptor(migration *models.Migration) Interceptor {
 	return f
 }
 
+// skippedDown returns the down migration to roll back an applied migration. If
+// the migration has been skipped, there is nothing to roll back, so only its
+// record is removed.
+func skippedDown(applied, down *models.Migration) *models.Migration {
+	if applied.SkipReason == "" || down == nil {
+		return down
+	}
+
+	record := *down
+	record.RecordOnly = true
+	record.SkipReason = applied.SkipReason
+
+	return &record
+}
+
 // SwapPlanDirection alters the plan direction to the opposite direction.
 func SwapPlanDirection(plan *models.Plan) {
 	// we need to ensure that the intended migrations for applying is in the
@@ -495,6 +1252,42 @@ func computePendingMigrations(appliedMigrations []*models.Migration, sourceMigra
 	return pendingMigrations, nil
 }
 
+// checkOutOfOrder applies the out of order policy to the pending migrations and
+// returns the ones that can be applied.
+func (m *Morph) checkOutOfOrder(graph *dependencyGraph, appliedMigrations, pendingMigrations []*models.Migration) ([]*models.Migration, error) {
+	outOfOrder, latest := graph.outOfOrder(appliedMigrations, pendingMigrations)
+	if len(outOfOrder) == 0 {
+		return pendingMigrations, nil
+	}
+
+	switch m.config.OutOfOrder {
+	case OutOfOrderStrict:
+		names := make([]string, 0, len(outOfOrder))
+		for _, migration := range outOfOrder {
+			names = append(names, migration.Name)
+		}
+		return nil, fmt.Errorf("migrations %s are out of order, they sort before the applied migration %s", strings.Join(names, ", "), latest.Name)
+	case OutOfOrderIgnore:
+		ignored := make(map[string]bool, len(outOfOrder))
+		for _, migration := range outOfOrder {
+			ignored[migration.Name] = true
+		}
+
+		migrations := make([]*models.Migration, 0, len(pendingMigrations))
+		for _, migration := range pendingMigrations {
+			if !ignored[migration.Name] {
+				migrations = append(migrations, migration)
+			}
+		}
+		return migrations, nil
+	default:
+		for _, migration := range outOfOrder {
+			m.config.Logger.Println(formatProgress(fmt.Sprintf(migrationOutOfOrder, migration.Name, latest.Name)))
+		}
+		return pendingMigrations, nil
+	}
+}
+
 func findDownScripts(appliedMigrations []*models.Migration, sourceMigrations []*models.Migration) (map[string]*models.Migration, error) {
 	tmp := make(map[string]*models.Migration)
 	for _, m := range sourceMigrations {
@@ -4,10 +4,19 @@
 package morph
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/mattermost/morph/drivers/memory"
+	"github.com/mattermost/morph/drivers/sqlite"
 	"github.com/mattermost/morph/models"
 
 	"github.com/stretchr/testify/assert"
@@ -446,6 +455,1145 @@ func TestApplyPlan(t *testing.T) {
 	})
 }
 
+func TestApplyPlanResume(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_migration_a", Direction: models.Up, Version: 1, RawName: "000001_migration_a.up.sql"},
+			{Name: "000002_migration_b", Direction: models.Up, Version: 2, RawName: "000002_migration_b.up.sql"},
+			{Name: "000003_migration_c", Direction: models.Up, Version: 3, RawName: "000003_migration_c.up.sql"},
+			{Name: "000001_migration_a", Direction: models.Down, Version: 1, RawName: "000001_migration_a.down.sql"},
+			{Name: "000002_migration_b", Direction: models.Down, Version: 2, RawName: "000002_migration_b.down.sql"},
+			{Name: "000003_migration_c", Direction: models.Down, Version: 3, RawName: "000003_migration_c.down.sql"},
+		},
+	}
+
+	t.Run("should skip the steps that are already applied/mockDriver", func(t *testing.T) {
+		td := &testDriver{failAt: 3, mode: models.Up}
+		engine, err := New(context.Background(), td, ts, SetPlanResume(true))
+		require.NoError(t, err)
+
+		migrations, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan(migrations, false)
+		require.NoError(t, err)
+
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "failed to apply migration")
+
+		step, err := engine.PlanProgress(plan)
+		require.NoError(t, err)
+		require.Equal(t, 2, step)
+
+		td.failAt = 0
+		err = engine.ApplyPlan(plan)
+		require.NoError(t, err)
+
+		applied, err := td.AppliedMigrations()
+		require.NoError(t, err)
+		require.Len(t, applied, 3)
+
+		step, err = engine.PlanProgress(plan)
+		require.NoError(t, err)
+		require.Equal(t, 3, step)
+
+		// running a completed plan once again should be a no-op
+		err = engine.ApplyPlan(plan)
+		require.NoError(t, err)
+
+		applied, err = td.AppliedMigrations()
+		require.NoError(t, err)
+		require.Len(t, applied, 3)
+	})
+
+	t.Run("should skip the down steps that are already reverted/mockDriver", func(t *testing.T) {
+		td := &testDriver{failAt: 2, mode: models.Down}
+		td.applied = []*models.Migration{ts.migrations[0], ts.migrations[1], ts.migrations[2]}
+
+		engine, err := New(context.Background(), td, ts, SetPlanResume(true))
+		require.NoError(t, err)
+
+		migrations, err := engine.Diff(models.Down)
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan(migrations, false)
+		require.NoError(t, err)
+
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "failed to apply migration")
+
+		step, err := engine.PlanProgress(plan)
+		require.NoError(t, err)
+		require.Equal(t, 1, step)
+
+		td.failAt = 0
+		err = engine.ApplyPlan(plan)
+		require.NoError(t, err)
+
+		applied, err := td.AppliedMigrations()
+		require.NoError(t, err)
+		require.Empty(t, applied)
+	})
+
+	t.Run("should not resume a partially applied plan unless enabled/mockDriver", func(t *testing.T) {
+		td := &testDriver{failAt: 3, mode: models.Up}
+		engine, err := New(context.Background(), td, ts)
+		require.NoError(t, err)
+
+		migrations, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan(migrations, false)
+		require.NoError(t, err)
+
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "failed to apply migration")
+
+		td.failAt = 0
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "the plan has been partially applied, previous run stopped at step 3 (000003_migration_c)")
+
+		applied, err := td.AppliedMigrations()
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+	})
+
+	t.Run("should roll back the steps applied by a previous run of an auto plan/mockDriver", func(t *testing.T) {
+		td := &testDriver{failAt: 2, mode: models.Up}
+		engine, err := New(context.Background(), td, ts, SetPlanResume(true))
+		require.NoError(t, err)
+
+		migrations, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan(migrations, false)
+		require.NoError(t, err)
+
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "failed to apply migration")
+
+		// the same plan, resumed with rollbacks enabled, fails at its last step
+		plan.Auto = true
+		td.failAt = 3
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "could not apply migration: failed to apply migration")
+
+		applied, err := td.AppliedMigrations()
+		require.NoError(t, err)
+		require.Empty(t, applied)
+	})
+
+	t.Run("should match the steps by version and name/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		// a migration with the same name as the second one, e.g. from another
+		// directory, has been applied with another version
+		td.applied = []*models.Migration{ts.migrations[0], {Name: "000002_migration_b", Direction: models.Up, Version: 20}}
+
+		engine, err := New(context.Background(), td, ts)
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan([]*models.Migration{ts.migrations[1], ts.migrations[2]}, false)
+		require.NoError(t, err)
+
+		step, err := engine.PlanProgress(plan)
+		require.NoError(t, err)
+		require.Equal(t, 0, step)
+	})
+}
+
+func TestApplyPlanJournal(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_migration_a", Direction: models.Up, Version: 1, RawName: "000001_migration_a.up.sql"},
+			{Name: "000002_migration_b", Direction: models.Up, Version: 2, RawName: "000002_migration_b.up.sql"},
+			{Name: "000001_migration_a", Direction: models.Down, Version: 1, RawName: "000001_migration_a.down.sql"},
+			{Name: "000002_migration_b", Direction: models.Down, Version: 2, RawName: "000002_migration_b.down.sql"},
+		},
+	}
+
+	td := &journalDriver{testDriver: testDriver{failAt: 2, mode: models.Up}}
+	engine, err := New(context.Background(), td, ts)
+	require.NoError(t, err)
+
+	migrations, err := engine.Diff(models.Up)
+	require.NoError(t, err)
+
+	plan, err := engine.GeneratePlan(migrations, true)
+	require.NoError(t, err)
+
+	err = engine.ApplyPlan(plan)
+	require.Error(t, err)
+
+	runs, err := engine.PlanRuns(10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, plan.ID, runs[0].PlanID)
+	require.Equal(t, models.RunReverted, runs[0].Status())
+
+	steps := runs[0].Steps
+	require.Len(t, steps, 4)
+	require.Equal(t, models.StepSucceeded, steps[0].Outcome)
+	require.Equal(t, models.StepFailed, steps[1].Outcome)
+	require.Equal(t, "failed to apply migration", steps[1].Error)
+	require.True(t, steps[2].Rollback)
+	require.Equal(t, models.Down, steps[2].Direction)
+	require.Equal(t, "000002_migration_b", steps[2].Name)
+	require.True(t, steps[3].Rollback)
+	require.Equal(t, "000001_migration_a", steps[3].Name)
+}
+
+func TestBaseline(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_migration_a", Direction: models.Up, Version: 1, RawName: "000001_migration_a.up.sql"},
+			{Name: "000002_migration_b", Direction: models.Up, Version: 2, RawName: "000002_migration_b.up.sql"},
+			{Name: "000003_migration_c", Direction: models.Up, Version: 3, RawName: "000003_migration_c.up.sql"},
+			{Name: "000001_migration_a", Direction: models.Down, Version: 1, RawName: "000001_migration_a.down.sql"},
+			{Name: "000002_migration_b", Direction: models.Down, Version: 2, RawName: "000002_migration_b.down.sql"},
+			{Name: "000003_migration_c", Direction: models.Down, Version: 3, RawName: "000003_migration_c.down.sql"},
+		},
+	}
+
+	t.Run("should record migrations up to the version without running them/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts)
+		require.NoError(t, err)
+
+		n, err := engine.Baseline(2)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		require.Len(t, td.applied, 2)
+		for _, migration := range td.applied {
+			require.True(t, migration.RecordOnly)
+		}
+
+		migrations, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+		require.Equal(t, uint64(3), migrations[0].Version)
+
+		// baseline is idempotent
+		n, err = engine.Baseline(2)
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("should fail if the version does not exist in the source/mockDriver", func(t *testing.T) {
+		engine, err := New(context.Background(), &testDriver{}, ts)
+		require.NoError(t, err)
+
+		_, err = engine.Baseline(42)
+		require.EqualError(t, err, "there is no migration with version 42 in the source")
+	})
+}
+
+func TestRedo(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_migration_a", Direction: models.Up, Version: 1, RawName: "000001_migration_a.up.sql"},
+			{Name: "000002_migration_b", Direction: models.Up, Version: 2, RawName: "000002_migration_b.up.sql"},
+			{Name: "000003_migration_c", Direction: models.Up, Version: 3, RawName: "000003_migration_c.up.sql"},
+			{Name: "000001_migration_a", Direction: models.Down, Version: 1, RawName: "000001_migration_a.down.sql"},
+			{Name: "000002_migration_b", Direction: models.Down, Version: 2, RawName: "000002_migration_b.down.sql"},
+			{Name: "000003_migration_c", Direction: models.Down, Version: 3, RawName: "000003_migration_c.down.sql"},
+		},
+	}
+
+	t.Run("should roll back and re-apply the last migrations/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts)
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+
+		var downs int
+		engine.AddInterceptor(2, models.Down, func() error {
+			downs++
+			return nil
+		})
+
+		n, err := engine.Redo(2)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		require.Equal(t, 1, downs)
+
+		migrations, err := engine.Diff(models.Down)
+		require.NoError(t, err)
+		require.Len(t, migrations, 3)
+	})
+
+	t.Run("should report the state if re-apply fails/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts)
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+
+		td.failAt = 3
+		td.mode = models.Up
+		n, err := engine.Redo(2)
+		require.EqualError(t, err, "redo stopped while re-applying 000003_migration_c: 1 of 2 migrations have been re-applied, 1 are still rolled back: failed to apply migration")
+		require.Equal(t, 1, n)
+
+		migrations, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+	})
+
+	t.Run("should fail if there are not enough applied migrations/mockDriver", func(t *testing.T) {
+		engine, err := New(context.Background(), &testDriver{}, ts)
+		require.NoError(t, err)
+
+		_, err = engine.Redo(1)
+		require.EqualError(t, err, "there are only 0 migrations applied, but you requested 1")
+	})
+}
+
+func TestCheckDrift(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer PRIMARY KEY, name text)")},
+			{Name: "000002_create_posts", Direction: models.Up, Version: 2, RawName: "000002_create_posts.up.sql", Bytes: []byte("CREATE TABLE posts (id integer PRIMARY KEY)")},
+		},
+	}
+
+	f, err := os.CreateTemp("", "morph-drift-*.db")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	t.Cleanup(func() {
+		_ = os.Remove(f.Name())
+	})
+
+	driver, err := sqlite.Open(f.Name())
+	require.NoError(t, err)
+
+	engine, err := New(context.Background(), driver, ts, WithLogger(log.New(io.Discard, "", 0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, engine.Close())
+	})
+
+	_, err = engine.Apply(1)
+	require.NoError(t, err)
+
+	t.Run("should not report pending migrations as drift", func(t *testing.T) {
+		diff, err := engine.CheckDrift()
+		require.NoError(t, err)
+		require.False(t, diff.HasChanges(), diff.String())
+	})
+
+	t.Run("should report objects changed by hand", func(t *testing.T) {
+		err := driver.Apply(&models.Migration{Bytes: []byte("ALTER TABLE users ADD COLUMN email text; CREATE TABLE hotfix (id integer)")}, false)
+		require.NoError(t, err)
+
+		diff, err := engine.CheckDrift()
+		require.NoError(t, err)
+		require.Len(t, diff.Changes, 2)
+		require.Equal(t, &models.SchemaChange{Kind: models.ObjectAdded, Object: "column users.email", Actual: "email TEXT"}, diff.Changes[0])
+		require.Equal(t, models.ObjectAdded, diff.Changes[1].Kind)
+		require.Equal(t, "table hotfix", diff.Changes[1].Object)
+	})
+}
+
+func TestSquash(t *testing.T) {
+	original := []*models.Migration{
+		{Name: "create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer PRIMARY KEY, name text)")},
+		{Name: "create_users", Direction: models.Down, Version: 1, RawName: "000001_create_users.down.sql", Bytes: []byte("DROP TABLE users")},
+		{Name: "create_posts", Direction: models.Up, Version: 2, RawName: "000002_create_posts.up.sql", Bytes: []byte("CREATE TABLE posts (id integer PRIMARY KEY, user_id integer REFERENCES users (id)); CREATE INDEX idx_posts_user_id ON posts (user_id)")},
+		{Name: "create_posts", Direction: models.Down, Version: 2, RawName: "000002_create_posts.down.sql", Bytes: []byte("DROP TABLE posts")},
+		{Name: "add_email", Direction: models.Up, Version: 3, RawName: "000003_add_email.up.sql", Bytes: []byte("ALTER TABLE users ADD COLUMN email text")},
+		{Name: "add_email", Direction: models.Down, Version: 3, RawName: "000003_add_email.down.sql", Bytes: []byte("ALTER TABLE users DROP COLUMN email")},
+	}
+	discard := WithLogger(log.New(io.Discard, "", 0))
+
+	newEngine := func(t *testing.T, src *basicSource) *Morph {
+		f, err := os.CreateTemp("", "morph-squash-*.db")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		t.Cleanup(func() {
+			_ = os.Remove(f.Name())
+		})
+
+		driver, err := sqlite.Open(f.Name())
+		require.NoError(t, err)
+
+		engine, err := New(context.Background(), driver, src, discard)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, engine.Close())
+		})
+
+		return engine
+	}
+
+	names := func(migrations []*models.Migration) []string {
+		var names []string
+		for _, migration := range migrations {
+			names = append(names, migration.Name)
+		}
+		return names
+	}
+
+	legacy := newEngine(t, &basicSource{migrations: original})
+	err := legacy.ApplyAll()
+	require.NoError(t, err)
+
+	up, down, err := legacy.Squash(2)
+	require.NoError(t, err)
+	require.Equal(t, "squashed_create_posts", up.Name)
+	require.Equal(t, "000002_squashed_create_posts.up.sql", up.RawName)
+	require.Equal(t, "000002_squashed_create_posts.down.sql", down.RawName)
+	require.Equal(t, uint64(2), up.Version)
+
+	directives, err := up.Directives()
+	require.NoError(t, err)
+	require.True(t, directives.Squash)
+	require.True(t, strings.Index(string(up.Bytes), "CREATE TABLE users") < strings.Index(string(up.Bytes), "CREATE TABLE posts"), string(up.Bytes))
+	require.NotContains(t, string(up.Bytes), "email")
+	require.Equal(t, "DROP TABLE IF EXISTS posts;\nDROP TABLE IF EXISTS users;\n", string(down.Bytes))
+
+	squashed := &basicSource{migrations: append(append([]*models.Migration{}, original...), up, down)}
+
+	t.Run("should consider databases past the squash point consistent with it", func(t *testing.T) {
+		legacy.source = squashed
+
+		diff, err := legacy.Diff(models.Up)
+		require.NoError(t, err)
+		require.Empty(t, diff)
+
+		diff, err = legacy.Diff(models.Down)
+		require.NoError(t, err)
+		require.Equal(t, []string{"add_email"}, names(diff))
+
+		drift, err := legacy.CheckDrift()
+		require.NoError(t, err)
+		require.False(t, drift.HasChanges(), drift.String())
+	})
+
+	t.Run("should run only the squashed migration on fresh databases", func(t *testing.T) {
+		engine := newEngine(t, squashed)
+
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Equal(t, []string{"squashed_create_posts", "add_email"}, names(diff))
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+
+		applied, err := engine.driver.AppliedMigrations()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"squashed_create_posts", "add_email"}, names(applied))
+
+		drift, err := engine.CheckDrift()
+		require.NoError(t, err)
+		require.False(t, drift.HasChanges(), drift.String())
+
+		n, err := engine.ApplyDown(-1)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("should meet the dependencies on replaced migrations", func(t *testing.T) {
+		dependent := *original[4]
+		dependent.Bytes = append([]byte("-- morph:depends-on=create_users\n"), dependent.Bytes...)
+		engine := newEngine(t, &basicSource{migrations: append(append([]*models.Migration{}, original[:4]...), &dependent, original[5], up, down)})
+
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Equal(t, []string{"squashed_create_posts", "add_email"}, names(diff))
+	})
+
+	t.Run("should apply the replaced migrations on databases behind the squash point", func(t *testing.T) {
+		engine := newEngine(t, &basicSource{migrations: original})
+		_, err := engine.Apply(1)
+		require.NoError(t, err)
+
+		engine.source = squashed
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Equal(t, []string{"create_posts", "add_email"}, names(diff))
+
+		engine.source = &basicSource{migrations: append(append([]*models.Migration{}, original[4:]...), up, down)}
+		_, err = engine.Diff(models.Up)
+		require.EqualError(t, err, "the database has not been migrated up to the squashed migration squashed_create_posts, and the migrations it replaces are no longer in the source")
+	})
+}
+
+func TestApplyRepeatables(t *testing.T) {
+	ts := &repeatableSource{
+		basicSource: basicSource{
+			migrations: []*models.Migration{
+				{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer PRIMARY KEY, name text)")},
+			},
+		},
+		repeatables: []*models.Migration{
+			{Name: "user_names", Direction: models.Up, RawName: "R__user_names.sql", Repeatable: true, Bytes: []byte("DROP VIEW IF EXISTS user_names; CREATE VIEW user_names AS SELECT name FROM users")},
+		},
+	}
+
+	f, err := os.CreateTemp("", "morph-repeatable-*.db")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	t.Cleanup(func() {
+		_ = os.Remove(f.Name())
+	})
+
+	driver, err := sqlite.Open(f.Name())
+	require.NoError(t, err)
+
+	engine, err := New(context.Background(), driver, ts, WithLogger(log.New(io.Discard, "", 0)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, engine.Close())
+	})
+
+	t.Run("should apply repeatables after the versioned migrations", func(t *testing.T) {
+		n, err := engine.Apply(-1)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		pending, err := engine.PendingRepeatables()
+		require.NoError(t, err)
+		require.Empty(t, pending)
+	})
+
+	t.Run("should not apply unchanged repeatables again", func(t *testing.T) {
+		n, err := engine.ApplyRepeatables()
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("should apply repeatables again when their checksum changes", func(t *testing.T) {
+		ts.repeatables[0].Bytes = []byte("DROP VIEW IF EXISTS user_names; CREATE VIEW user_names AS SELECT id, name FROM users")
+
+		pending, err := engine.PendingRepeatables()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+
+		n, err := engine.ApplyRepeatables()
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("should fail if the driver cannot track repeatables", func(t *testing.T) {
+		engine, err := New(context.Background(), &testDriver{}, ts, WithLogger(log.New(io.Discard, "", 0)))
+		require.NoError(t, err)
+
+		_, err = engine.ApplyRepeatables()
+		require.EqualError(t, err, "driver does not support repeatable migrations")
+	})
+}
+
+func TestSeed(t *testing.T) {
+	src := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "create_roles", Direction: models.Up, Version: 1, RawName: "000001_create_roles.up.sql", Bytes: []byte("CREATE TABLE roles (id integer PRIMARY KEY, name text NOT NULL)")},
+			{Name: "create_settings", Direction: models.Up, Version: 2, RawName: "000002_create_settings.up.sql", Bytes: []byte("CREATE TABLE settings (name text PRIMARY KEY, value text)")},
+		},
+	}
+	seeds := &seedSource{
+		seeds: []*models.Migration{
+			{Name: "01_roles", Direction: models.Up, RawName: "01_roles.json", Seed: true, Bytes: []byte(`{"table": "roles", "keys": ["id"], "rows": [{"id": 1, "name": "admin"}, {"id": 2, "name": "user"}]}`)},
+			{Name: "02_settings", Direction: models.Up, RawName: "02_settings.sql", Seed: true, Bytes: []byte("INSERT INTO settings (name, value) VALUES ('theme', 'dark') ON CONFLICT (name) DO NOTHING")},
+			{Name: "03_demo_roles", Direction: models.Up, RawName: "03_demo_roles.json", Seed: true, Bytes: []byte(`{"table": "roles", "keys": ["id"], "rows": [{"id": 100, "name": "demo"}], "environments": ["dev"]}`)},
+		},
+	}
+
+	f, err := os.CreateTemp("", "morph-seed-*.db")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	t.Cleanup(func() {
+		_ = os.Remove(f.Name())
+	})
+
+	newEngine := func(t *testing.T, options ...EngineOption) *Morph {
+		driver, err := sqlite.Open(f.Name())
+		require.NoError(t, err)
+
+		engine, err := New(context.Background(), driver, src, append([]EngineOption{WithLogger(log.New(io.Discard, "", 0)), WithSeeds(seeds)}, options...)...)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, engine.Close())
+		})
+
+		return engine
+	}
+
+	names := func(migrations []*models.Migration) []string {
+		var names []string
+		for _, migration := range migrations {
+			names = append(names, migration.Name)
+		}
+		return names
+	}
+
+	roles := func(t *testing.T) []string {
+		db, err := sql.Open("sqlite", f.Name())
+		require.NoError(t, err)
+		defer db.Close()
+
+		rows, err := db.Query("SELECT name FROM roles ORDER BY id")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var roles []string
+		for rows.Next() {
+			var role string
+			require.NoError(t, rows.Scan(&role))
+			roles = append(roles, role)
+		}
+		return roles
+	}
+
+	t.Run("should not apply seeds before the migrations", func(t *testing.T) {
+		engine := newEngine(t, SetEnvironment("prod"))
+
+		_, err := engine.Seed()
+		require.EqualError(t, err, "there are 2 pending migrations, seeds are applied after all migrations")
+	})
+
+	t.Run("should apply the seeds of the environment", func(t *testing.T) {
+		engine := newEngine(t, SetEnvironment("prod"))
+		require.NoError(t, engine.ApplyAll())
+
+		n, err := engine.Seed()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		// the seed of another environment stays pending
+		pending, err := engine.PendingSeeds()
+		require.NoError(t, err)
+		require.Equal(t, []string{"03_demo_roles"}, names(pending))
+		require.Equal(t, []string{"admin", "user"}, roles(t))
+	})
+
+	t.Run("should only apply new and changed seeds", func(t *testing.T) {
+		seeds.seeds[0].Bytes = []byte(`{"table": "roles", "keys": ["id"], "rows": [{"id": 1, "name": "administrator"}, {"id": 2, "name": "user"}]}`)
+		engine := newEngine(t, SetEnvironment("dev"))
+
+		pending, err := engine.PendingSeeds()
+		require.NoError(t, err)
+		require.Equal(t, []string{"01_roles", "03_demo_roles"}, names(pending))
+
+		n, err := engine.Seed()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		require.Equal(t, []string{"administrator", "user", "demo"}, roles(t))
+
+		n, err = engine.Seed()
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+
+	t.Run("should fail on invalid seed data", func(t *testing.T) {
+		invalid := &seedSource{
+			seeds: []*models.Migration{
+				{Name: "roles", Direction: models.Up, RawName: "roles.json", Seed: true, Bytes: []byte(`{"table": "roles; DROP TABLE roles", "keys": ["id"], "rows": [{"id": 1}]}`)},
+			},
+		}
+		engine := newEngine(t, WithSeeds(invalid))
+
+		_, err := engine.Seed()
+		require.EqualError(t, err, `invalid table name "roles; DROP TABLE roles" in seed roles`)
+	})
+
+	t.Run("should fail if the driver does not support seeds", func(t *testing.T) {
+		engine, err := New(context.Background(), &testDriver{}, src, WithLogger(log.New(io.Discard, "", 0)), WithSeeds(seeds))
+		require.NoError(t, err)
+
+		_, err = engine.Seed()
+		require.EqualError(t, err, "driver does not support seeds")
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE {{.prefix}}users (id integer) TABLESPACE {{.tablespace}}")},
+			{Name: "000001_create_users", Direction: models.Down, Version: 1, RawName: "000001_create_users.down.sql", Bytes: []byte("DROP TABLE {{.prefix}}users")},
+		},
+	}
+	vars := map[string]string{"prefix": "app_", "tablespace": "fast"}
+
+	t.Run("should render the migrations before applying them/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, WithTemplateVariables(vars))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Len(t, td.applied, 1)
+		require.Equal(t, "CREATE TABLE app_users (id integer) TABLESPACE fast", td.applied[0].Query())
+
+		// the source is not modified
+		require.Equal(t, "CREATE TABLE {{.prefix}}users (id integer) TABLESPACE {{.tablespace}}", ts.migrations[0].Query())
+	})
+
+	t.Run("should render undefined variables as empty strings/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, WithTemplateVariables(map[string]string{"tablespace": "fast"}))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Equal(t, "CREATE TABLE users (id integer) TABLESPACE fast", td.applied[0].Query())
+	})
+
+	t.Run("should fail on undefined variables in strict mode/mockDriver", func(t *testing.T) {
+		_, err := New(context.Background(), &testDriver{}, ts, WithTemplateVariables(map[string]string{"tablespace": "fast"}), SetStrictTemplates(true))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "could not render migration 000001_create_users.up.sql")
+	})
+
+	t.Run("should include the rendered migrations in the plan/mockDriver", func(t *testing.T) {
+		engine, err := New(context.Background(), &testDriver{}, ts, WithTemplateVariables(vars))
+		require.NoError(t, err)
+
+		migrations, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan(migrations, true)
+		require.NoError(t, err)
+		require.True(t, plan.Rendered)
+		require.Equal(t, "CREATE TABLE app_users (id integer) TABLESPACE fast", plan.Migrations[0].Query())
+		require.Equal(t, "DROP TABLE app_users", plan.RevertMigrations[0].Query())
+	})
+
+	t.Run("should not render a rendered plan again/mockDriver", func(t *testing.T) {
+		plan := models.NewPlan([]*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, Bytes: []byte("CREATE TABLE reviewed_users (id integer)")},
+		}, nil, false)
+		plan.Rendered = true
+
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, WithTemplateVariables(vars), WithLogger(log.New(io.Discard, "", 0)))
+		require.NoError(t, err)
+
+		err = engine.ApplyPlan(plan)
+		require.NoError(t, err)
+		require.Equal(t, "CREATE TABLE reviewed_users (id integer)", td.applied[0].Query())
+	})
+}
+
+func TestDirectives(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer)")},
+			{Name: "000002_seed_users", Direction: models.Up, Version: 2, RawName: "000002_seed_users.up.sql", Bytes: []byte("-- morph:env=staging|production\nINSERT INTO users VALUES (1)")},
+			{Name: "000003_drop_column", Direction: models.Up, Version: 3, RawName: "000003_drop_column.up.sql", Bytes: []byte("-- drops data\n-- morph:confirm, timeout=600\nALTER TABLE users DROP COLUMN name")},
+		},
+	}
+	discard := WithLogger(log.New(io.Discard, "", 0))
+
+	t.Run("should record migrations of other environments without running them/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, SetEnvironment("development"), WithConfirmation(func(*models.Migration) (bool, error) {
+			return true, nil
+		}))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Len(t, td.applied, 3)
+		require.False(t, td.applied[0].RecordOnly)
+		require.True(t, td.applied[1].RecordOnly)
+		require.False(t, td.applied[2].RecordOnly)
+	})
+
+	t.Run("should record migrations restricted to environments when none is set/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, WithConfirmation(func(*models.Migration) (bool, error) {
+			return true, nil
+		}))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Len(t, td.applied, 3)
+		require.True(t, td.applied[1].RecordOnly)
+		require.Equal(t, "no environment is set and it only runs in staging, production", td.applied[1].SkipReason)
+	})
+
+	t.Run("should not apply a migration that requires a confirmation without it/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, SetEnvironment("production"))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.EqualError(t, err, "migration 000003_drop_column requires a confirmation")
+		require.Len(t, td.applied, 2)
+	})
+
+	t.Run("should not apply a migration that has been declined/mockDriver", func(t *testing.T) {
+		var asked []string
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, SetEnvironment("production"), WithConfirmation(func(migration *models.Migration) (bool, error) {
+			asked = append(asked, migration.Name)
+			return false, nil
+		}))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.EqualError(t, err, "migration 000003_drop_column has not been confirmed")
+		require.Equal(t, []string{"000003_drop_column"}, asked)
+	})
+
+	t.Run("should ignore unknown directives/mockDriver", func(t *testing.T) {
+		src := &basicSource{
+			migrations: []*models.Migration{
+				{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("-- morph:nontransactinal\nCREATE TABLE users (id integer)")},
+			},
+		}
+		var buf bytes.Buffer
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, src, WithLogger(log.New(&buf, "", 0)))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Len(t, td.applied, 1)
+		require.Contains(t, buf.String(), `000001_create_users: unknown directive "nontransactinal" ignored`)
+	})
+}
+
+func TestTags(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer)")},
+			{Name: "000001_create_users", Direction: models.Down, Version: 1, RawName: "000001_create_users.down.sql", Bytes: []byte("DROP TABLE users")},
+			{Name: "000002_seed_users+dev+test", Direction: models.Up, Version: 2, RawName: "000002_seed_users+dev+test.up.sql", Bytes: []byte("INSERT INTO users VALUES (1)")},
+			{Name: "000002_seed_users+dev+test", Direction: models.Down, Version: 2, RawName: "000002_seed_users+dev+test.down.sql", Bytes: []byte("DELETE FROM users")},
+			{Name: "000003_add_index", Direction: models.Up, Version: 3, RawName: "000003_add_index.up.sql", Bytes: []byte("-- morph:tags=analytics\nCREATE INDEX idx_users_id ON users (id)")},
+			{Name: "000003_add_index", Direction: models.Down, Version: 3, RawName: "000003_add_index.down.sql", Bytes: []byte("DROP INDEX idx_users_id")},
+		},
+	}
+	discard := WithLogger(log.New(io.Discard, "", 0))
+
+	t.Run("should record migrations without an active tag along with the reason/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, WithTags("analytics"))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Len(t, td.applied, 3)
+		require.False(t, td.applied[0].RecordOnly)
+		require.True(t, td.applied[1].RecordOnly)
+		require.Equal(t, "none of the tags dev, test is active", td.applied[1].SkipReason)
+		require.False(t, td.applied[2].RecordOnly)
+		require.Empty(t, td.applied[2].SkipReason)
+	})
+
+	t.Run("should apply migrations with an active tag/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, WithTags("test"))
+		require.NoError(t, err)
+
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Len(t, diff, 2)
+		require.Equal(t, "000001_create_users", diff[0].Name)
+		require.Equal(t, "000002_seed_users+dev+test", diff[1].Name)
+
+		applied, err := engine.Apply(2)
+		require.NoError(t, err)
+		require.Equal(t, 2, applied)
+		require.Len(t, td.applied, 2)
+		require.False(t, td.applied[1].RecordOnly)
+
+		_, err = engine.Apply(1)
+		require.EqualError(t, err, "there are only 0 migrations available, but you requested 1")
+	})
+
+	t.Run("should not count skipped migrations/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard)
+		require.NoError(t, err)
+
+		applied, err := engine.Apply(1)
+		require.NoError(t, err)
+		require.Equal(t, 1, applied)
+		require.Len(t, td.applied, 1)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Len(t, td.applied, 3)
+		require.Equal(t, "none of the tags analytics is active", td.applied[2].SkipReason)
+	})
+
+	t.Run("should only remove the record when rolling back a skipped migration/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, WithTags("analytics"))
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+
+		diff, err := engine.Diff(models.Down)
+		require.NoError(t, err)
+		require.Len(t, diff, 3)
+		require.False(t, diff[0].RecordOnly)
+		require.True(t, diff[1].RecordOnly)
+
+		applied, err := engine.ApplyDown(2)
+		require.NoError(t, err)
+		require.Equal(t, 2, applied)
+		require.Len(t, td.applied, 1)
+		require.Equal(t, "000001_create_users", td.applied[0].Name)
+	})
+}
+
+func TestOutOfOrder(t *testing.T) {
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer)")},
+			{Name: "000002_create_teams", Direction: models.Up, Version: 2, RawName: "000002_create_teams.up.sql", Bytes: []byte("CREATE TABLE teams (id integer)")},
+			{Name: "000003_create_posts", Direction: models.Up, Version: 3, RawName: "000003_create_posts.up.sql", Bytes: []byte("CREATE TABLE posts (id integer)")},
+			{Name: "000004_create_files", Direction: models.Up, Version: 4, RawName: "000004_create_files.up.sql", Bytes: []byte("CREATE TABLE files (id integer)")},
+		},
+	}
+	discard := WithLogger(log.New(io.Discard, "", 0))
+
+	// 000002_create_teams has been merged after 000003_create_posts was deployed
+	newDriver := func() *testDriver {
+		return &testDriver{applied: []*models.Migration{ts.migrations[0], ts.migrations[2]}}
+	}
+
+	t.Run("should apply out of order migrations by default/mockDriver", func(t *testing.T) {
+		td := newDriver()
+		engine, err := New(context.Background(), td, ts, discard)
+		require.NoError(t, err)
+
+		applied, err := engine.Apply(-1)
+		require.NoError(t, err)
+		require.Equal(t, 2, applied)
+		require.Len(t, td.applied, 4)
+		require.Equal(t, "000002_create_teams", td.applied[2].Name)
+	})
+
+	t.Run("should fail without applying anything in strict mode/mockDriver", func(t *testing.T) {
+		td := newDriver()
+		engine, err := New(context.Background(), td, ts, discard, SetOutOfOrderPolicy(OutOfOrderStrict))
+		require.NoError(t, err)
+
+		_, err = engine.Apply(-1)
+		require.EqualError(t, err, "migrations 000002_create_teams are out of order, they sort before the applied migration 000003_create_posts")
+		require.Len(t, td.applied, 2)
+
+		_, err = engine.Diff(models.Up)
+		require.Error(t, err)
+	})
+
+	t.Run("should leave out of order migrations pending in ignore mode/mockDriver", func(t *testing.T) {
+		td := newDriver()
+		engine, err := New(context.Background(), td, ts, discard, SetOutOfOrderPolicy(OutOfOrderIgnore))
+		require.NoError(t, err)
+
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Len(t, diff, 1)
+		require.Equal(t, "000004_create_files", diff[0].Name)
+
+		applied, err := engine.Apply(-1)
+		require.NoError(t, err)
+		require.Equal(t, 1, applied)
+		require.Len(t, td.applied, 3)
+		require.Equal(t, "000004_create_files", td.applied[2].Name)
+	})
+
+	t.Run("should roll back out of order migrations in the order they have been applied in", func(t *testing.T) {
+		up := func(version uint64, name, query string) *models.Migration {
+			return &models.Migration{Name: name, Direction: models.Up, Version: version, RawName: fmt.Sprintf("%06d_%s.up.sql", version, name), Bytes: []byte(query)}
+		}
+		down := func(version uint64, name, query string) *models.Migration {
+			return &models.Migration{Name: name, Direction: models.Down, Version: version, RawName: fmt.Sprintf("%06d_%s.down.sql", version, name), Bytes: []byte(query)}
+		}
+		deployed := &basicSource{
+			migrations: []*models.Migration{
+				up(1, "create_users", "CREATE TABLE users (id integer)"),
+				down(1, "create_users", "DROP TABLE users"),
+				up(3, "create_posts", "CREATE TABLE posts (id integer)"),
+				down(3, "create_posts", "DROP TABLE posts"),
+			},
+		}
+		merged := &basicSource{
+			migrations: append([]*models.Migration{
+				up(2, "create_teams", "CREATE TABLE teams (id integer)"),
+				down(2, "create_teams", "DROP TABLE teams"),
+			}, deployed.migrations...),
+		}
+
+		driver := memory.New()
+		engine, err := New(context.Background(), driver, deployed, discard)
+		require.NoError(t, err)
+		require.NoError(t, engine.ApplyAll())
+
+		engine, err = New(context.Background(), driver, merged, discard)
+		require.NoError(t, err)
+		require.NoError(t, engine.ApplyAll())
+
+		diff, err := engine.Diff(models.Down)
+		require.NoError(t, err)
+		require.Len(t, diff, 3)
+		require.Equal(t, []string{"create_teams", "create_posts", "create_users"}, []string{diff[0].Name, diff[1].Name, diff[2].Name})
+
+		rolledBack, err := engine.ApplyDown(1)
+		require.NoError(t, err)
+		require.Equal(t, 1, rolledBack)
+
+		statements := driver.Statements()
+		require.Equal(t, "DROP TABLE teams", statements[len(statements)-1])
+
+		applied, err := driver.AppliedMigrations()
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+		require.Equal(t, "create_users", applied[0].Name)
+		require.Equal(t, "create_posts", applied[1].Name)
+	})
+
+	t.Run("should reject unknown policies", func(t *testing.T) {
+		_, err := New(context.Background(), newDriver(), ts, discard, SetOutOfOrderPolicy("sometimes"))
+		require.EqualError(t, err, `could not apply option: unknown out of order policy "sometimes"`)
+	})
+}
+
+func TestDependencies(t *testing.T) {
+	// 000002_add_owner depends on 000003_create_teams, added later by another team
+	ts := &basicSource{
+		migrations: []*models.Migration{
+			{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer)")},
+			{Name: "000001_create_users", Direction: models.Down, Version: 1, RawName: "000001_create_users.down.sql", Bytes: []byte("DROP TABLE users")},
+			{Name: "000002_add_owner", Direction: models.Up, Version: 2, RawName: "000002_add_owner.up.sql", Bytes: []byte("-- morph:depends-on=000003_create_teams\nALTER TABLE teams ADD COLUMN owner integer")},
+			{Name: "000002_add_owner", Direction: models.Down, Version: 2, RawName: "000002_add_owner.down.sql", Bytes: []byte("ALTER TABLE teams DROP COLUMN owner")},
+			{Name: "000003_create_teams", Direction: models.Up, Version: 3, RawName: "000003_create_teams.up.sql", Bytes: []byte("CREATE TABLE teams (id integer)")},
+			{Name: "000003_create_teams", Direction: models.Down, Version: 3, RawName: "000003_create_teams.down.sql", Bytes: []byte("DROP TABLE teams")},
+			{Name: "000004_create_posts", Direction: models.Up, Version: 4, RawName: "000004_create_posts.up.sql", Bytes: []byte("CREATE TABLE posts (id integer)")},
+			{Name: "000004_create_posts", Direction: models.Down, Version: 4, RawName: "000004_create_posts.down.sql", Bytes: []byte("DROP TABLE posts")},
+		},
+	}
+	discard := WithLogger(log.New(io.Discard, "", 0))
+
+	names := func(migrations []*models.Migration) []string {
+		var names []string
+		for _, migration := range migrations {
+			names = append(names, migration.Name)
+		}
+		return names
+	}
+
+	t.Run("should apply migrations after their dependencies/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard, SetOutOfOrderPolicy(OutOfOrderStrict))
+		require.NoError(t, err)
+
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Equal(t, []string{"000001_create_users", "000003_create_teams", "000002_add_owner", "000004_create_posts"}, names(diff))
+
+		_, err = engine.Apply(2)
+		require.NoError(t, err)
+
+		// applying the dependency first is not out of order
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+		require.Equal(t, []string{"000001_create_users", "000003_create_teams", "000002_add_owner", "000004_create_posts"}, names(td.applied))
+
+		diff, err = engine.Diff(models.Down)
+		require.NoError(t, err)
+		require.Equal(t, []string{"000004_create_posts", "000002_add_owner", "000003_create_teams", "000001_create_users"}, names(diff))
+
+		applied, err := engine.ApplyDown(-1)
+		require.NoError(t, err)
+		require.Equal(t, 4, applied)
+		require.Empty(t, td.applied)
+	})
+
+	t.Run("should refuse to roll back a migration with applied dependents/mockDriver", func(t *testing.T) {
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, ts, discard)
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.NoError(t, err)
+
+		plan, err := engine.GeneratePlan([]*models.Migration{ts.migrations[5]}, false)
+		require.NoError(t, err)
+
+		err = engine.ApplyPlan(plan)
+		require.EqualError(t, err, "cannot roll back migration 000003_create_teams, it is a dependency of the applied migrations 000002_add_owner")
+		require.Len(t, td.applied, 4)
+	})
+
+	t.Run("should detect dependency cycles/mockDriver", func(t *testing.T) {
+		src := &basicSource{
+			migrations: []*models.Migration{
+				{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer)")},
+				{Name: "000002_create_teams", Direction: models.Up, Version: 2, RawName: "000002_create_teams.up.sql", Bytes: []byte("-- morph:depends-on=000003_create_posts\nCREATE TABLE teams (id integer)")},
+				{Name: "000003_create_posts", Direction: models.Up, Version: 3, RawName: "000003_create_posts.up.sql", Bytes: []byte("-- morph:depends-on=000002_create_teams\nCREATE TABLE posts (id integer)")},
+			},
+		}
+		engine, err := New(context.Background(), &testDriver{}, src, discard)
+		require.NoError(t, err)
+
+		err = engine.ApplyAll()
+		require.EqualError(t, err, "dependency cycle between migrations 000002_create_teams -> 000003_create_posts -> 000002_create_teams")
+	})
+
+	t.Run("should fail on unknown dependencies/mockDriver", func(t *testing.T) {
+		src := &basicSource{
+			migrations: []*models.Migration{
+				{Name: "000001_create_users", Direction: models.Up, Version: 1, RawName: "000001_create_users.up.sql", Bytes: []byte("-- morph:depends-on=000000_init\nCREATE TABLE users (id integer)")},
+			},
+		}
+		engine, err := New(context.Background(), &testDriver{}, src, discard)
+		require.NoError(t, err)
+
+		_, err = engine.Diff(models.Up)
+		require.EqualError(t, err, "migration 000001_create_users depends on unknown migration 000000_init")
+	})
+
+	t.Run("should apply migrations without dependencies in the order of their versions/mockDriver", func(t *testing.T) {
+		src := &basicSource{
+			migrations: []*models.Migration{
+				{Name: "10_create_posts", Direction: models.Up, Version: 10, RawName: "10_create_posts.up.sql", Bytes: []byte("CREATE TABLE posts (id integer)")},
+				{Name: "9_create_teams", Direction: models.Up, Version: 9, RawName: "9_create_teams.up.sql", Bytes: []byte("CREATE TABLE teams (id integer)")},
+				{Name: "20060102150405_create_users", Direction: models.Up, Version: 20060102150405, RawName: "20060102150405_create_users.up.sql", Bytes: []byte("CREATE TABLE users (id integer)")},
+			},
+		}
+		td := &testDriver{}
+		engine, err := New(context.Background(), td, src, discard)
+		require.NoError(t, err)
+
+		diff, err := engine.Diff(models.Up)
+		require.NoError(t, err)
+		require.Equal(t, []string{"9_create_teams", "10_create_posts", "20060102150405_create_users"}, names(diff))
+
+		require.NoError(t, engine.ApplyAll())
+		require.Equal(t, []string{"9_create_teams", "10_create_posts", "20060102150405_create_users"}, names(td.applied))
+	})
+}
+
+type repeatableSource struct {
+	basicSource
+	repeatables []*models.Migration
+}
+
+func (s *repeatableSource) Repeatables() []*models.Migration {
+	return s.repeatables
+}
+
+type seedSource struct {
+	seeds []*models.Migration
+}
+
+func (s *seedSource) Seeds() []*models.Migration {
+	return s.seeds
+}
+
 type basicSource struct {
 	migrations []*models.Migration
 }
@@ -494,9 +1642,47 @@ func (d *testDriver) Apply(migration *models.Migration, saveVersion bool) error
 }
 
 func (d *testDriver) AppliedMigrations() ([]*models.Migration, error) {
-	return d.applied, nil
+	// return a copy, just like a real driver would do, as the engine sorts
+	// the returned slice in place
+	applied := make([]*models.Migration, len(d.applied))
+	copy(applied, d.applied)
+	return applied, nil
 }
 
 func (d *testDriver) SetConfig(key string, value interface{}) error {
 	return nil
 }
+
+// journalDriver is a testDriver that keeps the plan journal in memory.
+type journalDriver struct {
+	testDriver
+	steps []*models.PlanStep
+}
+
+func (d *journalDriver) SavePlanStep(step *models.PlanStep) error {
+	for i := range d.steps {
+		if d.steps[i].RunID == step.RunID && d.steps[i].Sequence == step.Sequence {
+			d.steps[i] = step
+			return nil
+		}
+	}
+
+	d.steps = append(d.steps, step)
+	return nil
+}
+
+func (d *journalDriver) PlanRuns(limit int) ([]*models.PlanRun, error) {
+	var runs []*models.PlanRun
+	for _, step := range d.steps {
+		if len(runs) == 0 || runs[len(runs)-1].RunID != step.RunID {
+			runs = append(runs, &models.PlanRun{PlanID: step.PlanID, RunID: step.RunID})
+		}
+		runs[len(runs)-1].Steps = append(runs[len(runs)-1].Steps, step)
+	}
+
+	if len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,126 @@
+package morph
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// Seed applies the seeds set with WithSeeds that have not been applied yet or
+// whose checksum has changed since they were last applied, in the order of
+// their file names. Seeds hold reference data, therefore they run after the
+// schema migrations and Seed fails if there are pending migrations. They should
+// be idempotent, seed data files are applied as upserts to this end.
+//
+// Seeds restricted to other environments or without an active tag are skipped
+// and not recorded, so they are considered again by the next run. The driver
+// has to implement the drivers.Seeder interface.
+func (m *Morph) Seed() (int, error) {
+	pending, err := m.PendingSeeds()
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	seeder, ok := m.driver.(drivers.Seeder)
+	if !ok {
+		return -1, errors.New("driver does not support seeds")
+	}
+
+	migrations, err := m.Diff(models.Up)
+	if err != nil {
+		return -1, err
+	}
+	if len(migrations) > 0 {
+		return -1, fmt.Errorf("there are %d pending migrations, seeds are applied after all migrations", len(migrations))
+	}
+
+	var applied int
+	for _, seed := range pending {
+		migration, err := seedMigration(seeder, seed)
+		if err != nil {
+			return applied, err
+		}
+
+		directives, err := migration.Directives()
+		if err != nil {
+			return applied, err
+		}
+
+		reason, err := m.skipReason(migration, directives)
+		if err != nil {
+			return applied, err
+		}
+		if reason != "" {
+			m.config.Logger.Println(formatProgress(fmt.Sprintf(migrationSkipped, migration.Name, reason)))
+			continue
+		}
+
+		if err := m.apply(migration, false, m.config.DryRun); err != nil {
+			return applied, err
+		}
+
+		if !m.config.DryRun {
+			if err := seeder.SaveSeed(seed.Name, seed.Checksum()); err != nil {
+				return applied, err
+			}
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// PendingSeeds returns the seeds that would be applied by Seed, including the
+// ones that do not apply to the deployment.
+func (m *Morph) PendingSeeds() ([]*models.Migration, error) {
+	if m.seeds == nil || len(m.seeds.Seeds()) == 0 {
+		return nil, nil
+	}
+
+	seeder, ok := m.driver.(drivers.Seeder)
+	if !ok {
+		return nil, errors.New("driver does not support seeds")
+	}
+
+	checksums, err := seeder.AppliedSeeds()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*models.Migration
+	for _, seed := range m.seeds.Seeds() {
+		if checksums[seed.Name] != seed.Checksum() {
+			pending = append(pending, seed)
+		}
+	}
+
+	return pending, nil
+}
+
+// seedMigration returns the migration that applies the seed. Seed data files
+// are turned into the upsert statement of the driver, preceded by the
+// directives equivalent to their environments and tags.
+func seedMigration(seeder drivers.Seeder, seed *models.Migration) (*models.Migration, error) {
+	if !seed.IsSeedData() {
+		return seed, nil
+	}
+
+	data, err := models.ParseSeedData(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := data.Columns()
+	query, args, err := seeder.UpsertQuery(data.Table, data.Keys, columns, data.Values(columns))
+	if err != nil {
+		return nil, fmt.Errorf("could not build the query of seed %s: %w", seed.Name, err)
+	}
+
+	migration := *seed
+	migration.Bytes = []byte(data.Header() + query)
+	migration.Args = args
+
+	return &migration, nil
+}
@@ -2,13 +2,29 @@ package embedded
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
 
 	"github.com/mattermost/morph/models"
 	"github.com/mattermost/morph/sources"
 )
 
+const scheme = "fs"
+
+var (
+	filesystemsLock sync.RWMutex
+	filesystems     = make(map[string]fs.FS)
+)
+
+func init() {
+	sources.Register(scheme, Open)
+}
+
 type AssetFunc func(name string) ([]byte, error)
 
 func Resource(names []string, fn AssetFunc) *AssetSource {
@@ -26,6 +42,7 @@ type AssetSource struct {
 type Embedded struct {
 	assetSource *AssetSource
 	migrations  []*models.Migration
+	repeatables []*models.Migration
 }
 
 func WithInstance(assetSource *AssetSource) (sources.Source, error) {
@@ -45,6 +62,11 @@ func WithInstance(assetSource *AssetSource) (sources.Source, error) {
 			return nil, fmt.Errorf("could not create migration: %w", err)
 		}
 
+		if m.Repeatable {
+			b.repeatables = append(b.repeatables, m)
+			continue
+		}
+
 		b.migrations = append(b.migrations, m)
 	}
 
@@ -54,3 +76,71 @@ func WithInstance(assetSource *AssetSource) (sources.Source, error) {
 func (b *Embedded) Migrations() []*models.Migration {
 	return b.migrations
 }
+
+// Repeatables returns the repeatable migrations of the assets, the files named
+// R__name.ext.
+func (b *Embedded) Repeatables() []*models.Migration {
+	return b.repeatables
+}
+
+// RegisterFS makes a file system, such as an embed.FS, available to
+// sources.Open under the given name, so that the migrations of its directories
+// can be opened with fs://<name>/<directory> URLs. RegisterFS panics if the
+// name is already taken.
+func RegisterFS(name string, fsys fs.FS) {
+	filesystemsLock.Lock()
+	defer filesystemsLock.Unlock()
+
+	if fsys == nil {
+		panic("embedded: RegisterFS file system is nil")
+	}
+	if _, ok := filesystems[name]; ok {
+		panic("embedded: RegisterFS called twice for name " + name)
+	}
+
+	filesystems[name] = fsys
+}
+
+// Open reads the migrations of a directory of a file system registered with
+// RegisterFS, e.g. fs://assets/migrations/postgres for the migrations/postgres
+// directory of the file system registered as assets.
+func Open(sourceURL string) (sources.Source, error) {
+	name, dir, _ := strings.Cut(strings.TrimPrefix(sourceURL, scheme+"://"), "/")
+	if name == "" {
+		return nil, errors.New("the name of the file system is missing")
+	}
+
+	filesystemsLock.RLock()
+	fsys, ok := filesystems[name]
+	filesystemsLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("file system %q has not been registered", name)
+	}
+
+	return WithFS(fsys, dir)
+}
+
+// WithFS reads the migrations of a directory of a file system, e.g. an
+// embed.FS. The subdirectories are not read.
+func WithFS(fsys fs.FS, dir string) (sources.Source, error) {
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations in directory %q: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return WithInstance(Resource(names, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, path.Join(dir, name))
+	}))
+}
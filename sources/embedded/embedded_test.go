@@ -7,6 +7,7 @@ import (
 	"embed"
 	"testing"
 
+	"github.com/mattermost/morph/sources"
 	"github.com/mattermost/morph/sources/embedded/testdata"
 	"github.com/mattermost/morph/sources/testlib"
 
@@ -45,3 +46,31 @@ func TestGoEmbed(t *testing.T) {
 
 	testlib.Test(t, src)
 }
+
+func TestFS(t *testing.T) {
+	t.Run("should read the migrations of a directory", func(t *testing.T) {
+		src, err := WithFS(assets, "testfiles")
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should open registered file systems by url", func(t *testing.T) {
+		RegisterFS("embedded-test", assets)
+
+		src, err := sources.Open("fs://embedded-test/testfiles")
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should fail if the file system is not registered", func(t *testing.T) {
+		_, err := Open("fs://missing/testfiles")
+		require.EqualError(t, err, `file system "missing" has not been registered`)
+	})
+
+	t.Run("should fail if the directory does not exist", func(t *testing.T) {
+		_, err := WithFS(assets, "missing")
+		require.Error(t, err)
+	})
+}
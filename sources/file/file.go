@@ -7,20 +7,51 @@ import (
 	"path/filepath"
 
 	"github.com/mattermost/morph/models"
+	"github.com/mattermost/morph/sources"
 )
 
+func init() {
+	sources.Register("file", func(sourceURL string) (sources.Source, error) {
+		f, err := Open(sourceURL)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	})
+}
+
 type File struct {
-	url        string
-	path       string
-	migrations []*models.Migration
+	url         string
+	path        string
+	migrations  []*models.Migration
+	repeatables []*models.Migration
 }
 
 func Open(sourceURL string) (*File, error) {
-	uri, err := url.Parse(sourceURL)
+	p, err := resolvePath(sourceURL)
 	if err != nil {
 		return nil, err
 	}
 
+	nf := &File{
+		url:  sourceURL,
+		path: p,
+	}
+
+	if err := nf.readMigrations(); err != nil {
+		return nil, fmt.Errorf("cannot read migrations in path %q: %w", p, err)
+	}
+
+	return nf, nil
+}
+
+// resolvePath returns the absolute path of a file URL or path.
+func resolvePath(sourceURL string) (string, error) {
+	uri, err := url.Parse(sourceURL)
+	if err != nil {
+		return "", err
+	}
+
 	// host might be "." for relative URLs like file://./migrations
 	p := uri.Opaque
 	if len(p) == 0 {
@@ -29,30 +60,13 @@ func Open(sourceURL string) (*File, error) {
 
 	// if no path provided, default to current directory
 	if len(p) == 0 {
-		wd, err := os.Getwd()
-		if err != nil {
-			return nil, err
-		}
-		p = wd
+		return os.Getwd()
 	} else if p[0:1] != "/" {
 		// make path absolute if required
-		abs, err := filepath.Abs(p)
-		if err != nil {
-			return nil, err
-		}
-		p = abs
-	}
-
-	nf := &File{
-		url:  sourceURL,
-		path: p,
-	}
-
-	if err := nf.readMigrations(); err != nil {
-		return nil, fmt.Errorf("cannot read migrations in path %q: %w", p, err)
+		return filepath.Abs(p)
 	}
 
-	return nf, nil
+	return p, nil
 }
 
 func (f *File) readMigrations() error {
@@ -65,6 +79,7 @@ func (f *File) readMigrations() error {
 	}
 
 	migrations := []*models.Migration{}
+	repeatables := []*models.Migration{}
 	walkerr := filepath.Walk(f.path, func(path string, info os.FileInfo, _ error) error {
 		if info.IsDir() {
 			return nil
@@ -80,6 +95,11 @@ func (f *File) readMigrations() error {
 			return fmt.Errorf("could not create migration: %w", err)
 		}
 
+		if m.Repeatable {
+			repeatables = append(repeatables, m)
+			return nil
+		}
+
 		migrations = append(migrations, m)
 		return nil
 	})
@@ -88,9 +108,16 @@ func (f *File) readMigrations() error {
 	}
 
 	f.migrations = migrations
+	f.repeatables = repeatables
 	return nil
 }
 
 func (f *File) Migrations() []*models.Migration {
 	return f.migrations
 }
+
+// Repeatables returns the repeatable migrations of the directory, the files
+// named R__name.ext.
+func (f *File) Repeatables() []*models.Migration {
+	return f.repeatables
+}
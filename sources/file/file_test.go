@@ -4,9 +4,12 @@
 package file
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/mattermost/morph/models"
+	"github.com/mattermost/morph/sources"
 	"github.com/mattermost/morph/sources/testlib"
 
 	"github.com/stretchr/testify/require"
@@ -32,4 +35,36 @@ func TestFile(t *testing.T) {
 
 		testlib.Test(t, f)
 	})
+
+	t.Run("should be registered for the file scheme and for paths", func(t *testing.T) {
+		src, err := sources.Open("file://" + testFilesDir)
+		require.NoError(t, err)
+		testlib.Test(t, src)
+
+		src, err = sources.Open(testFilesDir)
+		require.NoError(t, err)
+		testlib.Test(t, src)
+
+		_, err = sources.Open("unknown://" + testFilesDir)
+		require.EqualError(t, err, "unsupported source unknown, expected one of file")
+	})
+
+	t.Run("should read repeatable migrations separately", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_create_users.up.sql"), []byte("CREATE TABLE users (id integer)"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "000001_create_users.down.sql"), []byte("DROP TABLE users"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "R__user_view.sql"), []byte("CREATE VIEW user_view AS SELECT id FROM users"), 0644))
+
+		f, err := Open(dir)
+		require.NoError(t, err)
+
+		require.Len(t, f.Migrations(), 2)
+		require.Len(t, f.Repeatables(), 1)
+
+		repeatable := f.Repeatables()[0]
+		require.Equal(t, "user_view", repeatable.Name)
+		require.Equal(t, models.Up, repeatable.Direction)
+		require.True(t, repeatable.Repeatable)
+		require.Len(t, repeatable.Checksum(), 64)
+	})
 }
@@ -0,0 +1,83 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mattermost/morph/models"
+)
+
+// Seeds is a source of seeds read from a directory, separate from the
+// directory of the migrations. SQL seeds are named name.sql and seed data files
+// name.json, and they are applied in the order of their file names.
+type Seeds struct {
+	url   string
+	path  string
+	seeds []*models.Migration
+}
+
+// OpenSeeds reads the seeds of the directory.
+func OpenSeeds(sourceURL string) (*Seeds, error) {
+	p, err := resolvePath(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Seeds{
+		url:  sourceURL,
+		path: p,
+	}
+
+	if err := s.readSeeds(); err != nil {
+		return nil, fmt.Errorf("cannot read seeds in path %q: %w", p, err)
+	}
+
+	return s, nil
+}
+
+func (s *Seeds) readSeeds() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("file %q is not a directory", info.Name())
+	}
+
+	seeds := []*models.Migration{}
+	walkerr := filepath.Walk(s.path, func(path string, info os.FileInfo, _ error) error {
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		seed, err := models.NewSeed(file, filepath.Base(path))
+		if err != nil {
+			return fmt.Errorf("could not create seed: %w", err)
+		}
+
+		seeds = append(seeds, seed)
+		return nil
+	})
+	if walkerr != nil {
+		return walkerr
+	}
+
+	sort.Slice(seeds, func(i, j int) bool {
+		return seeds[i].RawName < seeds[j].RawName
+	})
+	s.seeds = seeds
+
+	return nil
+}
+
+// Seeds returns the seeds of the directory in the order they apply in.
+func (s *Seeds) Seeds() []*models.Migration {
+	return s.seeds
+}
@@ -0,0 +1,72 @@
+package sources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultScheme is the scheme of the source URLs that have none, which are the
+// paths of local directories.
+const DefaultScheme = "file"
+
+// OpenFunc opens a source from a URL.
+type OpenFunc func(sourceURL string) (Source, error)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]OpenFunc)
+)
+
+// Register makes a source available to Open for the URLs with the given scheme,
+// e.g. tar for tar://migrations.tar.gz. Sources are meant to be registered from
+// the init function of their package, Register panics if the scheme is already
+// taken.
+func Register(scheme string, open OpenFunc) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if open == nil {
+		panic("sources: Register open function is nil")
+	}
+	if _, ok := registry[scheme]; ok {
+		panic("sources: Register called twice for scheme " + scheme)
+	}
+
+	registry[scheme] = open
+}
+
+// Registered returns the registered schemes in alphabetical order.
+func Registered() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Open opens the URL with the source registered for its scheme. URLs without a
+// scheme are opened as paths with the source of the DefaultScheme. The URL is
+// passed to the source as it is.
+func Open(sourceURL string) (Source, error) {
+	scheme, _, ok := strings.Cut(sourceURL, "://")
+	if !ok {
+		scheme = DefaultScheme
+	}
+
+	registryLock.RLock()
+	open, ok := registry[scheme]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported source %s, expected one of %s", scheme, strings.Join(Registered(), ", "))
+	}
+
+	return open(sourceURL)
+}
@@ -0,0 +1,62 @@
+//go:build sources && !drivers
+// +build sources,!drivers
+
+package sources
+
+import (
+	"testing"
+
+	"github.com/mattermost/morph/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	url string
+}
+
+func (s *fakeSource) Migrations() []*models.Migration {
+	return nil
+}
+
+func openFake(sourceURL string) (Source, error) {
+	return &fakeSource{url: sourceURL}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("fake", openFake)
+	Register(DefaultScheme, openFake)
+
+	t.Run("should open the source registered for the scheme", func(t *testing.T) {
+		src, err := Open("fake://migrations")
+		require.NoError(t, err)
+		require.Equal(t, &fakeSource{url: "fake://migrations"}, src)
+	})
+
+	t.Run("should open paths with the default scheme", func(t *testing.T) {
+		src, err := Open("migrations/postgres")
+		require.NoError(t, err)
+		require.Equal(t, &fakeSource{url: "migrations/postgres"}, src)
+	})
+
+	t.Run("should list the registered schemes", func(t *testing.T) {
+		require.Equal(t, []string{"fake", "file"}, Registered())
+	})
+
+	t.Run("should fail for unknown schemes", func(t *testing.T) {
+		_, err := Open("ftp://migrations")
+		require.EqualError(t, err, "unsupported source ftp, expected one of fake, file")
+	})
+
+	t.Run("should panic if the scheme is already registered", func(t *testing.T) {
+		require.PanicsWithValue(t, "sources: Register called twice for scheme fake", func() {
+			Register("fake", openFake)
+		})
+	})
+
+	t.Run("should panic if the open function is nil", func(t *testing.T) {
+		require.PanicsWithValue(t, "sources: Register open function is nil", func() {
+			Register("nil", nil)
+		})
+	})
+}
@@ -0,0 +1,52 @@
+// Package remote provides a source of migrations downloaded over HTTP. The URL
+// has to serve a tar archive of the migrations, optionally compressed with
+// gzip, as read by the tar source, e.g.
+// https://example.com/releases/v1.2.0/migrations.tar.gz.
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/morph/sources"
+	"github.com/mattermost/morph/sources/tar"
+)
+
+// DefaultTimeout bounds the time it takes to download the archive.
+const DefaultTimeout = 60 * time.Second
+
+func init() {
+	open := func(sourceURL string) (sources.Source, error) {
+		r, err := Open(sourceURL)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	sources.Register("http", open)
+	sources.Register("https", open)
+}
+
+// Open downloads the archive at the URL with a client bounded by the
+// DefaultTimeout and reads its migrations.
+func Open(sourceURL string) (*tar.Tar, error) {
+	return WithClient(&http.Client{Timeout: DefaultTimeout}, sourceURL)
+}
+
+// WithClient downloads the archive at the URL with the given client, e.g. one
+// that authenticates its requests, and reads its migrations.
+func WithClient(client *http.Client, sourceURL string) (*tar.Tar, error) {
+	resp, err := client.Get(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download migrations from %q: %w", sourceURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download migrations from %q: %s", sourceURL, resp.Status)
+	}
+
+	return tar.Read(sourceURL, resp.Body)
+}
@@ -0,0 +1,73 @@
+//go:build sources && !drivers
+// +build sources,!drivers
+
+package remote
+
+import (
+	archivetar "archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/morph/sources"
+	"github.com/mattermost/morph/sources/testlib"
+
+	"github.com/stretchr/testify/require"
+)
+
+func archive(t *testing.T) []byte {
+	testFilesDir := "../embedded/testfiles"
+	entries, err := os.ReadDir(testFilesDir)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := archivetar.NewWriter(gw)
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(testFilesDir, entry.Name()))
+		require.NoError(t, err)
+
+		require.NoError(t, tw.WriteHeader(&archivetar.Header{Name: "migrations/" + entry.Name(), Typeflag: archivetar.TypeReg, Mode: 0644, Size: int64(len(b))}))
+		_, err = tw.Write(b)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+func TestRemote(t *testing.T) {
+	b := archive(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/migrations.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer server.Close()
+
+	t.Run("should read the migrations of a downloaded archive", func(t *testing.T) {
+		src, err := Open(server.URL + "/migrations.tar.gz")
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should be registered for the http scheme", func(t *testing.T) {
+		src, err := sources.Open(server.URL + "/migrations.tar.gz")
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should fail if the archive is not found", func(t *testing.T) {
+		_, err := Open(server.URL + "/missing.tar.gz")
+		require.EqualError(t, err, `cannot download migrations from "`+server.URL+`/missing.tar.gz": 404 Not Found`)
+	})
+}
@@ -7,3 +7,15 @@ import (
 type Source interface {
 	Migrations() (migrations []*models.Migration)
 }
+
+// RepeatableSource is implemented by sources that provide repeatable migrations
+// along with the versioned ones.
+type RepeatableSource interface {
+	Repeatables() (migrations []*models.Migration)
+}
+
+// SeedSource provides the seeds, the reference data applied after the schema
+// migrations. Seeds are kept apart from the migrations.
+type SeedSource interface {
+	Seeds() (seeds []*models.Migration)
+}
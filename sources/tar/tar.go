@@ -0,0 +1,149 @@
+package tar
+
+import (
+	archivetar "archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mattermost/morph/models"
+	"github.com/mattermost/morph/sources"
+)
+
+const scheme = "tar"
+
+func init() {
+	sources.Register(scheme, func(sourceURL string) (sources.Source, error) {
+		t, err := Open(sourceURL)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	})
+}
+
+// Tar is a source of migrations read from a tar archive, optionally compressed
+// with gzip. The migrations are read from every directory of the archive.
+type Tar struct {
+	url         string
+	path        string
+	migrations  []*models.Migration
+	repeatables []*models.Migration
+}
+
+// Open reads the migrations of the archive at the path of a tar:// URL, e.g.
+// tar://migrations.tar.gz or tar:///srv/migrations.tar. The URL may also be a
+// plain path.
+func Open(sourceURL string) (*Tar, error) {
+	p := strings.TrimPrefix(sourceURL, scheme+"://")
+	if p == "" {
+		return nil, errors.New("the path of the archive is missing")
+	}
+
+	t := &Tar{
+		url:  sourceURL,
+		path: p,
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations in archive %q: %w", p, err)
+	}
+	defer f.Close()
+
+	if err := t.readMigrations(f); err != nil {
+		return nil, fmt.Errorf("cannot read migrations in archive %q: %w", p, err)
+	}
+
+	return t, nil
+}
+
+// Read reads the migrations of an archive from a reader, e.g. the body of an
+// HTTP response. The URL identifies the archive in the errors.
+func Read(sourceURL string, r io.Reader) (*Tar, error) {
+	t := &Tar{
+		url: sourceURL,
+	}
+
+	if err := t.readMigrations(r); err != nil {
+		return nil, fmt.Errorf("cannot read migrations in archive %q: %w", sourceURL, err)
+	}
+
+	return t, nil
+}
+
+func (t *Tar) readMigrations(f io.Reader) error {
+	r, err := decompress(f)
+	if err != nil {
+		return err
+	}
+
+	migrations := []*models.Migration{}
+	repeatables := []*models.Migration{}
+	tr := archivetar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != archivetar.TypeReg {
+			continue
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		m, err := models.NewMigration(io.NopCloser(bytes.NewReader(b)), path.Base(header.Name))
+		if err != nil {
+			return fmt.Errorf("could not create migration: %w", err)
+		}
+
+		if m.Repeatable {
+			repeatables = append(repeatables, m)
+			continue
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	t.migrations = migrations
+	t.repeatables = repeatables
+	return nil
+}
+
+// decompress returns a reader of the uncompressed archive, detecting gzip
+// compression by its magic number.
+func decompress(f io.Reader) (io.Reader, error) {
+	r := bufio.NewReader(f)
+	magic, err := r.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(r)
+	}
+
+	return r, nil
+}
+
+func (t *Tar) Migrations() []*models.Migration {
+	return t.migrations
+}
+
+// Repeatables returns the repeatable migrations of the archive, the files named
+// R__name.ext.
+func (t *Tar) Repeatables() []*models.Migration {
+	return t.repeatables
+}
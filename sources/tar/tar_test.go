@@ -0,0 +1,79 @@
+//go:build sources && !drivers
+// +build sources,!drivers
+
+package tar
+
+import (
+	archivetar "archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/morph/sources"
+	"github.com/mattermost/morph/sources/testlib"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeArchive(t *testing.T, name string, compress bool) string {
+	testFilesDir := "../embedded/testfiles"
+	entries, err := os.ReadDir(testFilesDir)
+	require.NoError(t, err)
+
+	archive := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(archive)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.Writer = f
+	if compress {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+
+	tw := archivetar.NewWriter(w)
+	defer tw.Close()
+
+	require.NoError(t, tw.WriteHeader(&archivetar.Header{Name: "migrations/", Typeflag: archivetar.TypeDir, Mode: 0755}))
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(testFilesDir, entry.Name()))
+		require.NoError(t, err)
+
+		require.NoError(t, tw.WriteHeader(&archivetar.Header{Name: "migrations/" + entry.Name(), Typeflag: archivetar.TypeReg, Mode: 0644, Size: int64(len(b))}))
+		_, err = tw.Write(b)
+		require.NoError(t, err)
+	}
+
+	return archive
+}
+
+func TestTar(t *testing.T) {
+	t.Run("should read the migrations of an archive", func(t *testing.T) {
+		src, err := Open(writeArchive(t, "migrations.tar", false))
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should read the migrations of a compressed archive", func(t *testing.T) {
+		src, err := Open("tar://" + writeArchive(t, "migrations.tar.gz", true))
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should be registered for the tar scheme", func(t *testing.T) {
+		src, err := sources.Open("tar://" + writeArchive(t, "migrations.tgz", true))
+		require.NoError(t, err)
+
+		testlib.Test(t, src)
+	})
+
+	t.Run("should fail if the archive does not exist", func(t *testing.T) {
+		_, err := Open("tar://missing.tar")
+		require.Error(t, err)
+	})
+}
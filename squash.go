@@ -0,0 +1,241 @@
+package morph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+)
+
+// SquashedPrefix is prepended to the name of the last migration replaced by a
+// squashed migration to form its name, e.g. 000120_squashed_add_email.up.sql.
+const SquashedPrefix = "squashed_"
+
+// migrationSet holds the applied migrations and the migrations of the source as
+// the engine sees them, leaving out the migrations replaced by a squashed
+// migration where they are not needed.
+type migrationSet struct {
+	applied []*models.Migration
+	source  []*models.Migration
+	// squash is the latest migration of the source with the squash directive,
+	// nil if there is none.
+	squash *models.Migration
+	// squashImplied is set if the database has been migrated past the squash
+	// point with the migrations the squashed migration replaces, therefore it
+	// is consistent with the squashed migration without having applied it.
+	squashImplied bool
+}
+
+// loadMigrations returns the migrations the engine works with. A squashed
+// migration replaces every migration up to and including its version:
+//   - a database that has not applied any of them runs only the squashed migration,
+//   - a database that has applied all of them is consistent with the squashed
+//     migration, and the migrations before the squash point are left out,
+//   - a database that has applied some of them applies the rest instead of the
+//     squashed migration, as long as they are still in the source.
+func (m *Morph) loadMigrations() (*migrationSet, error) {
+	appliedMigrations, err := m.driver.AppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveSquash(appliedMigrations, m.source.Migrations())
+}
+
+func resolveSquash(appliedMigrations, sourceMigrations []*models.Migration) (*migrationSet, error) {
+	set := &migrationSet{
+		applied: appliedMigrations,
+		source:  sourceMigrations,
+	}
+
+	squash, err := findSquash(sourceMigrations)
+	if err != nil || squash == nil {
+		return set, err
+	}
+	set.squash = squash
+
+	var replacedApplied, pastSquash bool
+	for _, migration := range appliedMigrations {
+		if replaces(squash, migration) {
+			replacedApplied = true
+			pastSquash = pastSquash || migration.Version == squash.Version
+		}
+	}
+
+	switch {
+	case !replacedApplied:
+		set.source = filterMigrations(sourceMigrations, func(migration *models.Migration) bool {
+			return !replaces(squash, migration)
+		})
+	case pastSquash:
+		keep := func(migration *models.Migration) bool {
+			return !replaces(squash, migration) && !isSquash(squash, migration)
+		}
+		set.applied = filterMigrations(appliedMigrations, keep)
+		set.source = filterMigrations(sourceMigrations, keep)
+		set.squashImplied = true
+	default:
+		set.source = filterMigrations(sourceMigrations, func(migration *models.Migration) bool {
+			return !isSquash(squash, migration)
+		})
+
+		var found bool
+		for _, migration := range set.source {
+			if migration.Direction == models.Up && migration.Version == squash.Version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("the database has not been migrated up to the squashed migration %s, and the migrations it replaces are no longer in the source", squash.Name)
+		}
+	}
+
+	return set, nil
+}
+
+// findSquash returns the up migration with the squash directive that has the
+// highest version.
+func findSquash(migrations []*models.Migration) (*models.Migration, error) {
+	var squash *models.Migration
+	for _, migration := range migrations {
+		if migration.Direction != models.Up {
+			continue
+		}
+
+		directives, err := migration.Directives()
+		if err != nil {
+			return nil, err
+		}
+		if directives.Squash && (squash == nil || migration.Version > squash.Version) {
+			squash = migration
+		}
+	}
+
+	return squash, nil
+}
+
+// replaces reports whether the migration is one of the migrations replaced by
+// the squashed migration.
+func replaces(squash, migration *models.Migration) bool {
+	return migration.Version <= squash.Version && !isSquash(squash, migration)
+}
+
+// isSquash reports whether the migration is the squashed migration, in either
+// direction.
+func isSquash(squash, migration *models.Migration) bool {
+	return migration.Name == squash.Name && migration.Version == squash.Version
+}
+
+func filterMigrations(migrations []*models.Migration, keep func(*models.Migration) bool) []*models.Migration {
+	filtered := make([]*models.Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if keep(migration) {
+			filtered = append(filtered, migration)
+		}
+	}
+
+	return filtered
+}
+
+// Squash replays the migrations of the source up to and including the given
+// version on a scratch database, and returns a migration that creates the
+// resulting schema at once, along with the migration that drops it. The squashed
+// migration has the same version as the last migration it replaces, so fresh
+// databases run it instead of the migrations it replaces, while the databases
+// that are past the squash point are consistent with it.
+//
+// Only the tables, columns, indexes and constraints are kept, the data inserted
+// by the replaced migrations is not. The driver has to implement both the
+// drivers.SchemaDumper and the drivers.Scratcher interfaces.
+func (m *Morph) Squash(version uint64) (*models.Migration, *models.Migration, error) {
+	if _, ok := m.driver.(drivers.SchemaDumper); !ok {
+		return nil, nil, errors.New("driver does not support schema dumps")
+	}
+
+	scratcher, ok := m.driver.(drivers.Scratcher)
+	if !ok {
+		return nil, nil, errors.New("driver does not support scratch databases")
+	}
+
+	// the migrations a fresh database would run
+	set, err := resolveSquash(nil, m.source.Migrations())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var last *models.Migration
+	var migrations []*models.Migration
+	for _, migration := range set.source {
+		if migration.Direction != models.Up || migration.Version > version {
+			continue
+		}
+		if migration.Version == version {
+			last = migration
+		}
+
+		skipped, err := m.isSkipped(migration)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !skipped {
+			migrations = append(migrations, migration)
+		}
+	}
+	if last == nil {
+		return nil, nil, fmt.Errorf("there is no migration with version %d in the source", version)
+	}
+
+	graph, err := m.dependencyGraph(set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scratch, err := scratcher.Scratch()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer scratch.Close()
+
+	m.config.Logger.Printf("replaying %d migrations on a scratch database", len(migrations))
+	for _, migration := range graph.sort(migrations) {
+		if err := scratch.Apply(migration, false); err != nil {
+			return nil, nil, fmt.Errorf("could not apply migration %s to the scratch database: %w", migration.Name, err)
+		}
+	}
+
+	scratchDumper, ok := scratch.(drivers.SchemaDumper)
+	if !ok {
+		return nil, nil, errors.New("scratch driver does not support schema dumps")
+	}
+
+	schema, err := scratchDumper.DumpSchema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the tags of the last migration do not apply to the squashed migration
+	prefix, _, _ := strings.Cut(last.RawName, "_")
+	lastName, _, _ := strings.Cut(last.Name, models.TagSeparator)
+	name := SquashedPrefix + lastName
+	header := fmt.Sprintf("%s%s\n-- Replaces the migrations up to and including %s.\n", models.DirectivePrefix, models.DirectiveSquash, last.Name)
+
+	up := &models.Migration{
+		Name:      name,
+		RawName:   prefix + "_" + name + "." + string(models.Up) + ".sql",
+		Version:   version,
+		Direction: models.Up,
+		Bytes:     []byte(header + schema.Script()),
+	}
+	down := &models.Migration{
+		Name:      name,
+		RawName:   prefix + "_" + name + "." + string(models.Down) + ".sql",
+		Version:   version,
+		Direction: models.Down,
+		Bytes:     []byte(schema.DropScript()),
+	}
+
+	return up, down, nil
+}
@@ -0,0 +1,124 @@
+package morph
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/mattermost/morph/models"
+	"github.com/mattermost/morph/sources"
+)
+
+// renderedSource holds the rendered migrations of a source.
+type renderedSource struct {
+	migrations  []*models.Migration
+	repeatables []*models.Migration
+	seeds       []*models.Migration
+}
+
+func (s *renderedSource) Migrations() []*models.Migration {
+	return s.migrations
+}
+
+func (s *renderedSource) Repeatables() []*models.Migration {
+	return s.repeatables
+}
+
+func (s *renderedSource) Seeds() []*models.Migration {
+	return s.seeds
+}
+
+func (m *Morph) templatingEnabled() bool {
+	return m.config.TemplateVariables != nil || m.config.StrictTemplates
+}
+
+// renderSource renders all migrations of the source up front, so an undefined
+// variable is reported before any migration is applied.
+func (m *Morph) renderSource(source sources.Source) (sources.Source, error) {
+	migrations, err := m.renderMigrations(source.Migrations())
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &renderedSource{migrations: migrations}
+	if rs, ok := source.(sources.RepeatableSource); ok {
+		if rendered.repeatables, err = m.renderMigrations(rs.Repeatables()); err != nil {
+			return nil, err
+		}
+	}
+
+	return rendered, nil
+}
+
+// renderPlan returns a copy of the plan with rendered migrations. Plans that
+// have been rendered when they were generated are returned as they are, so the
+// SQL that has been reviewed is the SQL that runs.
+func (m *Morph) renderPlan(plan *models.Plan) (*models.Plan, error) {
+	if plan.Rendered {
+		if m.templatingEnabled() {
+			m.config.Logger.Println("the plan has been rendered when it was generated, template variables are not applied again")
+		}
+		return plan, nil
+	}
+
+	if !m.templatingEnabled() {
+		return plan, nil
+	}
+
+	migrations, err := m.renderMigrations(plan.Migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	revertMigrations, err := m.renderMigrations(plan.RevertMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := *plan
+	rendered.Migrations = migrations
+	rendered.RevertMigrations = revertMigrations
+	rendered.Rendered = true
+
+	return &rendered, nil
+}
+
+func (m *Morph) renderMigrations(migrations []*models.Migration) ([]*models.Migration, error) {
+	rendered := make([]*models.Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		r, err := m.renderMigration(migration)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, r)
+	}
+
+	return rendered, nil
+}
+
+func (m *Morph) renderMigration(migration *models.Migration) (*models.Migration, error) {
+	missingKey := "missingkey=zero"
+	if m.config.StrictTemplates {
+		missingKey = "missingkey=error"
+	}
+
+	tmpl, err := template.New(migration.RawName).Option(missingKey).Parse(migration.Query())
+	if err != nil {
+		return nil, fmt.Errorf("could not parse migration %s as a template: %w", migration.RawName, err)
+	}
+
+	vars := m.config.TemplateVariables
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return nil, fmt.Errorf("could not render migration %s: %w", migration.RawName, err)
+	}
+
+	rendered := *migration
+	rendered.Bytes = b.Bytes()
+
+	return &rendered, nil
+}
@@ -100,7 +100,7 @@ func (h *testHelper) AddMigration(t *testing.T, migrationName string) {
 	// Just generate a random name
 	tableName := fmt.Sprintf("test_%s_%d", migrationName, time.Now().Unix())
 	for name := range h.drivers {
-		v := 1 + uint32(len(h.migrations[name]))
+		v := 1 + uint64(len(h.migrations[name]))
 		h.migrations[name] = append(h.migrations[name], &models.Migration{
 			Name:      migrationName,
 			Direction: models.Up,
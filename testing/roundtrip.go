@@ -0,0 +1,237 @@
+// Package testing provides helpers to verify that the migrations of a source can
+// be applied and reverted reliably before they are shipped.
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/morph"
+	"github.com/mattermost/morph/drivers"
+	"github.com/mattermost/morph/models"
+	"github.com/mattermost/morph/sources"
+)
+
+// Snapshotter returns a description of the database schema. It is used to verify
+// that rolling back a migration restores the schema it was applied on.
+type Snapshotter func() (string, error)
+
+// Option configures the round trip.
+type Option func(*runner)
+
+// WithLogger sets the logger of the engine used for the round trip.
+func WithLogger(logger morph.Logger) Option {
+	return func(r *runner) {
+		r.engineOptions = append(r.engineOptions, morph.WithLogger(logger))
+	}
+}
+
+// WithEngineOptions passes the given options to the engine used for the round trip.
+func WithEngineOptions(options ...morph.EngineOption) Option {
+	return func(r *runner) {
+		r.engineOptions = append(r.engineOptions, options...)
+	}
+}
+
+// WithSnapshotter enables comparing the schema before applying a migration with
+// the schema after rolling it back.
+func WithSnapshotter(snapshot Snapshotter) Option {
+	return func(r *runner) {
+		r.snapshot = snapshot
+	}
+}
+
+// Error describes the step of the round trip that has failed.
+type Error struct {
+	// Migration is the name of the migration being tested.
+	Migration string
+	// Step is the step of the round trip, one of "up", "down", "up again" or "snapshot".
+	Step string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("round trip of migration %s failed at step %q: %v", e.Migration, e.Step, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type runner struct {
+	driver        drivers.Driver
+	engine        *morph.Morph
+	snapshot      Snapshotter
+	engineOptions []morph.EngineOption
+}
+
+// RoundTrip applies every pending migration of the source up, then down, then up
+// again. After each step, it checks that the migrations table contains exactly the
+// migrations that are expected to be applied. If a snapshotter is set, the schema
+// before applying a migration is compared with the schema after rolling it back.
+//
+// Repeatable migrations are not part of the round trip, as they cannot be rolled
+// back. The migrations are left applied once the round trip succeeds. The driver
+// is not closed, it is the responsibility of the caller.
+func RoundTrip(ctx context.Context, driver drivers.Driver, source sources.Source, options ...Option) error {
+	r := &runner{driver: driver}
+	for _, option := range options {
+		option(r)
+	}
+
+	engine, err := morph.New(ctx, driver, versionedSource{source}, r.engineOptions...)
+	if err != nil {
+		return err
+	}
+	r.engine = engine
+
+	pendingMigrations, err := engine.Diff(models.Up)
+	if err != nil {
+		return err
+	}
+
+	appliedMigrations, err := driver.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	expected := make([]string, 0, len(appliedMigrations)+len(pendingMigrations))
+	for _, migration := range appliedMigrations {
+		if migration.SkipReason != "" {
+			continue
+		}
+		expected = append(expected, migration.Name)
+	}
+
+	for _, migration := range pendingMigrations {
+		if err := r.roundTrip(migration, expected); err != nil {
+			return err
+		}
+		expected = append(expected, migration.Name)
+	}
+
+	return nil
+}
+
+// versionedSource hides the repeatable migrations of a source.
+type versionedSource struct {
+	source sources.Source
+}
+
+func (s versionedSource) Migrations() []*models.Migration {
+	return s.source.Migrations()
+}
+
+func (r *runner) roundTrip(migration *models.Migration, applied []string) error {
+	withMigration := append(append([]string{}, applied...), migration.Name)
+
+	before, err := r.takeSnapshot(migration)
+	if err != nil {
+		return err
+	}
+
+	if err := r.step(migration, "up", r.engine.Apply, withMigration); err != nil {
+		return err
+	}
+
+	if err := r.step(migration, "down", r.engine.ApplyDown, applied); err != nil {
+		return err
+	}
+
+	after, err := r.takeSnapshot(migration)
+	if err != nil {
+		return err
+	}
+
+	if before != after {
+		return &Error{
+			Migration: migration.Name,
+			Step:      "snapshot",
+			Err:       fmt.Errorf("schema after rolling back differs from the schema before applying the migration:\n%s", diffLines(before, after)),
+		}
+	}
+
+	return r.step(migration, "up again", r.engine.Apply, withMigration)
+}
+
+func (r *runner) step(migration *models.Migration, name string, fn func(int) (int, error), expected []string) error {
+	if _, err := fn(1); err != nil {
+		return &Error{Migration: migration.Name, Step: name, Err: err}
+	}
+
+	if err := r.checkMigrationsTable(expected); err != nil {
+		return &Error{Migration: migration.Name, Step: name, Err: err}
+	}
+
+	return nil
+}
+
+func (r *runner) takeSnapshot(migration *models.Migration) (string, error) {
+	if r.snapshot == nil {
+		return "", nil
+	}
+
+	snapshot, err := r.snapshot()
+	if err != nil {
+		return "", &Error{Migration: migration.Name, Step: "snapshot", Err: err}
+	}
+
+	return snapshot, nil
+}
+
+// checkMigrationsTable verifies that exactly the expected migrations are recorded
+// as applied.
+func (r *runner) checkMigrationsTable(expected []string) error {
+	appliedMigrations, err := r.driver.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	actual := make([]string, 0, len(appliedMigrations))
+	for _, migration := range appliedMigrations {
+		// skipped migrations are recorded on the way, they are not part of the round trip
+		if migration.SkipReason != "" {
+			continue
+		}
+		actual = append(actual, migration.Name)
+	}
+
+	sortedExpected := append([]string{}, expected...)
+	sort.Strings(sortedExpected)
+	sort.Strings(actual)
+
+	if strings.Join(sortedExpected, ",") != strings.Join(actual, ",") {
+		return fmt.Errorf("migrations table is inconsistent, expected applied migrations %v but found %v", sortedExpected, actual)
+	}
+
+	return nil
+}
+
+// diffLines returns the lines that are only present in one of the snapshots.
+func diffLines(before, after string) string {
+	count := make(map[string]int)
+	for _, line := range strings.Split(before, "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(after, "\n") {
+		count[line]--
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(before, "\n") {
+		if count[line] > 0 {
+			b.WriteString("- " + line + "\n")
+			count[line]--
+		}
+	}
+	for _, line := range strings.Split(after, "\n") {
+		if count[line] < 0 {
+			b.WriteString("+ " + line + "\n")
+			count[line]++
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package testing_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/morph/drivers/sqlite"
+	"github.com/mattermost/morph/models"
+	morphtesting "github.com/mattermost/morph/testing"
+	"github.com/stretchr/testify/require"
+)
+
+type testSource struct {
+	migrations []*models.Migration
+}
+
+func (s *testSource) Migrations() []*models.Migration {
+	return s.migrations
+}
+
+func newSource(down2 string) *testSource {
+	return &testSource{
+		migrations: []*models.Migration{
+			{Name: "create_a", Version: 1, Direction: models.Up, RawName: "000001_create_a.up.sql", Bytes: []byte("CREATE TABLE a (id integer)")},
+			{Name: "create_a", Version: 1, Direction: models.Down, RawName: "000001_create_a.down.sql", Bytes: []byte("DROP TABLE a")},
+			{Name: "create_b", Version: 2, Direction: models.Up, RawName: "000002_create_b.up.sql", Bytes: []byte("CREATE TABLE b (id integer)")},
+			{Name: "create_b", Version: 2, Direction: models.Down, RawName: "000002_create_b.down.sql", Bytes: []byte(down2)},
+		},
+	}
+}
+
+func openSqlite(t *testing.T) (*sql.DB, string) {
+	f, err := os.CreateTemp("", "morph-roundtrip-*.db")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	db, err := sql.Open("sqlite", f.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db, f.Name()
+}
+
+func snapshotter(db *sql.DB) morphtesting.Snapshotter {
+	return func() (string, error) {
+		rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'db_%' ORDER BY name")
+		if err != nil {
+			return "", err
+		}
+		defer rows.Close()
+
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return "", err
+			}
+			names = append(names, name)
+		}
+
+		return strings.Join(names, "\n"), nil
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	logger := morphtesting.WithLogger(log.New(io.Discard, "", 0))
+
+	t.Run("should succeed with reversible migrations", func(t *testing.T) {
+		db, dsn := openSqlite(t)
+		driver, err := sqlite.Open(dsn)
+		require.NoError(t, err)
+		defer driver.Close()
+
+		err = morphtesting.RoundTrip(context.Background(), driver, newSource("DROP TABLE b"), logger, morphtesting.WithSnapshotter(snapshotter(db)))
+		require.NoError(t, err)
+
+		applied, err := driver.AppliedMigrations()
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+	})
+
+	t.Run("should fail if down script is broken", func(t *testing.T) {
+		_, dsn := openSqlite(t)
+		driver, err := sqlite.Open(dsn)
+		require.NoError(t, err)
+		defer driver.Close()
+
+		err = morphtesting.RoundTrip(context.Background(), driver, newSource("DROP TABLE c"), logger)
+		var rtErr *morphtesting.Error
+		require.True(t, errors.As(err, &rtErr))
+		require.Equal(t, "create_b", rtErr.Migration)
+		require.Equal(t, "down", rtErr.Step)
+	})
+
+	t.Run("should fail if down script does not restore the schema", func(t *testing.T) {
+		db, dsn := openSqlite(t)
+		driver, err := sqlite.Open(dsn)
+		require.NoError(t, err)
+		defer driver.Close()
+
+		err = morphtesting.RoundTrip(context.Background(), driver, newSource("SELECT 1"), logger, morphtesting.WithSnapshotter(snapshotter(db)))
+		var rtErr *morphtesting.Error
+		require.True(t, errors.As(err, &rtErr))
+		require.Equal(t, "create_b", rtErr.Migration)
+		require.Equal(t, "snapshot", rtErr.Step)
+		require.Contains(t, rtErr.Error(), "+ b\n")
+	})
+}